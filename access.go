@@ -0,0 +1,75 @@
+package jsonrpc
+
+import "context"
+
+// Codes of the errors answering requests to methods the client may not call.
+const (
+	CodeUnauthorized = -32002
+	CodeForbidden    = -32003
+)
+
+var (
+	// ErrUnauthorized answers unauthenticated requests to methods requiring
+	// authentication, see Authenticated.
+	ErrUnauthorized = &Error{Code: CodeUnauthorized, Message: "Unauthorized"}
+	// ErrForbidden answers requests to methods requiring scopes the client
+	// wasn't granted, see RequireScopes.
+	ErrForbidden = &Error{Code: CodeForbidden, Message: "Forbidden"}
+)
+
+// MethodOption configures a method registered with HandleFunc.
+type MethodOption func(*handlerType)
+
+type accessLevel int
+
+const (
+	accessDefault accessLevel = iota
+	accessPublic
+	accessAuthenticated
+)
+
+// Public lets anyone call the method, even if Server.RequireAuth is set.
+func Public() MethodOption {
+	return func(h *handlerType) {
+		h.access = accessPublic
+	}
+}
+
+// Authenticated restricts the method to authenticated clients, those with a
+// Principal, see GetPrincipal.
+func Authenticated() MethodOption {
+	return func(h *handlerType) {
+		h.access = accessAuthenticated
+	}
+}
+
+// RequireScopes restricts the method to authenticated clients granted all
+// of scopes.
+func RequireScopes(scopes ...string) MethodOption {
+	return func(h *handlerType) {
+		h.access = accessAuthenticated
+		h.scopes = append(h.scopes, scopes...)
+	}
+}
+
+// authorize reports why the client of ctx may not call the method handled
+// by h, if it may not.
+func (s *Server) authorize(ctx context.Context, h *handlerType) *Error {
+	access := h.access
+	if access == accessDefault && s.RequireAuth {
+		access = accessAuthenticated
+	}
+	if access != accessAuthenticated {
+		return nil
+	}
+	p := GetPrincipal(ctx)
+	if p == nil {
+		return ErrUnauthorized
+	}
+	for _, scope := range h.scopes {
+		if !p.HasScope(scope) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMethodAccess(t *testing.T) {
+	pong := func(ctx context.Context) (string, error) { return "pong", nil }
+	server := NewServer()
+	server.RequireAuth = true
+	server.HandleFunc("ping", pong, Public())
+	server.HandleFunc("orders.list", pong)
+	server.HandleFunc("orders.cancel", pong, RequireScopes("orders:write"))
+
+	reader := &Principal{Subject: "gopher", Scopes: []string{"orders:read"}}
+	writer := &Principal{Subject: "admin", Scopes: []string{"orders:read", "orders:write"}}
+	tests := []struct {
+		method    string
+		principal *Principal
+		want      string
+	}{
+		{"ping", nil, `{"jsonrpc":"2.0","id":1,"result":"pong"}`},
+		{"orders.list", nil, `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Unauthorized"}}`},
+		{"orders.list", reader, `{"jsonrpc":"2.0","id":1,"result":"pong"}`},
+		{"orders.cancel", reader, `{"jsonrpc":"2.0","id":1,"error":{"code":-32003,"message":"Forbidden"}}`},
+		{"orders.cancel", writer, `{"jsonrpc":"2.0","id":1,"result":"pong"}`},
+	}
+	for _, test := range tests {
+		ctx := context.Background()
+		if test.principal != nil {
+			ctx = WithPrincipal(ctx, test.principal)
+		}
+		got := string(server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"`+test.method+`"}`)))
+		if got != test.want {
+			t.Errorf("%v as %v:\ngot: %v\nwant: %v", test.method, test.principal, got, test.want)
+		}
+	}
+
+	server.RequireAuth = false
+	if got := string(server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"orders.list"}`))); got != `{"jsonrpc":"2.0","id":1,"result":"pong"}` {
+		t.Errorf("method without option not public by default: %v", got)
+	}
+}
@@ -0,0 +1,91 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CodeServerBusy is the code of ErrServerBusy.
+const CodeServerBusy = -32001
+
+// ErrServerBusy is returned when a request couldn't be admitted, see
+// Server.MaxConcurrentRequests. It is retriable.
+var ErrServerBusy = &Error{Code: CodeServerBusy, Message: "Server busy", Data: retryData{Retriable: true}}
+
+// AdmissionStats describes the load of a server limiting its concurrent
+// requests.
+type AdmissionStats struct {
+	// InFlight is the number of requests being executed.
+	InFlight int
+	// Queued is the number of requests waiting to be executed.
+	Queued int
+	// Rejected is the number of requests answered with ErrServerBusy.
+	Rejected uint64
+}
+
+// admission bounds the requests executed concurrently.
+type admission struct {
+	once     sync.Once
+	slots    chan struct{}
+	queued   int64
+	rejected uint64
+}
+
+// AdmissionStats returns the current load of s, for capacity tuning.
+func (s *Server) AdmissionStats() AdmissionStats {
+	slots := s.admissionSlots()
+	return AdmissionStats{
+		InFlight: len(slots),
+		Queued:   int(atomic.LoadInt64(&s.admission.queued)),
+		Rejected: atomic.LoadUint64(&s.admission.rejected),
+	}
+}
+
+func (s *Server) admissionSlots() chan struct{} {
+	s.admission.once.Do(func() {
+		if s.MaxConcurrentRequests > 0 {
+			s.admission.slots = make(chan struct{}, s.MaxConcurrentRequests)
+		}
+	})
+	return s.admission.slots
+}
+
+// admit waits for the request of ctx to be allowed to execute, and returns
+// the function to call once it is done. ok is false if it was rejected.
+func (s *Server) admit(ctx context.Context) (release func(), ok bool) {
+	slots := s.admissionSlots()
+	if slots == nil {
+		return func() {}, true
+	}
+	release = func() { <-slots }
+	select {
+	case slots <- struct{}{}:
+		return release, true
+	default:
+	}
+
+	a := &s.admission
+	if atomic.AddInt64(&a.queued, 1) > int64(s.AdmissionQueue) {
+		atomic.AddInt64(&a.queued, -1)
+		atomic.AddUint64(&a.rejected, 1)
+		return nil, false
+	}
+	defer atomic.AddInt64(&a.queued, -1)
+
+	var timeout <-chan time.Time
+	if s.AdmissionTimeout > 0 {
+		t := time.NewTimer(s.AdmissionTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case slots <- struct{}{}:
+		return release, true
+	case <-ctx.Done():
+	case <-timeout:
+	}
+	atomic.AddUint64(&a.rejected, 1)
+	return nil, false
+}
@@ -0,0 +1,125 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	unblock := make(chan struct{})
+	started := make(chan struct{}, 10)
+	server := NewServer()
+	server.MaxConcurrentRequests = 1
+	server.AdmissionQueue = 1
+	server.AdmissionTimeout = time.Second
+	server.HandleFunc("block", func(ctx context.Context) (int, error) {
+		started <- struct{}{}
+		<-unblock
+		return 1, nil
+	})
+
+	serve := func() string {
+		return string(server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"block"}`)))
+	}
+	var wg sync.WaitGroup
+	results := make([]string, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = serve()
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+	// wait for the second request to be queued
+	for server.AdmissionStats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := server.AdmissionStats(); stats.InFlight != 1 {
+		t.Errorf("invalid stats: %+v", stats)
+	}
+
+	// the queue is full
+	busy := `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"Server busy","data":{"retriable":true}}}`
+	if got := serve(); got != busy {
+		t.Errorf("queue full:\ngot: %v\nwant: %v", got, busy)
+	}
+
+	close(unblock)
+	wg.Wait()
+	for i, got := range results {
+		if want := `{"jsonrpc":"2.0","id":1,"result":1}`; got != want {
+			t.Errorf("request %v:\ngot: %v\nwant: %v", i, got, want)
+		}
+	}
+	if stats := server.AdmissionStats(); stats != (AdmissionStats{Rejected: 1}) {
+		t.Errorf("invalid stats: %+v", stats)
+	}
+}
+
+func TestAdmissionTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	server := NewServer()
+	server.MaxConcurrentRequests = 1
+	server.AdmissionQueue = 1
+	server.AdmissionTimeout = 10 * time.Millisecond
+	server.HandleFunc("block", func(ctx context.Context) (int, error) {
+		<-unblock
+		return 1, nil
+	})
+	defer close(unblock)
+
+	go server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"block"}`))
+	for server.AdmissionStats().InFlight != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":2,"method":"block"}`))
+	if want := `{"jsonrpc":"2.0","id":2,"error":{"code":-32001,"message":"Server busy","data":{"retriable":true}}}`; string(got) != want {
+		t.Errorf("timeout:\ngot: %s\nwant: %v", got, want)
+	}
+}
+
+func TestAdmissionStream(t *testing.T) {
+	sent, unblock := make(chan struct{}), make(chan struct{})
+	server := NewServer()
+	server.MaxConcurrentRequests = 1
+	server.HandleFunc("watch", func(ctx context.Context) (<-chan int, error) {
+		c := make(chan int)
+		go func() {
+			defer close(c)
+			c <- 1
+			close(sent)
+			<-unblock
+		}()
+		return c, nil
+	})
+	server.HandleFunc("ping", func(ctx context.Context) (string, error) {
+		return "pong", nil
+	})
+	ping := func() string {
+		return string(server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"watch"}`)))
+	}()
+	<-sent
+	// the stream is being written, it still holds the only slot
+	busy := `{"jsonrpc":"2.0","id":2,"error":{"code":-32001,"message":"Server busy","data":{"retriable":true}}}`
+	if got := ping(); got != busy {
+		t.Errorf("while streaming:\ngot: %v\nwant: %v", got, busy)
+	}
+	close(unblock)
+	<-done
+	if got, want := ping(), `{"jsonrpc":"2.0","id":2,"result":"pong"}`; got != want {
+		t.Errorf("after the stream:\ngot: %v\nwant: %v", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package jsonrpc
+
+import (
+	"context"
+	"log"
+)
+
+// AMQPDelivery is a JSON-RPC request consumed from an AMQP queue.
+type AMQPDelivery struct {
+	Body          []byte
+	ReplyTo       string
+	CorrelationID string
+	// Ack acknowledges the delivery, it is called once the request was served
+	// and its response, if any, published. It may be nil.
+	Ack func() error
+}
+
+// AMQPPublisher publishes responses to reply-to queues. It is usually
+// implemented on top of an *amqp.Channel with the default exchange.
+type AMQPPublisher interface {
+	Publish(ctx context.Context, replyTo, correlationID string, body []byte) error
+}
+
+// ServeAMQP serves the requests received on deliveries and publishes their
+// responses to the delivery reply-to queue, tagged with its correlation id.
+// It returns when deliveries is closed or ctx is done.
+func (s *Server) ServeAMQP(ctx context.Context, deliveries <-chan AMQPDelivery, pub AMQPPublisher) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return nil
+			}
+			s.serveAMQPDelivery(ctx, d, pub)
+		}
+	}
+}
+
+func (s *Server) serveAMQPDelivery(ctx context.Context, d AMQPDelivery, pub AMQPPublisher) {
+	resp := s.ServeMessage(ctx, d.Body)
+	if resp != nil && d.ReplyTo != "" {
+		if err := pub.Publish(ctx, d.ReplyTo, d.CorrelationID, resp); err != nil {
+			// leave the delivery unacknowledged so the broker redelivers it
+			log.Printf("jsonrpc: amqp: publishing response: %v", err)
+			return
+		}
+	}
+	if d.Ack != nil {
+		if err := d.Ack(); err != nil {
+			log.Printf("jsonrpc: amqp: acknowledging delivery: %v", err)
+		}
+	}
+}
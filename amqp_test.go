@@ -0,0 +1,72 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type amqpMsg struct {
+	replyTo, correlationID, body string
+}
+
+type fakeAMQP struct {
+	msgs []amqpMsg
+	err  error
+}
+
+func (f *fakeAMQP) Publish(ctx context.Context, replyTo, correlationID string, body []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.msgs = append(f.msgs, amqpMsg{replyTo, correlationID, string(body)})
+	return nil
+}
+
+func TestServeAMQP(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	acked := 0
+	ack := func() error { acked++; return nil }
+	deliveries := make(chan AMQPDelivery, 2)
+	deliveries <- AMQPDelivery{
+		Body:          []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`),
+		ReplyTo:       "replies",
+		CorrelationID: "c1",
+		Ack:           ack,
+	}
+	deliveries <- AMQPDelivery{Body: []byte(`{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}`), Ack: ack}
+	close(deliveries)
+
+	pub := &fakeAMQP{}
+	if err := server.ServeAMQP(context.Background(), deliveries, pub); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	want := amqpMsg{"replies", "c1", `{"jsonrpc":"2.0","id":1,"result":{"C":3}}`}
+	if len(pub.msgs) != 1 || pub.msgs[0] != want {
+		t.Errorf("invalid published responses:\ngot: %v\nwant: %v", pub.msgs, want)
+	}
+	if acked != 2 {
+		t.Errorf("invalid number of acks:\ngot: %v\nwant: %v", acked, 2)
+	}
+}
+
+func TestServeAMQPPublishError(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	acked := false
+	deliveries := make(chan AMQPDelivery, 1)
+	deliveries <- AMQPDelivery{
+		Body:    []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`),
+		ReplyTo: "replies",
+		Ack:     func() error { acked = true; return nil },
+	}
+	close(deliveries)
+
+	server.ServeAMQP(context.Background(), deliveries, &fakeAMQP{err: errors.New("channel closed")})
+	if acked {
+		t.Errorf("delivery acknowledged although its response was not published")
+	}
+}
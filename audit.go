@@ -0,0 +1,251 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AuditRecord records a request served by the server.
+type AuditRecord struct {
+	Time      time.Time       `json:"time"`
+	Caller    string          `json:"caller,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Method    string          `json:"method"`
+	ID        interface{}     `json:"id,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	// Code and Error describe the failure of the request, they are empty
+	// when it succeeded.
+	Code     int           `json:"code,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// AuditSink stores audit records. WriteAudit is only called from the
+// goroutine of the Auditor and must not retain recs.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, recs []AuditRecord) error
+}
+
+// AuditConfig configures an Auditor.
+type AuditConfig struct {
+	// Caller identifies who made the request, typically from values set on
+	// the context by an authentication middleware.
+	Caller func(ctx context.Context) string
+	// Redact returns the params to record for a request, nil records none.
+	// Defaults to Server.Redact.
+	Redact func(method string, params json.RawMessage) json.RawMessage
+	// BatchSize is the number of records written to the sink at once.
+	// Defaults to 100.
+	BatchSize int
+	// FlushInterval is how long a partial batch waits before being written.
+	// Defaults to 1s.
+	FlushInterval time.Duration
+	// QueueSize is the number of records waiting to be written past which
+	// new records are dropped. Defaults to 1024.
+	QueueSize int
+}
+
+// Auditor asynchronously writes an audit record for every request served to
+// a sink. Set it to Server.Audit.
+type Auditor struct {
+	sink    AuditSink
+	cfg     AuditConfig
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan AuditRecord
+	done   chan struct{}
+}
+
+// NewAuditor returns an Auditor writing to sink. It must be closed to flush
+// the pending records.
+func NewAuditor(sink AuditSink, cfg AuditConfig) *Auditor {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	cfg.FlushInterval = durationOr(cfg.FlushInterval, time.Second)
+	a := &Auditor{
+		sink:  sink,
+		cfg:   cfg,
+		queue: make(chan AuditRecord, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (a *Auditor) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Close writes the pending records and stops the auditor, records of requests
+// served afterwards are dropped.
+func (a *Auditor) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
+
+func (a *Auditor) record(ctx context.Context, req *request, redact func(string, json.RawMessage) json.RawMessage, resp *Response, start time.Time, err error) {
+	rec := AuditRecord{
+		Time:      start,
+		Method:    req.Method,
+		ID:        req.ID,
+		Duration:  time.Since(start),
+		RequestID: RequestID(ctx),
+	}
+	if a.cfg.Caller != nil {
+		rec.Caller = a.cfg.Caller(ctx)
+	}
+	if a.cfg.Redact != nil {
+		redact = a.cfg.Redact
+	}
+	params := redact(req.Method, req.Params)
+	if len(params) > 0 {
+		// requests are pooled, params must be copied
+		rec.Params = append(json.RawMessage(nil), params...)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		if resp != nil && resp.error != nil {
+			rec.Code = resp.error.Code
+		} else if e, ok := AsError(err); ok {
+			rec.Code = e.Code
+		}
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		atomic.AddUint64(&a.dropped, 1)
+		return
+	}
+	select {
+	case a.queue <- rec:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+func (a *Auditor) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]AuditRecord, 0, a.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := a.sink.WriteAudit(context.Background(), batch); err != nil {
+			log.Printf("jsonrpc: audit: dropping %d records: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case rec, ok := <-a.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= a.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// WriterAuditSink writes records to W, typically a file, as JSON lines.
+type WriterAuditSink struct {
+	W io.Writer
+}
+
+// WriteAudit implements AuditSink.
+func (s *WriterAuditSink) WriteAudit(ctx context.Context, recs []AuditRecord) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	enc := json.NewEncoder(buf)
+	for i := range recs {
+		if err := enc.Encode(&recs[i]); err != nil {
+			return err
+		}
+	}
+	_, err := s.W.Write(buf.Bytes())
+	return err
+}
+
+// KafkaAuditSink produces a message per record to Topic.
+type KafkaAuditSink struct {
+	Writer KafkaWriter
+	Topic  string
+}
+
+// WriteAudit implements AuditSink.
+func (s *KafkaAuditSink) WriteAudit(ctx context.Context, recs []AuditRecord) error {
+	msgs := make([]KafkaMessage, len(recs))
+	for i := range recs {
+		b, err := json.Marshal(&recs[i])
+		if err != nil {
+			return err
+		}
+		msgs[i] = KafkaMessage{Topic: s.Topic, Key: []byte(recs[i].Method), Value: b}
+	}
+	return s.Writer.WriteMessages(ctx, msgs...)
+}
+
+// HTTPAuditSink posts each batch of records to URL as a JSON array.
+type HTTPAuditSink struct {
+	URL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// WriteAudit implements AuditSink.
+func (s *HTTPAuditSink) WriteAudit(ctx context.Context, recs []AuditRecord) error {
+	b, err := json.Marshal(recs)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("jsonrpc: audit: unexpected status %v", resp.Status)
+	}
+	return nil
+}
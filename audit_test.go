@@ -0,0 +1,111 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type callerKey struct{}
+
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	batches [][]AuditRecord
+}
+
+func (s *memoryAuditSink) WriteAudit(ctx context.Context, recs []AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]AuditRecord(nil), recs...))
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	sink := &memoryAuditSink{}
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.Audit = NewAuditor(sink, AuditConfig{
+		BatchSize: 2,
+		Caller: func(ctx context.Context) string {
+			s, _ := ctx.Value(callerKey{}).(string)
+			return s
+		},
+		Redact: func(method string, params json.RawMessage) json.RawMessage {
+			return json.RawMessage(`"redacted"`)
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), callerKey{}, "alice")
+	server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`))
+	server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","id":2,"method":"sum"}`))
+	server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"unknown"}`))
+	server.Audit.Close()
+
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 2 || len(sink.batches[1]) != 1 {
+		t.Fatalf("invalid batches: %+v", sink.batches)
+	}
+	recs := append(sink.batches[0], sink.batches[1]...)
+	for _, rec := range recs {
+		if rec.Caller != "alice" || string(rec.Params) != `"redacted"` || rec.Time.IsZero() {
+			t.Errorf("invalid record: %+v", rec)
+		}
+	}
+	if recs[0].Method != "sum" || recs[0].ID != float64(1) || recs[0].Code != 0 || recs[0].Error != "" {
+		t.Errorf("invalid success record: %+v", recs[0])
+	}
+	if recs[1].Code != ErrInvalidParams.Code || recs[1].Error == "" {
+		t.Errorf("invalid failure record: %+v", recs[1])
+	}
+	if recs[2].Method != "unknown" || recs[2].Code != ErrMethodNotFound.Code || recs[2].ID != nil {
+		t.Errorf("invalid notification record: %+v", recs[2])
+	}
+
+	// records are dropped once closed
+	server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","id":3,"method":"sum"}`))
+	if n := server.Audit.Dropped(); n != 1 {
+		t.Errorf("invalid dropped records:\ngot: %v\nwant: 1", n)
+	}
+}
+
+func TestAuditSinks(t *testing.T) {
+	recs := []AuditRecord{{Method: "a"}, {Method: "b"}}
+
+	var buf bytes.Buffer
+	if err := (&WriterAuditSink{W: &buf}).WriteAudit(context.Background(), recs); err != nil {
+		t.Fatalf("writer sink: %v", err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 2 {
+		t.Errorf("writer sink lines:\ngot: %v\nwant: 2", n)
+	}
+
+	kafka := &fakeKafka{}
+	if err := (&KafkaAuditSink{Writer: kafka, Topic: "audit"}).WriteAudit(context.Background(), recs); err != nil {
+		t.Fatalf("kafka sink: %v", err)
+	}
+	if len(kafka.written) != 2 || kafka.written[1].Topic != "audit" || string(kafka.written[1].Key) != "b" {
+		t.Errorf("invalid kafka messages: %+v", kafka.written)
+	}
+
+	var posted []AuditRecord
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer ts.Close()
+	if err := (&HTTPAuditSink{URL: ts.URL}).WriteAudit(context.Background(), recs); err != nil {
+		t.Fatalf("http sink: %v", err)
+	}
+	if len(posted) != 2 || posted[0].Method != "a" {
+		t.Errorf("invalid posted records: %+v", posted)
+	}
+	failing := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if err := (&HTTPAuditSink{URL: failing.URL}).WriteAudit(context.Background(), recs); err == nil {
+		t.Errorf("http sink: expected an error on 503")
+	}
+}
@@ -0,0 +1,26 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// BasicAuth returns a middleware requiring HTTP Basic credentials accepted by
+// check, which should compare secrets with crypto/subtle. Requests without
+// valid credentials are answered with 401 and a challenge for realm. The
+// username is set on the context as the subject of the Principal, see
+// GetPrincipal.
+func BasicAuth(realm string, check func(ctx context.Context, user, password string) bool) Middleware {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok || !check(r.Context(), user, password) {
+				unauthorized(rw, challenge)
+				return
+			}
+			h.ServeHTTP(rw, r.WithContext(WithPrincipal(r.Context(), &Principal{Subject: user})))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	var user string
+	server := NewServer()
+	server.HandleFunc("whoami", func(ctx context.Context) (string, error) {
+		user = GetPrincipal(ctx).Subject
+		return user, nil
+	})
+	h := BasicAuth("tools", func(ctx context.Context, user, password string) bool {
+		return user == "admin" && subtle.ConstantTimeCompare([]byte(password), []byte("s3cret")) == 1
+	})(server)
+
+	tests := []struct {
+		user, password string
+		status         int
+	}{
+		{"admin", "s3cret", 200},
+		{"admin", "guess", 401},
+		{"", "", 401},
+	}
+	for _, test := range tests {
+		user = ""
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"whoami"}`))
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.password)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		if rw.Code != test.status {
+			t.Errorf("%v: invalid status:\ngot: %v\nwant: %v", test.user, rw.Code, test.status)
+		}
+		if test.status == 401 {
+			if got := rw.Header().Get("WWW-Authenticate"); got != `Basic realm="tools", charset="UTF-8"` {
+				t.Errorf("invalid challenge: %q", got)
+			}
+		} else if user != test.user {
+			t.Errorf("invalid user in context:\ngot: %q\nwant: %q", user, test.user)
+		}
+	}
+}
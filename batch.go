@@ -0,0 +1,353 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchRequest is a request sent by Client.Batch.
+type BatchRequest struct {
+	Method string
+	Params interface{}
+	// Notification sends the request without an id, it gets no response.
+	Notification bool
+}
+
+// Batch sends reqs in a single batch and returns their responses, in the
+// order of reqs. The responses of notifications are nil, a batch made of
+// notifications only gets none. If the responses don't match the calls, the
+// error wraps a *BatchMismatchError and the responses received are returned
+// along with it, nil for the calls without one.
+func (c *Client) Batch(ctx context.Context, reqs []BatchRequest, opts ...CallOption) ([]*Response, error) {
+	ctx, cancel := callContext(ctx, opts)
+	defer cancel()
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("jsonrpc: empty batch")
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	ids := make(map[int64]int, len(reqs)) // index of requests by id
+	defer func() {
+		for id := range ids {
+			c.releaseID(id)
+		}
+	}()
+	for i, r := range reqs {
+		p, err := json.Marshal(r.Params)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: marshaling params of %v: %w", r.Method, err)
+		}
+		req := &request{Method: r.Method, Params: p}
+		if !r.Notification {
+			req.ID = c.nextID()
+			ids[req.ID.(int64)] = i
+		}
+		b, err := req.bytes()
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: sending request: %w", err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte(']')
+
+	ctx = c.startSpan(ctx)
+	start := time.Now()
+	rc, endpoint, err := c.send(ctx, buf.Bytes())
+	if err != nil {
+		c.observeBatch(ctx, endpoint, reqs, nil, start, err)
+		return nil, fmt.Errorf("jsonrpc: sending request: %w", err)
+	}
+	defer rc.Close()
+	resps := make([]*Response, len(reqs))
+	if len(ids) == 0 {
+		io.Copy(ioutil.Discard, rc)
+		c.observeBatch(ctx, endpoint, reqs, resps, start, nil)
+		return resps, nil
+	}
+	report := func(id interface{}, duplicate bool) {
+		c.reportID(ctx, endpoint, id, nil, duplicate)
+	}
+	if err := decodeBatchResponse(rc, ids, resps, c.strict, report); err != nil {
+		c.observeBatch(ctx, endpoint, reqs, nil, start, err)
+		var mismatch *BatchMismatchError
+		if errors.As(err, &mismatch) {
+			return resps, fmt.Errorf("jsonrpc: reading response: %w", err)
+		}
+		return nil, fmt.Errorf("jsonrpc: reading response: %w", err)
+	}
+	c.observeBatch(ctx, endpoint, reqs, resps, start, nil)
+	return resps, nil
+}
+
+// observeBatch fires the hooks for each request of a batch, failed with err
+// or answered with resps.
+func (c *Client) observeBatch(ctx context.Context, endpoint string, reqs []BatchRequest, resps []*Response, start time.Time, err error) {
+	if c.hooks.OnCall == nil {
+		return
+	}
+	for i, r := range reqs {
+		rerr := err
+		if rerr == nil && resps[i] != nil {
+			rerr = resps[i].Err()
+		}
+		c.observe(ctx, endpoint, r.Method, r.Notification, start, rerr)
+	}
+}
+
+// decodeBatchResponse decodes the responses of a batch from r into resps, at
+// the index of the id they answer. Responses with an unknown or duplicate id
+// are passed to report and ignored. A *BatchMismatchError is returned if a
+// call has no response or, if strict is set, if a response was ignored. If
+// strict is set, responses which don't follow the specification are
+// rejected.
+func decodeBatchResponse(r io.Reader, ids map[int64]int, resps []*Response, strict bool, report func(id interface{}, duplicate bool)) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return errInvalidEncodedJSON
+	}
+	if firstByte(raw) != '[' {
+		// the batch as a whole was rejected
+		resp := &Response{}
+		if err := decodeResponseFromReader(bytes.NewReader(raw), resp, false, nil); err != nil {
+			return err
+		}
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		return errInvalidDecodedMessage
+	}
+	var msgs []rawMessage
+	if err := json.Unmarshal(raw, &msgs); err != nil {
+		return errInvalidDecodedMessage
+	}
+	var mismatch BatchMismatchError
+	for _, msg := range msgs {
+		if strict {
+			if err := checkResponse(&msg); err != nil {
+				return err
+			}
+		}
+		if msg.ID == nil && msg.Error != nil {
+			// an entry which couldn't be decoded by the server
+			return msg.Error
+		}
+		id, ok := msg.ID.(float64)
+		i, known := ids[int64(id)]
+		if !ok || !known || resps[i] != nil {
+			duplicate := ok && known
+			report(msg.ID, duplicate)
+			if duplicate {
+				mismatch.Duplicate = append(mismatch.Duplicate, msg.ID)
+			} else {
+				mismatch.Unexpected = append(mismatch.Unexpected, msg.ID)
+			}
+			continue
+		}
+		resp := &Response{id: msg.ID, result: msg.Result, error: msg.Error}
+		if resp.result == nil {
+			resp.result = null
+		}
+		resps[i] = resp
+	}
+	for id, i := range ids {
+		if resps[i] == nil {
+			mismatch.Missing = append(mismatch.Missing, id)
+		}
+	}
+	if len(mismatch.Missing) > 0 || strict && (len(mismatch.Unexpected) > 0 || len(mismatch.Duplicate) > 0) {
+		sort.Slice(mismatch.Missing, func(i, j int) bool { return mismatch.Missing[i] < mismatch.Missing[j] })
+		return &mismatch
+	}
+	return nil
+}
+
+// BatchMismatchError describes how the responses to a batch don't match its
+// calls. It wraps ErrInvalidResponse.
+type BatchMismatchError struct {
+	// Missing are the ids of the calls without a response.
+	Missing []int64
+	// Unexpected are the ids of the responses answering no call.
+	Unexpected []interface{}
+	// Duplicate are the ids of the calls answered more than once.
+	Duplicate []interface{}
+}
+
+func (e *BatchMismatchError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("missing responses to %v", e.Missing))
+	}
+	if len(e.Unexpected) > 0 {
+		parts = append(parts, fmt.Sprintf("unexpected responses %v", e.Unexpected))
+	}
+	if len(e.Duplicate) > 0 {
+		parts = append(parts, fmt.Sprintf("duplicate responses to %v", e.Duplicate))
+	}
+	return fmt.Sprintf("%v: %v", ErrInvalidResponse, strings.Join(parts, ", "))
+}
+
+func (e *BatchMismatchError) Unwrap() error {
+	return ErrInvalidResponse
+}
+
+// Parallel makes CallMany send its calls as concurrent requests instead of a
+// batch, for servers which don't support batches.
+func Parallel() CallOption {
+	return func(o *callOptions) {
+		o.parallel = true
+	}
+}
+
+// CallMany makes independent calls at once and returns their responses by
+// the key of calls, which is only a label:
+//
+//	resps, err := client.CallMany(ctx, map[string]jsonrpc.BatchRequest{
+//		"user":   {Method: "getUser", Params: id},
+//		"orders": {Method: "listOrders", Params: id},
+//	})
+//
+// The calls are sent as a batch, or in parallel with the Parallel option.
+// Notifications get a nil response. The error is that of the batch, or the
+// first error met sending the calls in parallel. As with Batch, the
+// responses received are returned along with a *BatchMismatchError. Errors
+// answered by the server are reported by the responses.
+func (c *Client) CallMany(ctx context.Context, calls map[string]BatchRequest, opts ...CallOption) (map[string]*Response, error) {
+	if len(calls) == 0 {
+		return map[string]*Response{}, nil
+	}
+	keys := make([]string, 0, len(calls))
+	reqs := make([]BatchRequest, 0, len(calls))
+	for k, r := range calls {
+		keys = append(keys, k)
+		reqs = append(reqs, r)
+	}
+	var resps []*Response
+	var err error
+	if newCallOptions(opts).parallel {
+		resps, err = c.parallel(ctx, reqs, opts)
+	} else {
+		resps, err = c.Batch(ctx, reqs, opts...)
+	}
+	if resps == nil {
+		return nil, err
+	}
+	m := make(map[string]*Response, len(keys))
+	for i, k := range keys {
+		m[k] = resps[i]
+	}
+	return m, err
+}
+
+// parallel sends reqs as concurrent requests and returns their responses.
+func (c *Client) parallel(ctx context.Context, reqs []BatchRequest, opts []CallOption) ([]*Response, error) {
+	resps := make([]*Response, len(reqs))
+	errs := make([]error, len(reqs))
+	var wg sync.WaitGroup
+	for i, r := range reqs {
+		wg.Add(1)
+		go func(i int, r BatchRequest) {
+			defer wg.Done()
+			if r.Notification {
+				errs[i] = c.Notify(ctx, r.Method, r.Params, opts...)
+				return
+			}
+			resps[i], errs[i] = c.Call(ctx, r.Method, r.Params, opts...)
+		}(i, r)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resps, nil
+}
+
+// BatchBuilder collects the requests of a batch along with the values their
+// results are decoded into, see Client.NewBatch.
+type BatchBuilder struct {
+	client *Client
+	reqs   []BatchRequest
+	calls  []*BatchCall // by request, nil for notifications
+}
+
+// BatchCall is a call of a batch, filled once the batch was sent.
+type BatchCall struct {
+	Method string
+	// Response is the response to the call.
+	Response *Response
+	// Err is the error the call failed with, answered by the server or
+	// met decoding the result.
+	Err error
+
+	out interface{}
+}
+
+// NewBatch returns an empty batch to be sent by c:
+//
+//	batch := client.NewBatch()
+//	var user User
+//	call := batch.Add("getUser", 1, &user)
+//	batch.Notify("audit", event)
+//	if err := batch.Send(ctx); err != nil {
+//		return err
+//	}
+//	if call.Err != nil {
+//		...
+//	}
+func (c *Client) NewBatch() *BatchBuilder {
+	return &BatchBuilder{client: c}
+}
+
+// Add adds a call for method to the batch, whose result is decoded into out
+// once the batch is sent, unless out is nil.
+func (b *BatchBuilder) Add(method string, params, out interface{}) *BatchCall {
+	call := &BatchCall{Method: method, out: out}
+	b.reqs = append(b.reqs, BatchRequest{Method: method, Params: params})
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Notify adds a notification for method to the batch.
+func (b *BatchBuilder) Notify(method string, params interface{}) {
+	b.reqs = append(b.reqs, BatchRequest{Method: method, Params: params, Notification: true})
+	b.calls = append(b.calls, nil)
+}
+
+// Send sends the batch and fills its calls. The error is that of the batch
+// as a whole, the errors of calls are reported by their Err field. Calls
+// are filled even if the responses don't match them, see Client.Batch,
+// those without a response failing with the error of the batch.
+func (b *BatchBuilder) Send(ctx context.Context, opts ...CallOption) error {
+	resps, err := b.client.Batch(ctx, b.reqs, opts...)
+	if resps == nil {
+		return err
+	}
+	for i, call := range b.calls {
+		if call == nil {
+			continue
+		}
+		if resps[i] == nil {
+			// no response, see BatchMismatchError
+			call.Err = err
+			continue
+		}
+		call.Response = resps[i]
+		if call.Err = resps[i].Err(); call.Err == nil && call.out != nil {
+			call.Err = resps[i].Decode(call.out)
+		}
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientBatch(t *testing.T) {
+	counter := &state{}
+	s := NewServer()
+	s.HandleFunc("sum", sum)
+	s.HandleFunc("counter", counter.increaseCounter)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	resps, err := client.Batch(context.Background(), []BatchRequest{
+		{Method: "sum", Params: Args{1, 2}},
+		{Method: "counter", Params: 2, Notification: true},
+		{Method: "unknown"},
+		{Method: "sum", Params: Args{2, 2}},
+	})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	var r1, r4 Reply
+	if err := resps[0].Decode(&r1); err != nil || r1.C != 3 {
+		t.Errorf("first call: %v, %v", r1, err)
+	}
+	if resps[1] != nil {
+		t.Errorf("notification answered: %v", resps[1])
+	}
+	if err := resps[2].Err(); err == nil || err.(*Error).Code != CodeMethodNotFound {
+		t.Errorf("unknown method:\ngot: %v\nwant: ErrMethodNotFound", err)
+	}
+	if err := resps[3].Decode(&r4); err != nil || r4.C != 4 {
+		t.Errorf("last call: %v, %v", r4, err)
+	}
+
+	resps, err = client.Batch(context.Background(), []BatchRequest{
+		{Method: "counter", Params: 1, Notification: true},
+		{Method: "counter", Params: 1, Notification: true},
+	})
+	if err != nil || len(resps) != 2 || resps[0] != nil || resps[1] != nil {
+		t.Errorf("notifications: %v, %v", resps, err)
+	}
+	if counter.N != 4 {
+		t.Errorf("bad state counter:\ngot: %v\nwant: 4", counter.N)
+	}
+
+	if _, err := client.Batch(context.Background(), nil); err == nil {
+		t.Errorf("empty batch sent")
+	}
+}
+
+func TestBatchBuilder(t *testing.T) {
+	s := NewServer()
+	s.HandleFunc("sum", sum)
+	s.HandleFunc("counter", (&state{}).increaseCounter)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	batch := NewClient(ts.URL).NewBatch()
+	var r1 Reply
+	var wrong []string
+	c1 := batch.Add("sum", Args{1, 2}, &r1)
+	batch.Notify("counter", 1)
+	c2 := batch.Add("unknown", nil, nil)
+	c3 := batch.Add("sum", Args{2, 2}, &wrong)
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if c1.Err != nil || r1.C != 3 {
+		t.Errorf("first call: %v, %v", r1, c1.Err)
+	}
+	if err, ok := AsError(c2.Err); !ok || err.Code != CodeMethodNotFound {
+		t.Errorf("unknown method:\ngot: %v\nwant: ErrMethodNotFound", c2.Err)
+	}
+	if c3.Err == nil || c3.Response == nil {
+		t.Errorf("result decoded into the wrong type: %v", wrong)
+	}
+}
+
+func TestCallMany(t *testing.T) {
+	s := NewServer()
+	s.HandleFunc("sum", sum)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	for _, opts := range [][]CallOption{nil, {Parallel()}} {
+		resps, err := client.CallMany(context.Background(), map[string]BatchRequest{
+			"small":   {Method: "sum", Params: Args{1, 2}},
+			"large":   {Method: "sum", Params: Args{20, 30}},
+			"unknown": {Method: "unknown"},
+		}, opts...)
+		if err != nil {
+			t.Fatalf("call many: %v", err)
+		}
+		var small, large Reply
+		if err := resps["small"].Decode(&small); err != nil || small.C != 3 {
+			t.Errorf("small: %v, %v", small, err)
+		}
+		if err := resps["large"].Decode(&large); err != nil || large.C != 50 {
+			t.Errorf("large: %v, %v", large, err)
+		}
+		if err, ok := AsError(resps["unknown"].Err()); !ok || err.Code != CodeMethodNotFound {
+			t.Errorf("unknown method:\ngot: %v\nwant: ErrMethodNotFound", resps["unknown"].Err())
+		}
+	}
+}
+
+func TestBatchMismatch(t *testing.T) {
+	body := `[{"jsonrpc":"2.0","id":1,"result":"a"},{"jsonrpc":"2.0","id":1,"result":"a"},{"jsonrpc":"2.0","id":99,"result":"x"}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		strict bool
+		want   BatchMismatchError
+	}{
+		{false, BatchMismatchError{Missing: []int64{2, 3}, Unexpected: []interface{}{float64(99)}, Duplicate: []interface{}{float64(1)}}},
+		{true, BatchMismatchError{Missing: []int64{2, 3}, Unexpected: []interface{}{float64(99)}, Duplicate: []interface{}{float64(1)}}},
+	}
+	for _, test := range tests {
+		var opts []ClientOption
+		if test.strict {
+			opts = append(opts, WithStrictResponses())
+		}
+		batch := NewClient(ts.URL, opts...).NewBatch()
+		var a string
+		c1 := batch.Add("a", nil, &a)
+		c2 := batch.Add("b", nil, nil)
+		batch.Add("c", nil, nil)
+		err := batch.Send(context.Background())
+		var mismatch *BatchMismatchError
+		if !errors.As(err, &mismatch) || !errors.Is(err, ErrInvalidResponse) {
+			t.Fatalf("strict %v: got error %v, want a BatchMismatchError", test.strict, err)
+		}
+		if !reflect.DeepEqual(*mismatch, test.want) {
+			t.Errorf("strict %v:\ngot: %+v\nwant: %+v", test.strict, *mismatch, test.want)
+		}
+		if c1.Err != nil || a != "a" {
+			t.Errorf("strict %v: call answered not filled: %q, %v", test.strict, a, c1.Err)
+		}
+		if c2.Err != err {
+			t.Errorf("strict %v: call without response: got error %v", test.strict, c2.Err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// benchServer returns a server with the handlers used by the benchmarks.
+func benchServer() *Server {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.HandleFunc("random", random)
+	return server
+}
+
+// nopResponseWriter discards responses, keeping httptest allocations out of
+// the numbers.
+type nopResponseWriter struct {
+	header http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header         { return w.header }
+func (w *nopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopResponseWriter) WriteHeader(statusCode int)  {}
+
+func benchmarkServeHTTP(b *testing.B, body string) {
+	server := benchServer()
+	req := httptest.NewRequest("POST", "localhost:8080", nil)
+	rw := &nopResponseWriter{header: http.Header{}}
+	reader := bytes.NewReader([]byte(body))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset([]byte(body))
+		req.Body = nopCloser{reader}
+		req.ContentLength = int64(len(body))
+		server.ServeHTTP(rw, req)
+	}
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+func BenchmarkServeHTTP(b *testing.B) {
+	b.Run("call", func(b *testing.B) {
+		benchmarkServeHTTP(b, `{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`)
+	})
+	b.Run("call_no_params", func(b *testing.B) {
+		benchmarkServeHTTP(b, `{"jsonrpc":"2.0","id":"abc","method":"random"}`)
+	})
+	b.Run("notification", func(b *testing.B) {
+		benchmarkServeHTTP(b, `{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}`)
+	})
+	b.Run("batch", func(b *testing.B) {
+		benchmarkServeHTTP(b, `[{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}},{"jsonrpc":"2.0","id":2,"method":"random"}]`)
+	})
+	b.Run("method_not_found", func(b *testing.B) {
+		benchmarkServeHTTP(b, `{"jsonrpc":"2.0","id":1,"method":"unknown"}`)
+	})
+}
+
+func BenchmarkServeMessage(b *testing.B) {
+	server := benchServer()
+	msg := []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		server.ServeMessage(context.Background(), msg)
+	}
+}
@@ -0,0 +1,505 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Bounds of the number of events buffered per subscription.
+const (
+	DefaultSubscriptionBuffer = 64
+	MaxSubscriptionBuffer     = 4096
+)
+
+// Event is a value published on a topic of a Broker.
+type Event struct {
+	// ID is the sequence number of the event, increasing with every event
+	// published by the broker, see SubscribeOptions.SinceID.
+	ID    uint64      `json:"id,omitempty"`
+	Topic string      `json:"topic,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	// Closed is set on the last event of a subscription closed by the
+	// broker, to the reason why.
+	Closed string `json:"closed,omitempty"`
+}
+
+// OverflowPolicy selects what happens to the events published while the
+// buffer of a subscription is full, because its client can't keep up.
+type OverflowPolicy string
+
+const (
+	// DropNewest drops the events published while the buffer is full.
+	DropNewest OverflowPolicy = "drop_newest"
+	// DropOldest drops the oldest buffered event to make room.
+	DropOldest OverflowPolicy = "drop_oldest"
+	// CloseOnOverflow ends the subscription with an event whose Closed is
+	// "overflow", for clients which can't work with gaps.
+	CloseOnOverflow OverflowPolicy = "close"
+)
+
+// SubscribeOptions describes a subscription, it can be decoded from the
+// params of a subscribe method.
+type SubscribeOptions struct {
+	// Topics are the patterns of the topics subscribed to.
+	Topics []string `json:"topics"`
+	// Buffer is the number of events buffered, defaults to
+	// DefaultSubscriptionBuffer and is bounded by MaxSubscriptionBuffer.
+	Buffer int `json:"buffer,omitempty"`
+	// Overflow defaults to DropNewest.
+	Overflow OverflowPolicy `json:"overflow,omitempty"`
+	// SinceID, if set, replays the events published after the event with
+	// this id, so that a client resubscribing after a disconnection misses
+	// none. It requires the broker to keep a history, see WithHistory.
+	SinceID uint64 `json:"since_id,omitempty"`
+	// Filter, if set, selects the events delivered by their data.
+	Filter Filter `json:"filter,omitempty"`
+}
+
+// EventLog keeps the recent events published by a Broker.
+type EventLog interface {
+	// Append adds ev, whose ID is greater than those of the events added
+	// before.
+	Append(ev Event) error
+	// Since returns the events added after the event id, in order. It
+	// reports false if some of them were discarded.
+	Since(id uint64) ([]Event, bool, error)
+	// LastID returns the ID of the last event added, zero if none, so that
+	// ids keep increasing across restarts with a persistent log.
+	LastID() uint64
+}
+
+// BrokerOption configures a Broker.
+type BrokerOption func(*Broker)
+
+// WithHistory keeps the events published in l, so that clients can resume
+// their subscriptions, see SubscribeOptions.SinceID.
+func WithHistory(l EventLog) BrokerOption {
+	return func(b *Broker) {
+		b.history = l
+		b.seq = l.LastID()
+	}
+}
+
+// BrokerStats counts the events handled by a Broker.
+type BrokerStats struct {
+	Subscriptions int
+	Published     uint64
+	Delivered     uint64
+	// Dropped counts the events dropped because subscriptions were full.
+	Dropped uint64
+}
+
+// Broker delivers the events published on topics to the subscriptions
+// matching them. Topics are dot separated, like "orders.eu.created", and
+// subscriptions match them by pattern: "*" matches exactly one segment, as
+// in "orders.*.created", and a trailing "#" matches any number of segments,
+// including none, as in "chat.#".
+//
+// Subscriptions are channels, which streaming handlers return to push the
+// events to clients as they are published:
+//
+//	server.HandleFunc("subscribe", func(ctx context.Context, topics []string) (<-chan jsonrpc.Event, error) {
+//		return broker.Subscribe(ctx, topics...)
+//	})
+//
+// Since the subscription lasts as long as the request, it is only streamed
+// when called on its own over HTTP, see Server.StreamFlushInterval.
+type Broker struct {
+	published, delivered, dropped uint64
+
+	// seqMu orders the ids of events and their addition to history, it is
+	// held with mu.RLock so that subscriptions taking mu see all the
+	// events up to seq in history
+	seqMu   sync.Mutex
+	seq     uint64
+	history EventLog
+
+	mu   sync.RWMutex
+	root topicNode
+	subs int
+}
+
+// topicNode is a node of the trie of subscription patterns, children are
+// indexed by segment.
+type topicNode struct {
+	children map[string]*topicNode
+	subs     map[*subscription]struct{} // patterns ending here
+	any      map[*subscription]struct{} // patterns ending here with "#"
+}
+
+type subscription struct {
+	b        *Broker
+	patterns [][]string
+	filter   []condition
+	overflow OverflowPolicy
+	done     chan struct{} // closed on overflow
+	mu       sync.Mutex
+	closed   bool
+	c        chan Event
+}
+
+// NewBroker returns a Broker without subscriptions.
+func NewBroker(opts ...BrokerOption) *Broker {
+	b := &Broker{}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// parsePattern splits pattern into segments, checking that "#" only ends it.
+func parsePattern(pattern string) ([]string, error) {
+	segs := strings.Split(pattern, ".")
+	for i, seg := range segs {
+		if seg == "" || seg == "#" && i != len(segs)-1 || seg != "#" && strings.Contains(seg, "#") ||
+			seg != "*" && strings.Contains(seg, "*") {
+			return nil, fmt.Errorf("invalid topic pattern %q", pattern)
+		}
+	}
+	return segs, nil
+}
+
+// Subscribe returns a channel receiving the events published on the topics
+// matching any of patterns until ctx is done, it is then closed. Events
+// published while the channel is full are dropped for this subscription.
+func (b *Broker) Subscribe(ctx context.Context, patterns ...string) (<-chan Event, error) {
+	return b.SubscribeWith(ctx, SubscribeOptions{Topics: patterns})
+}
+
+// SubscribeWith is like Subscribe with the buffering of the subscription
+// configured by opts.
+func (b *Broker) SubscribeWith(ctx context.Context, opts SubscribeOptions) (<-chan Event, error) {
+	if len(opts.Topics) == 0 {
+		return nil, ErrInvalidParamsf("no topic")
+	}
+	switch opts.Overflow {
+	case "":
+		opts.Overflow = DropNewest
+	case DropNewest, DropOldest, CloseOnOverflow:
+	default:
+		return nil, ErrInvalidParamsf("invalid overflow policy %q", opts.Overflow)
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultSubscriptionBuffer
+	} else if opts.Buffer > MaxSubscriptionBuffer {
+		opts.Buffer = MaxSubscriptionBuffer
+	}
+	sub := &subscription{
+		b:        b,
+		overflow: opts.Overflow,
+		done:     make(chan struct{}),
+	}
+	for _, p := range opts.Topics {
+		segs, err := parsePattern(p)
+		if err != nil {
+			return nil, ErrInvalidParamsf("%v", err)
+		}
+		sub.patterns = append(sub.patterns, segs)
+	}
+	if len(opts.Filter) > 0 {
+		var err error
+		if sub.filter, err = opts.Filter.compile(); err != nil {
+			return nil, ErrInvalidParamsf("%v", err)
+		}
+	}
+
+	b.mu.Lock()
+	// events published concurrently are either in the replay or delivered
+	// afterwards, since publishing holds mu.RLock
+	var replay []Event
+	if opts.SinceID > 0 {
+		var err error
+		if replay, err = b.replay(sub, opts.SinceID); err != nil {
+			b.mu.Unlock()
+			return nil, err
+		}
+	}
+	sub.c = make(chan Event, opts.Buffer+len(replay))
+	for _, ev := range replay {
+		sub.c <- ev
+	}
+	for _, segs := range sub.patterns {
+		b.root.insert(segs, sub)
+	}
+	b.subs++
+	b.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sub.done:
+		}
+		b.mu.Lock()
+		for _, segs := range sub.patterns {
+			b.root.remove(segs, sub)
+		}
+		b.subs--
+		b.mu.Unlock()
+		sub.mu.Lock()
+		if !sub.closed {
+			sub.closed = true
+			close(sub.c)
+		}
+		sub.mu.Unlock()
+	}()
+	return sub.c, nil
+}
+
+// Publish delivers data to the subscriptions matching topic and returns how
+// many got it.
+func (b *Broker) Publish(topic string, data interface{}) int {
+	atomic.AddUint64(&b.published, 1)
+	matched := make(map[*subscription]struct{})
+	b.mu.RLock()
+	b.seqMu.Lock()
+	b.seq++
+	ev := Event{ID: b.seq, Topic: topic, Data: data}
+	if b.history != nil {
+		if err := b.history.Append(ev); err != nil {
+			log.Printf("jsonrpc: keeping event %v: %v", ev.ID, err)
+		}
+	}
+	b.seqMu.Unlock()
+	b.root.match(strings.Split(topic, "."), matched)
+	b.mu.RUnlock()
+
+	n := 0
+	// the data is decoded for the filters once per event
+	var decoded interface{}
+	isDecoded := false
+	for sub := range matched {
+		if sub.filter != nil {
+			if !isDecoded {
+				decoded, isDecoded = normalize(ev.Data), true
+			}
+			if !matchFilter(sub.filter, decoded) {
+				continue
+			}
+		}
+		if sub.send(ev) {
+			n++
+		}
+	}
+	atomic.AddUint64(&b.delivered, uint64(n))
+	return n
+}
+
+// replay returns the events after id matching the patterns of sub.
+func (b *Broker) replay(sub *subscription, id uint64) ([]Event, error) {
+	if b.history == nil {
+		return nil, ErrInvalidParamsf("subscriptions can't be resumed")
+	}
+	events, complete, err := b.history.Since(id)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: reading event history: %w", err)
+	}
+	if !complete {
+		return nil, ErrInvalidParamsf("events after %v are no longer available", id)
+	}
+	var replay []Event
+	for _, ev := range events {
+		topic := strings.Split(ev.Topic, ".")
+		for _, segs := range sub.patterns {
+			if matchPattern(segs, topic) {
+				if sub.filter == nil || matchFilter(sub.filter, normalize(ev.Data)) {
+					replay = append(replay, ev)
+				}
+				break
+			}
+		}
+	}
+	return replay, nil
+}
+
+// matchPattern reports whether the pattern segs matches the topic segments.
+func matchPattern(segs, topic []string) bool {
+	for i, seg := range segs {
+		if seg == "#" {
+			return true
+		}
+		if i >= len(topic) || seg != "*" && seg != topic[i] {
+			return false
+		}
+	}
+	return len(segs) == len(topic)
+}
+
+// Stats returns the counters of b, for monitoring.
+func (b *Broker) Stats() BrokerStats {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	return BrokerStats{
+		Subscriptions: subs,
+		Published:     atomic.LoadUint64(&b.published),
+		Delivered:     atomic.LoadUint64(&b.delivered),
+		Dropped:       atomic.LoadUint64(&b.dropped),
+	}
+}
+
+// send delivers ev to s according to its overflow policy and reports
+// whether it was.
+func (s *subscription) send(ev Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.c <- ev:
+		return true
+	default:
+	}
+
+	switch s.overflow {
+	case DropOldest:
+		// only the receiver competes, which can only make room
+		select {
+		case <-s.c:
+		default:
+		}
+		s.c <- ev
+		atomic.AddUint64(&s.b.dropped, 1)
+		return true
+	case CloseOnOverflow:
+		// the oldest event makes room for the closing one
+		dropped := uint64(1)
+		select {
+		case <-s.c:
+			dropped++
+		default:
+		}
+		s.c <- Event{Closed: "overflow"}
+		s.closed = true
+		close(s.c)
+		close(s.done)
+		atomic.AddUint64(&s.b.dropped, dropped)
+		return false
+	default:
+		atomic.AddUint64(&s.b.dropped, 1)
+		return false
+	}
+}
+
+func (n *topicNode) insert(segs []string, sub *subscription) {
+	for _, seg := range segs {
+		if seg == "#" {
+			if n.any == nil {
+				n.any = make(map[*subscription]struct{})
+			}
+			n.any[sub] = struct{}{}
+			return
+		}
+		if n.children == nil {
+			n.children = make(map[string]*topicNode)
+		}
+		child := n.children[seg]
+		if child == nil {
+			child = &topicNode{}
+			n.children[seg] = child
+		}
+		n = child
+	}
+	if n.subs == nil {
+		n.subs = make(map[*subscription]struct{})
+	}
+	n.subs[sub] = struct{}{}
+}
+
+// remove removes sub from the pattern segs and reports whether n is left
+// empty, so that its parent drops it.
+func (n *topicNode) remove(segs []string, sub *subscription) bool {
+	switch {
+	case len(segs) == 0:
+		delete(n.subs, sub)
+	case segs[0] == "#":
+		delete(n.any, sub)
+	default:
+		if child := n.children[segs[0]]; child != nil && child.remove(segs[1:], sub) {
+			delete(n.children, segs[0])
+		}
+	}
+	return len(n.children) == 0 && len(n.subs) == 0 && len(n.any) == 0
+}
+
+// match adds the subscriptions matching the topic segs to matched.
+func (n *topicNode) match(segs []string, matched map[*subscription]struct{}) {
+	for sub := range n.any {
+		matched[sub] = struct{}{}
+	}
+	if len(segs) == 0 {
+		for sub := range n.subs {
+			matched[sub] = struct{}{}
+		}
+		return
+	}
+	if child := n.children[segs[0]]; child != nil {
+		child.match(segs[1:], matched)
+	}
+	if child := n.children["*"]; child != nil {
+		child.match(segs[1:], matched)
+	}
+}
+
+// MemoryEventLog is an EventLog keeping the last events in memory.
+type MemoryEventLog struct {
+	mu     sync.Mutex
+	events []Event // ring buffer
+	next   int
+	full   bool
+}
+
+// NewMemoryEventLog returns a MemoryEventLog keeping the last size events.
+func NewMemoryEventLog(size int) *MemoryEventLog {
+	return &MemoryEventLog{events: make([]Event, size)}
+}
+
+// Append implements EventLog.
+func (l *MemoryEventLog) Append(ev Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) == 0 {
+		return nil
+	}
+	l.events[l.next] = ev
+	if l.next++; l.next == len(l.events) {
+		l.next, l.full = 0, true
+	}
+	return nil
+}
+
+// Since implements EventLog.
+func (l *MemoryEventLog) Since(id uint64) ([]Event, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kept := l.events[:l.next]
+	if l.full {
+		kept = append(append([]Event(nil), l.events[l.next:]...), l.events[:l.next]...)
+	}
+	for i, ev := range kept {
+		if ev.ID > id {
+			// ids are consecutive, the oldest kept must follow id
+			complete := i > 0 || ev.ID == id+1
+			return append([]Event(nil), kept[i:]...), complete, nil
+		}
+	}
+	return nil, true, nil
+}
+
+// LastID implements EventLog.
+func (l *MemoryEventLog) LastID() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next == 0 && !l.full {
+		return 0
+	}
+	i := l.next - 1
+	if i < 0 {
+		i = len(l.events) - 1
+	}
+	return l.events[i].ID
+}
@@ -0,0 +1,266 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestBrokerWildcards(t *testing.T) {
+	b := NewBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	subs := map[string]<-chan Event{}
+	for _, p := range []string{"orders.eu.created", "orders.*.created", "orders.#", "chat.#", "*"} {
+		c, err := b.Subscribe(ctx, p)
+		if err != nil {
+			t.Fatalf("subscribe %v: %v", p, err)
+		}
+		subs[p] = c
+	}
+	for _, p := range []string{"orders.#.created", "orders.*x", "", "orders..created"} {
+		if _, err := b.Subscribe(ctx, p); err == nil {
+			t.Errorf("invalid pattern %q accepted", p)
+		}
+	}
+
+	tests := []struct {
+		topic string
+		want  []string
+	}{
+		{"orders.eu.created", []string{"orders.#", "orders.*.created", "orders.eu.created"}},
+		{"orders.us.created", []string{"orders.#", "orders.*.created"}},
+		{"orders", []string{"*", "orders.#"}},
+		{"chat.room.1", []string{"chat.#"}},
+		{"users.created", nil},
+	}
+	for _, test := range tests {
+		if n := b.Publish(test.topic, 1); n != len(test.want) {
+			t.Errorf("%v: delivered to %v subscriptions, want %v", test.topic, n, len(test.want))
+		}
+		var got []string
+		for p, c := range subs {
+			select {
+			case ev := <-c:
+				if ev.Topic != test.topic {
+					t.Errorf("%v: invalid event %+v", p, ev)
+				}
+				got = append(got, p)
+			default:
+			}
+		}
+		sort.Strings(got)
+		if len(got) != len(test.want) || len(got) > 0 && !equalStrings(got, test.want) {
+			t.Errorf("%v: matched %v, want %v", test.topic, got, test.want)
+		}
+	}
+
+	cancel()
+	for p, c := range subs {
+		if _, ok := <-c; ok {
+			t.Errorf("%v: subscription not closed", p)
+		}
+	}
+	b.mu.RLock()
+	empty := len(b.root.children) == 0 && len(b.root.subs) == 0
+	b.mu.RUnlock()
+	if !empty {
+		t.Errorf("trie not pruned after unsubscribing")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestBrokerStream(t *testing.T) {
+	b := NewBroker()
+	server := NewServer()
+	server.HandleFunc("subscribe", func(ctx context.Context, topics []string) (<-chan Event, error) {
+		return b.Subscribe(ctx, topics...)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"subscribe","params":["orders.*"]}`))).WithContext(ctx)
+	rw := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(rw, req)
+		close(done)
+	}()
+	for b.Stats().Subscriptions == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	b.Publish("orders.created", "o1")
+	b.Publish("users.created", "u1")
+	b.Publish("orders.paid", "o1")
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	want := `{"jsonrpc":"2.0","id":1,"result":{"id":1,"topic":"orders.created","data":"o1"}}
+{"jsonrpc":"2.0","id":1,"result":{"id":3,"topic":"orders.paid","data":"o1"}}
+`
+	if got := rw.Body.String(); got != want {
+		t.Errorf("invalid stream:\ngot: %v\nwant: %v", got, want)
+	}
+}
+
+func TestBrokerOverflow(t *testing.T) {
+	b := NewBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"t"}, Overflow: "block"}); err == nil {
+		t.Errorf("invalid overflow policy accepted")
+	}
+
+	subs := map[OverflowPolicy]<-chan Event{}
+	for _, p := range []OverflowPolicy{"", DropOldest, CloseOnOverflow} {
+		c, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"t"}, Buffer: 2, Overflow: p})
+		if err != nil {
+			t.Fatalf("subscribe %v: %v", p, err)
+		}
+		subs[p] = c
+	}
+	for i := 1; i <= 3; i++ {
+		b.Publish("t", i)
+	}
+
+	tests := []struct {
+		policy OverflowPolicy
+		want   []Event
+	}{
+		{"", []Event{{1, "t", 1, ""}, {2, "t", 2, ""}}},
+		{DropOldest, []Event{{2, "t", 2, ""}, {3, "t", 3, ""}}},
+		{CloseOnOverflow, []Event{{2, "t", 2, ""}, {Closed: "overflow"}}},
+	}
+	for _, test := range tests {
+		var got []Event
+		c := subs[test.policy]
+	recv:
+		for len(got) < 3 {
+			select {
+			case ev, ok := <-c:
+				if !ok {
+					break recv
+				}
+				got = append(got, ev)
+			case <-time.After(10 * time.Millisecond):
+				break recv
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.policy, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q: got %+v, want %+v", test.policy, got, test.want)
+				break
+			}
+		}
+	}
+	if _, ok := <-subs[CloseOnOverflow]; ok {
+		t.Errorf("subscription not closed on overflow")
+	}
+
+	want := BrokerStats{Subscriptions: 2, Published: 3, Delivered: 7, Dropped: 4}
+	for i := 0; i < 100 && b.Stats() != want; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := b.Stats(); got != want {
+		t.Errorf("invalid stats:\ngot: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestBrokerResume(t *testing.T) {
+	b := NewBroker(WithHistory(NewMemoryEventLog(4)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := NewBroker().SubscribeWith(ctx, SubscribeOptions{Topics: []string{"#"}, SinceID: 1}); err == nil {
+		t.Errorf("resumed without history")
+	}
+
+	for i := 1; i <= 6; i++ {
+		topic := "orders"
+		if i%2 == 0 {
+			topic = "users"
+		}
+		b.Publish(topic, i)
+	}
+	c, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"orders"}, SinceID: 2})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	b.Publish("orders", 7)
+	var ids []uint64
+	for len(ids) < 3 {
+		ids = append(ids, (<-c).ID)
+	}
+	if want := []uint64{3, 5, 7}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("invalid events:\ngot: %v\nwant: %v", ids, want)
+	}
+
+	if _, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"orders"}, SinceID: 1}); err == nil {
+		t.Errorf("resumed with discarded events")
+	}
+	if c, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"orders"}, SinceID: 7}); err != nil || len(c) != 0 {
+		t.Errorf("resuming up to date: %v, %v events", err, len(c))
+	}
+	b2 := NewBroker(WithHistory(b.history))
+	b2.Publish("orders", 8)
+	if last := b.history.LastID(); last != 8 {
+		t.Errorf("ids restarted with the history:\ngot: %v\nwant: 8", last)
+	}
+}
+
+func TestBrokerFilter(t *testing.T) {
+	type Customer struct {
+		Country string `json:"country"`
+	}
+	type Order struct {
+		ID       int      `json:"id"`
+		Status   string   `json:"status"`
+		Amount   float64  `json:"amount"`
+		Customer Customer `json:"customer"`
+	}
+	b := NewBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"orders"}, Filter: Filter{"amount": map[string]interface{}{"gt": true}}}); err == nil {
+		t.Errorf("invalid filter accepted")
+	}
+
+	var filter Filter
+	json.Unmarshal([]byte(`{"status":"paid","amount":{"gte":100,"lt":1000},"customer.country":{"in":["FR","DE"]}}`), &filter)
+	c, err := b.SubscribeWith(ctx, SubscribeOptions{Topics: []string{"orders"}, Filter: filter})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	orders := []Order{
+		{1, "paid", 100, Customer{"FR"}},
+		{2, "pending", 150, Customer{"FR"}},
+		{3, "paid", 1000, Customer{"DE"}},
+		{4, "paid", 999.5, Customer{"US"}},
+		{5, "paid", 500, Customer{"DE"}},
+	}
+	for _, o := range orders {
+		b.Publish("orders", o)
+	}
+	var ids []int
+	for len(c) > 0 {
+		ids = append(ids, (<-c).Data.(Order).ID)
+	}
+	if want := []int{1, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("invalid events:\ngot: %v\nwant: %v", ids, want)
+	}
+}
@@ -3,19 +3,52 @@ package jsonrpc
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"sync/atomic"
+	"net/url"
+	"sync"
+	"time"
 )
 
 // Client represents a JSON-RPC Client.
 type Client struct {
 	next       int64
+	rr         uint64
 	url        string
 	httpClient httpClient
+	resolver   Resolver
+	proxy      func(*http.Request) (*url.URL, error)
+	tlsConfig  *tls.Config
+
+	retryAttempts int
+	retryBackoff  time.Duration
+
+	signKeyID  string
+	signSecret []byte
+
+	strict  bool
+	hooks   ClientHooks
+	tracing bool
+	b3      bool
+
+	compressMin int
+
+	failover *failover
+	limiter  *limiter
+	offline  *offlineQueue
+
+	interceptors []Interceptor
+	invoker      Invoker // transmit wrapped by the interceptors
+
+	inflight sync.Map // ids of the requests in flight
+
+	mu        sync.Mutex
+	endpoints []string
+	stop      context.CancelFunc
 }
 
 type httpClient interface {
@@ -24,34 +57,141 @@ type httpClient interface {
 
 var errClientContextCanceled = errors.New("context canceled by the client")
 
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http.Client used to send requests. Any transport
+// can be plugged in this way, e.g. an HTTP/3 round tripper from quic-go.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithStrictResponses makes the client reject the responses which don't
+// follow the specification, such as a wrong jsonrpc version, both result and
+// error set, or an id answering another request, with an error wrapping
+// ErrInvalidResponse. It helps with third-party servers of dubious quality.
+func WithStrictResponses() ClientOption {
+	return func(c *Client) {
+		c.strict = true
+	}
+}
+
 // NewClient returns a new Client to handle requests to a JSON-RPC server.
-// TODO: support custom httpClients
-func NewClient(url string) *Client {
-	return &Client{url: url, httpClient: http.DefaultClient}
+func NewClient(url string, opts ...ClientOption) *Client {
+	c := &Client{url: url}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = c.newHTTPClient()
+	}
+	if c.offline != nil {
+		c.offline.load()
+	}
+	w, watch := c.resolver.(Watcher)
+	if watch || c.failover != nil || c.offline != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.stop = cancel
+		if watch {
+			go c.watchEndpoints(ctx, w)
+		}
+		if c.failover != nil {
+			go c.failover.probe(ctx, c)
+		}
+		if c.offline != nil {
+			go c.flushOffline(ctx)
+		}
+	}
+	return c
 }
 
-// Call executes the named method, waits for it to complete, and returns a JSONRPC response.
-func (c *Client) Call(ctx context.Context, method string, params interface{}) (*Response, error) {
-	done := make(chan error)
-	resp := &Response{}
-	go c.call(ctx, method, params, resp, done)
-	select {
-	case <-ctx.Done():
-		return nil, fmt.Errorf("jsonrpc: %v", ctx.Err())
-	case err := <-done:
-		return resp, err
+// Close releases the resources held by the client, like endpoint watches and
+// failover probes. Requests left in an offline queue are kept by its
+// store, if any.
+func (c *Client) Close() error {
+	if c.stop != nil {
+		c.stop()
 	}
+	return nil
+}
+
+// CallOption configures a single call of a Client.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout  time.Duration
+	hedge    time.Duration
+	parallel bool
+}
+
+func newCallOptions(opts []CallOption) callOptions {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTimeout bounds the whole call, retries included, to d, independently of
+// the timeouts of the HTTP client.
+func WithTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+	}
+}
+
+// callContext returns the context of a call made with opts.
+func callContext(ctx context.Context, opts []CallOption) (context.Context, context.CancelFunc) {
+	o := newCallOptions(opts)
+	if o.hedge > 0 {
+		ctx = context.WithValue(ctx, hedgeKey{}, o.hedge)
+	}
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
+	}
+	return ctx, func() {}
+}
+
+// Call executes the named method, waits for it to complete, and returns a JSONRPC response.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, opts ...CallOption) (*Response, error) {
+	ctx, cancel := callContext(ctx, opts)
+	defer cancel()
+	return c.invoke(ctx, &ClientRequest{Method: method, Params: params})
 }
 
 // Notify executes the named method and discards the response.
-func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
-	done := make(chan error)
-	go c.notify(ctx, method, params, done)
+func (c *Client) Notify(ctx context.Context, method string, params interface{}, opts ...CallOption) error {
+	ctx, cancel := callContext(ctx, opts)
+	defer cancel()
+	_, err := c.invoke(ctx, &ClientRequest{Method: method, Params: params, Notification: true})
+	return err
+}
+
+// transmit sends req to the server, it ends the chain of interceptors.
+func (c *Client) transmit(ctx context.Context, req *ClientRequest) (*Response, error) {
+	if c.offline != nil && c.offline.len() > 0 {
+		return nil, c.queue(ctx, req)
+	}
+	done := make(chan error, 1)
+	var resp *Response
+	if req.Notification {
+		go c.notify(ctx, req.Method, req.Params, done)
+	} else {
+		resp = &Response{}
+		go c.call(ctx, req.Method, req.Params, resp, done)
+	}
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("jsonrpc: %v", ctx.Err())
+		return nil, fmt.Errorf("jsonrpc: %v", ctx.Err())
 	case err := <-done:
-		return err
+		if c.offline != nil && isUnreachable(err) && ctx.Err() == nil {
+			if qerr := c.queue(ctx, req); qerr == nil || errors.Is(qerr, ErrQueued) {
+				return nil, qerr
+			}
+		}
+		return resp, err
 	}
 }
 
@@ -62,7 +202,15 @@ func (c *Client) notify(ctx context.Context, method string, params interface{},
 		return
 	}
 	req := &request{ID: nil, Method: method, Params: p}
-	rc, err := c.send(ctx, req)
+	b, err := req.bytes()
+	if err != nil {
+		done <- fmt.Errorf("jsonrpc: sending request: %w", err)
+		return
+	}
+	ctx = c.startSpan(ctx)
+	start := time.Now()
+	rc, endpoint, err := c.send(ctx, b)
+	c.observe(ctx, endpoint, method, true, start, err)
 	if err != nil {
 		done <- fmt.Errorf("jsonrpc: sending request: %w", err)
 		return
@@ -78,43 +226,125 @@ func (c *Client) call(ctx context.Context, method string, params interface{}, re
 		done <- fmt.Errorf("jsonrpc: marshaling params: %w", err)
 		return
 	}
-	req := &request{ID: c.nextID(), Method: method, Params: p}
-	rc, err := c.send(ctx, req)
+	for attempt := 1; ; attempt++ {
+		req := &request{ID: c.nextID(), Method: method, Params: p}
+		err := c.exchange(ctx, req, resp)
+		c.releaseID(req.ID)
+		if err != nil {
+			done <- err
+			return
+		}
+		after, ok := RetryAfter(resp.Err())
+		if !ok || attempt >= c.retryAttempts {
+			break
+		}
+		if after <= 0 {
+			after = c.retryBackoff
+		}
+		t := time.NewTimer(after)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			done <- nil
+			return
+		case <-t.C:
+		}
+		*resp = Response{}
+	}
+	done <- nil
+}
+
+// roundTrip sends req and decodes its response into resp.
+func (c *Client) roundTrip(ctx context.Context, req *request, resp *Response) error {
+	b, err := req.bytes()
 	if err != nil {
-		done <- fmt.Errorf("jsonrpc: sending request: %w", err)
-		return
+		return fmt.Errorf("jsonrpc: sending request: %w", err)
+	}
+	ctx = c.startSpan(ctx)
+	start := time.Now()
+	rc, endpoint, err := c.send(ctx, b)
+	if err != nil {
+		c.observe(ctx, endpoint, req.Method, false, start, err)
+		return fmt.Errorf("jsonrpc: sending request: %w", err)
 	}
 	defer rc.Close()
 
-	if err := decodeResponseFromReader(rc, resp); err != nil {
-		done <- fmt.Errorf("jsonrpc: reading response: %w", err)
-		return
+	err = decodeResponseFromReader(rc, resp, c.strict, req.ID)
+	if err == nil && !sameID(resp.id, req.ID) && !(resp.id == nil && resp.error != nil) {
+		// errors answering requests which couldn't be decoded have a null id
+		c.reportID(ctx, endpoint, resp.id, req.ID, false)
 	}
-
-	done <- nil
+	var idErr *IDError
+	if errors.As(err, &idErr) {
+		err = c.reportID(ctx, endpoint, idErr.ID, idErr.Expected, false)
+	}
+	if err != nil {
+		c.observe(ctx, endpoint, req.Method, false, start, err)
+		return fmt.Errorf("jsonrpc: reading response: %w", err)
+	}
+	c.observe(ctx, endpoint, req.Method, false, start, resp.Err())
+	return nil
 }
 
-// send sends data from r to the http server and returns a reader of the response
-func (c *Client) send(ctx context.Context, req *request) (io.ReadCloser, error) {
-	b, err := req.bytes()
+// newHTTPRequest returns the HTTP request posting the encoded message b to
+// url, compressed, signed and traced as configured.
+func (c *Client) newHTTPRequest(ctx context.Context, url string, b []byte) (*http.Request, error) {
+	body, compressed, err := c.compressBody(b)
 	if err != nil {
 		return nil, err
 	}
-	hreq, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewBuffer(b))
+	hreq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	hreq.Header.Set("Content-Type", "application/json")
 	hreq.Header.Set("Accept", "application/json")
+	if c.compressMin > 0 {
+		hreq.Header.Set("Accept-Encoding", "gzip")
+	}
+	if compressed {
+		hreq.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.signSecret != nil {
+		c.signRequest(hreq, body)
+	}
+	c.injectSpan(ctx, hreq.Header)
+	return hreq, nil
+}
 
-	hres, err := c.httpClient.Do(hreq)
+// send sends the encoded message b to the http server and returns a reader
+// of the response and the endpoint it was sent to
+func (c *Client) send(ctx context.Context, b []byte) (io.ReadCloser, string, error) {
+	url, err := c.endpoint(ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	hreq, err := c.newHTTPRequest(ctx, url, b)
+	if err != nil {
+		return nil, url, err
 	}
-	return hres.Body, nil
-}
 
-// nextID returns the next id using atomic operations
-func (c *Client) nextID() interface{} {
-	return atomic.AddInt64(&c.next, 1)
+	release := func() {}
+	if c.limiter != nil {
+		if release, err = c.limiter.acquire(ctx); err != nil {
+			return nil, url, err
+		}
+	}
+	hres, err := c.httpClient.Do(hreq)
+	if err != nil {
+		release()
+		if c.failover != nil && ctx.Err() == nil {
+			c.failover.setDown(url, true)
+		}
+		return nil, url, unreachableError{err}
+	}
+	rc, err := decodeResponseBody(hres)
+	if err != nil {
+		release()
+		return nil, url, err
+	}
+	if c.limiter != nil {
+		rc = &releaseCloser{ReadCloser: rc, release: release}
+	}
+	return rc, url, nil
 }
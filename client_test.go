@@ -3,7 +3,10 @@ package jsonrpc
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -43,7 +46,7 @@ var port = ":4545"
 
 func TestCallSync(t *testing.T) {
 	counter := &state{}
-	go startServer(t, counter)
+	startServer(t, counter)
 
 	client := NewClient("http://localhost" + port)
 
@@ -101,8 +104,13 @@ func TestCallSync(t *testing.T) {
 }
 
 func BenchmarkClientSync(b *testing.B) {
+	s := NewServer()
+	s.HandleFunc("counter", (&state{}).increaseCounter)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
 	b.Run("call", func(b *testing.B) {
-		client := NewClient("http://localhost" + port)
+		client := NewClient(ts.URL)
 		for i := 0; i < b.N; i++ {
 			var reply int
 			resp, err := client.Call(context.Background(), "counter", 6)
@@ -115,7 +123,7 @@ func BenchmarkClientSync(b *testing.B) {
 		}
 	})
 	b.Run("notify", func(b *testing.B) {
-		client := NewClient("http://localhost" + port)
+		client := NewClient(ts.URL)
 		for i := 0; i < b.N; i++ {
 			err := client.Notify(context.Background(), "counter", 6)
 			if err != nil {
@@ -132,18 +140,17 @@ func startServer(t *testing.T, counter *state) {
 	s.HandleFunc("random", random)
 	s.HandleFunc("counter", counter.increaseCounter)
 
-	if err := http.ListenAndServe(port, s); err != nil {
-		t.Errorf("starting server: %v", err)
+	// Listen before returning so that the first call doesn't race the server.
+	l, err := net.Listen("tcp", port)
+	if err != nil {
+		t.Fatalf("starting server: %v", err)
 	}
+	go http.Serve(l, s)
 }
 
 func startServerAddCORS(t *testing.T, counter *state) {
 	s := NewServer()
-	s.Cors = map[string]string{
-		"Access-Control-Allow-Origin":"*",
-		"Access-Control-Allow-Methods":"POST,GET,OPTIONS",
-		"Access-Control-Allow-Headers":"Content-Type",
-	}
+	s.CORS = CORSConfig{AllowedOrigins: []string{"*"}}
 	s.HandleFunc("sum", sum)
 	s.HandleFunc("random", random)
 	s.HandleFunc("counter", counter.increaseCounter)
@@ -152,3 +159,119 @@ func startServerAddCORS(t *testing.T, counter *state) {
 		t.Errorf("starting server: %v", err)
 	}
 }
+
+type countingTransport struct {
+	n int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	s := NewServer()
+	s.HandleFunc("random", random)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	client := NewClient(ts.URL, WithHTTPClient(&http.Client{Transport: transport}))
+	if _, err := client.Call(context.Background(), "random", nil); err != nil {
+		t.Fatalf("random: error not expected: %v", err)
+	}
+	if n := atomic.LoadInt32(&transport.n); n != 1 {
+		t.Errorf("custom transport not used:\ngot: %v round trips\nwant: 1", n)
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	s := NewServer()
+	s.HandleFunc("wait", func(ctx context.Context) (bool, error) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+		return true, nil
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	start := time.Now()
+	if _, err := client.Call(context.Background(), "wait", nil, WithTimeout(10*time.Millisecond)); err == nil {
+		t.Errorf("call not bounded by its timeout")
+	}
+	if d := time.Since(start); d > 80*time.Millisecond {
+		t.Errorf("call timed out after %v", d)
+	}
+	if _, err := client.Call(context.Background(), "wait", nil, WithTimeout(time.Second)); err != nil {
+		t.Errorf("call within its timeout: %v", err)
+	}
+}
+
+func TestCallCancelPropagates(t *testing.T) {
+	stopped := make(chan struct{})
+	s := NewServer()
+	s.HandleFunc("wait", func(ctx context.Context) (bool, error) {
+		<-ctx.Done()
+		close(stopped)
+		return false, ctx.Err()
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	NewClient(ts.URL).Call(ctx, "wait", nil)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Errorf("handler not cancelled with the call")
+	}
+}
+
+func TestStrictResponses(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(body))
+	}))
+	defer ts.Close()
+	lax, strict := NewClient(ts.URL), NewClient(ts.URL, WithStrictResponses())
+
+	tests := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"jsonrpc":"2.0","id":1,"result":3}`, true},
+		{`{"jsonrpc":"2.0","id":1,"result":null}`, true},
+		{`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`, true},
+		{`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`, true},
+		{`{"jsonrpc":"2.0","id":null,"result":3}`, false},
+		{`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"}}`, false},
+		{`{"jsonrpc":"1.0","id":1,"result":3}`, false},
+		{`{"id":1,"result":3}`, false},
+		{`{"jsonrpc":"2.0","id":1,"result":3,"error":{"code":-32601,"message":"Method not found"}}`, false},
+		{`{"jsonrpc":"2.0","id":1}`, false},
+		{`{"jsonrpc":"2.0","id":2,"result":3}`, false},
+		{`{"jsonrpc":"2.0","id":"1","result":3}`, false},
+	}
+	for _, test := range tests {
+		body = test.body
+		// ids restart at 1 for every call
+		lax.next, strict.next = 0, 0
+		if _, err := lax.Call(context.Background(), "m", nil); err != nil {
+			t.Errorf("%v: rejected by the lax client: %v", test.body, err)
+		}
+		_, err := strict.Call(context.Background(), "m", nil)
+		if test.valid && err != nil {
+			t.Errorf("%v: rejected: %v", test.body, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("%v: invalid response error:\ngot: %v\nwant: ErrInvalidResponse", test.body, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package jsonrpc
+
+import (
+	"context"
+	"time"
+)
+
+// CallInfo describes a request sent by a client, for metrics and logging.
+type CallInfo struct {
+	// Endpoint is the URL the request was sent to, "" if none could be
+	// resolved.
+	Endpoint     string
+	Method       string
+	Notification bool
+	// Duration is the time from sending the request to decoding its
+	// response.
+	Duration time.Duration
+	// Err is the error the request failed with: a transport error, or
+	// the *Error the server answered with, see AsError.
+	Err error
+	// Span is the span of the request, with WithTracing.
+	Span SpanContext
+}
+
+// ClientHooks are observational callbacks fired by the client, the
+// counterpart of the server Hooks.
+type ClientHooks struct {
+	// OnCall is called once a request was answered or failed, every
+	// attempt of retried calls and every request of batches included.
+	OnCall func(ctx context.Context, info *CallInfo)
+	// OnIDError is called with the responses whose id doesn't answer
+	// the request they were received for. Those responses fail with err
+	// with WithStrictResponses. Otherwise, unexpected responses to batches
+	// are ignored and those to calls are accepted, as they can only answer
+	// them over HTTP.
+	OnIDError func(ctx context.Context, err *IDError)
+}
+
+// WithHooks sets the hooks of the client, to collect metrics such as the
+// number of calls by endpoint and method, their error codes and their
+// latency.
+func WithHooks(h ClientHooks) ClientOption {
+	return func(c *Client) {
+		c.hooks = h
+	}
+}
+
+// observe fires the hooks for the request for method sent to endpoint at
+// start.
+func (c *Client) observe(ctx context.Context, endpoint, method string, notification bool, start time.Time, err error) {
+	if c.hooks.OnCall == nil {
+		return
+	}
+	info := &CallInfo{
+		Endpoint:     endpoint,
+		Method:       method,
+		Notification: notification,
+		Duration:     time.Since(start),
+		Err:          err,
+	}
+	if c.tracing {
+		info.Span, _ = GetSpanContext(ctx)
+	}
+	c.hooks.OnCall(ctx, info)
+}
@@ -0,0 +1,52 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestClientHooks(t *testing.T) {
+	s := NewServer()
+	s.HandleFunc("sum", sum)
+	s.HandleFunc("counter", (&state{}).increaseCounter)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	client := NewClient(ts.URL, WithHooks(ClientHooks{
+		OnCall: func(ctx context.Context, info *CallInfo) {
+			code := 0
+			if err, ok := AsError(info.Err); ok {
+				code = err.Code
+			}
+			if info.Endpoint != ts.URL || info.Duration <= 0 {
+				t.Errorf("invalid call info: %+v", info)
+			}
+			mu.Lock()
+			calls = append(calls, fmt.Sprintf("%v %v %v", info.Method, info.Notification, code))
+			mu.Unlock()
+		},
+	}))
+
+	ctx := context.Background()
+	client.Call(ctx, "sum", Args{1, 2})
+	client.Call(ctx, "unknown", nil)
+	client.Notify(ctx, "counter", 1)
+	client.Batch(ctx, []BatchRequest{{Method: "sum", Params: Args{1, 1}}, {Method: "counter", Params: 1, Notification: true}})
+
+	want := []string{
+		"sum false 0",
+		"unknown false -32601",
+		"counter true 0",
+		"sum false 0",
+		"counter true 0",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("invalid calls:\ngot: %q\nwant: %q", calls, want)
+	}
+}
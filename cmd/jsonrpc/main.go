@@ -0,0 +1,152 @@
+// Command jsonrpc calls JSON-RPC 2.0 endpoints over HTTP, for operations and
+// debugging.
+//
+// Usage:
+//
+//	jsonrpc [-timeout d] call URL METHOD [PARAMS]
+//	jsonrpc [-timeout d] notify URL METHOD [PARAMS]
+//	jsonrpc [-timeout d] batch URL FILE
+//
+// PARAMS is a JSON value. FILE holds a JSON array of requests, "-" reads it
+// from the standard input. Results and errors are pretty printed, the exit
+// status is 1 if the server answered with an error.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/echovl/jsonrpc"
+)
+
+const usage = `usage:
+  jsonrpc [-timeout d] call URL METHOD [PARAMS]
+  jsonrpc [-timeout d] notify URL METHOD [PARAMS]
+  jsonrpc [-timeout d] batch URL FILE
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("jsonrpc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	args = fs.Args()
+	if len(args) < 3 {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var err error
+	switch cmd, url := args[0], args[1]; {
+	case cmd == "call" && len(args) <= 4:
+		err = call(ctx, stdout, url, args[2], paramsArg(args[3:]))
+	case cmd == "notify" && len(args) <= 4:
+		err = jsonrpc.NewClient(url).Notify(ctx, args[2], paramsArg(args[3:]))
+	case cmd == "batch" && len(args) == 3:
+		err = batch(ctx, stdin, stdout, url, args[2])
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	var rpcErr *jsonrpc.Error
+	if errors.As(err, &rpcErr) {
+		printJSON(stdout, rpcErr)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// paramsArg returns the optional params argument.
+func paramsArg(args []string) interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	return json.RawMessage(args[0])
+}
+
+func call(ctx context.Context, stdout io.Writer, url, method string, params interface{}) error {
+	if p, ok := params.(json.RawMessage); ok && !json.Valid(p) {
+		return fmt.Errorf("invalid params: %s", p)
+	}
+	resp, err := jsonrpc.NewClient(url).Call(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	var result json.RawMessage
+	if err := resp.Decode(&result); err != nil {
+		return err
+	}
+	printJSON(stdout, result)
+	return nil
+}
+
+func batch(ctx context.Context, stdin io.Reader, stdout io.Writer, url, file string) error {
+	var b []byte
+	var err error
+	if file == "-" {
+		b, err = ioutil.ReadAll(stdin)
+	} else {
+		b, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+	if !json.Valid(b) {
+		return fmt.Errorf("invalid batch in %v", file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		// notifications only
+		return nil
+	}
+	printJSON(stdout, json.RawMessage(body))
+	return nil
+}
+
+func printJSON(w io.Writer, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(w, v)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", b)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/echovl/jsonrpc"
+)
+
+type Args struct {
+	A, B int
+}
+
+func TestRun(t *testing.T) {
+	server := jsonrpc.NewServer()
+	server.HandleFunc("sum", func(ctx context.Context, a Args) (int, error) {
+		return a.A + a.B, nil
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		status int
+		out    string
+	}{
+		{[]string{"call", ts.URL, "sum", `{"A":1,"B":2}`}, "", 0, "3\n"},
+		{[]string{"call", ts.URL, "unknown"}, "", 1, "{\n  \"code\": -32601,\n  \"message\": \"Method not found\"\n}\n"},
+		{[]string{"notify", ts.URL, "sum", `{"A":1,"B":2}`}, "", 0, ""},
+		{[]string{"batch", ts.URL, "-"}, `[{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":1}}]`, 0,
+			"[\n  {\n    \"jsonrpc\": \"2.0\",\n    \"id\": 1,\n    \"result\": 2\n  }\n]\n"},
+		{[]string{"call", ts.URL, "sum", `{"A":`}, "", 1, ""},
+		{[]string{"call", ts.URL}, "", 2, ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if status != tt.status || stdout.String() != tt.out {
+			t.Errorf("%v:\ngot: %v %q (%s)\nwant: %v %q", tt.args, status, stdout.String(), stderr.String(), tt.status, tt.out)
+		}
+	}
+}
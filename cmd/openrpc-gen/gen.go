@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// document is the subset of an OpenRPC document used by the generator.
+type document struct {
+	Info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Methods    []method `json:"methods"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type method struct {
+	Name           string         `json:"name"`
+	Summary        string         `json:"summary"`
+	Description    string         `json:"description"`
+	ParamStructure string         `json:"paramStructure"`
+	Params         []contentDescr `json:"params"`
+	Result         *contentDescr  `json:"result"`
+}
+
+type contentDescr struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 interface{}        `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties interface{}        `json:"additionalProperties"`
+}
+
+// generator accumulates the generated declarations.
+type generator struct {
+	doc   *document
+	types bytes.Buffer
+	// named holds the types already generated.
+	named   map[string]bool
+	imports map[string]bool
+}
+
+func generate(doc *document, pkg string) ([]byte, error) {
+	g := &generator{doc: doc, named: map[string]bool{}, imports: map[string]bool{"context": true}}
+
+	// components first, in a stable order
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := g.namedType(goName(name), doc.Components.Schemas[name]); err != nil {
+			return nil, fmt.Errorf("schema %v: %w", name, err)
+		}
+	}
+
+	var iface, register, stubs bytes.Buffer
+	for _, m := range doc.Methods {
+		params, result, err := g.signature(m)
+		if err != nil {
+			return nil, fmt.Errorf("method %v: %w", m.Name, err)
+		}
+		name := goName(m.Name)
+		if doc := firstNonEmpty(m.Summary, m.Description); doc != "" {
+			fmt.Fprintf(&iface, "\t// %v %v\n", name, oneLine(doc))
+		}
+		fmt.Fprintf(&iface, "\t%v(ctx context.Context%v) (%v, error)\n", name, params, result)
+		fmt.Fprintf(&register, "\tif err := s.HandleFunc(%q, svc.%v); err != nil {\n\t\treturn err\n\t}\n", m.Name, name)
+		fmt.Fprintf(&stubs, "\n// %v implements Service.\nfunc (UnimplementedService) %v(ctx context.Context%v) (result %v, err error) {\n\treturn result, jsonrpc.ErrMethodNotFound\n}\n",
+			name, name, params, result)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by openrpc-gen from %v %v. DO NOT EDIT.\n\n", doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&src, "package %v\n\nimport (\n", pkg)
+	var imports []string
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&src, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&src, "\n\t%q\n)\n", "github.com/echovl/jsonrpc")
+	src.Write(g.types.Bytes())
+	fmt.Fprintf(&src, "\n// Service is implemented by the server of the API.\ntype Service interface {\n%v}\n", iface.String())
+	fmt.Fprintf(&src, "\n// Register registers the methods of svc in s.\nfunc Register(s *jsonrpc.Server, svc Service) error {\n%v\treturn nil\n}\n", register.String())
+	fmt.Fprintf(&src, "\n// UnimplementedService answers every method with Method not found, embed it\n// to implement Service progressively.\ntype UnimplementedService struct{}\n%v", stubs.String())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, src.Bytes())
+	}
+	return out, nil
+}
+
+// signature returns the params, after the context, and the result of the
+// handler of m. Params are the fields of a struct, which also decodes them
+// from an array unless they are by-name, see positional.
+func (g *generator) signature(m method) (params, result string, err error) {
+	name := goName(m.Name)
+	if len(m.Params) > 0 {
+		s := &schema{Type: "object", Properties: map[string]*schema{}}
+		for _, p := range m.Params {
+			s.Properties[p.Name] = p.Schema
+			if p.Required {
+				s.Required = append(s.Required, p.Name)
+			}
+		}
+		t, err := g.namedType(name+"Params", s)
+		if err != nil {
+			return "", "", err
+		}
+		if m.ParamStructure != "by-name" {
+			g.positional(t, m)
+		}
+		params = ", params " + t
+	}
+
+	result = "interface{}"
+	if m.Result != nil {
+		if result, err = g.goType(name+"Result", m.Result.Schema); err != nil {
+			return "", "", err
+		}
+	}
+	return params, result, nil
+}
+
+// positional declares the UnmarshalJSON method of the params type t of m,
+// decoding its fields from an array, in the order of the params of m, or
+// from an object unless the params are by-position.
+func (g *generator) positional(t string, m method) {
+	g.imports["bytes"], g.imports["encoding/json"], g.imports["fmt"] = true, true, true
+	required := 0
+	for _, p := range m.Params {
+		if p.Required {
+			required++
+		}
+	}
+	fields := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		fields[i] = "&p." + goName(p.Name)
+	}
+
+	w := &g.types
+	fmt.Fprintf(w, "\n// UnmarshalJSON decodes the params of %v", m.Name)
+	if m.ParamStructure == "by-position" {
+		fmt.Fprintf(w, " by position.\n")
+	} else {
+		fmt.Fprintf(w, " by position or by name.\n")
+	}
+	fmt.Fprintf(w, "func (p *%v) UnmarshalJSON(b []byte) error {\n", t)
+	fmt.Fprintf(w, "\tif b = bytes.TrimSpace(b); len(b) == 0 || b[0] != '[' {\n")
+	if m.ParamStructure == "by-position" {
+		fmt.Fprintf(w, "\t\treturn fmt.Errorf(\"params of %v must be an array\")\n", m.Name)
+	} else {
+		fmt.Fprintf(w, "\t\ttype byName %v\n\t\treturn json.Unmarshal(b, (*byName)(p))\n", t)
+	}
+	fmt.Fprintf(w, "\t}\n")
+	fmt.Fprintf(w, "\tvar values []json.RawMessage\n\tif err := json.Unmarshal(b, &values); err != nil {\n\t\treturn err\n\t}\n")
+	switch {
+	case required == len(fields):
+		fmt.Fprintf(w, "\tif len(values) != %v {\n", required)
+		fmt.Fprintf(w, "\t\treturn fmt.Errorf(\"%%v params instead of %v\", len(values))\n\t}\n", required)
+	case required == 0:
+		fmt.Fprintf(w, "\tif len(values) > %v {\n", len(fields))
+		fmt.Fprintf(w, "\t\treturn fmt.Errorf(\"%%v params instead of at most %v\", len(values))\n\t}\n", len(fields))
+	default:
+		fmt.Fprintf(w, "\tif len(values) < %v || len(values) > %v {\n", required, len(fields))
+		fmt.Fprintf(w, "\t\treturn fmt.Errorf(\"%%v params instead of %v to %v\", len(values))\n\t}\n", required, len(fields))
+	}
+	fmt.Fprintf(w, "\tfields := []interface{}{%v}\n", strings.Join(fields, ", "))
+	fmt.Fprintf(w, "\tfor i, v := range values {\n\t\tif err := json.Unmarshal(v, fields[i]); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n\treturn nil\n}\n")
+}
+
+// goType returns the Go type of s, declaring a type named name for objects.
+func (g *generator) goType(name string, s *schema) (string, error) {
+	if s == nil {
+		return "interface{}", nil
+	}
+	if s.Ref != "" {
+		const prefix = "#/components/schemas/"
+		if !strings.HasPrefix(s.Ref, prefix) {
+			return "", fmt.Errorf("unsupported reference %v", s.Ref)
+		}
+		ref := strings.TrimPrefix(s.Ref, prefix)
+		if _, ok := g.doc.Components.Schemas[ref]; !ok {
+			return "", fmt.Errorf("unknown reference %v", s.Ref)
+		}
+		return goName(ref), nil
+	}
+	typ, _ := s.Type.(string)
+	switch typ {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		t, err := g.goType(name+"Item", s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + t, nil
+	case "object":
+		if len(s.Properties) > 0 {
+			return g.namedType(name, s)
+		}
+		if ap, ok := s.AdditionalProperties.(map[string]interface{}); ok && len(ap) > 0 {
+			if t, ok := ap["type"].(string); ok {
+				elem, err := g.goType(name+"Value", &schema{Type: t})
+				if err != nil {
+					return "", err
+				}
+				return "map[string]" + elem, nil
+			}
+		}
+		return "map[string]interface{}", nil
+	}
+	// unions, nulls and untyped schemas
+	return "interface{}", nil
+}
+
+// namedType declares the struct type name for the object s.
+func (g *generator) namedType(name string, s *schema) (string, error) {
+	if g.named[name] {
+		return name, nil
+	}
+	g.named[name] = true
+	if typ, _ := s.Type.(string); typ != "object" || len(s.Properties) == 0 {
+		t, err := g.goType(name, s)
+		if err != nil {
+			return "", err
+		}
+		if t != name {
+			fmt.Fprintf(&g.types, "\n%vtype %v %v\n", comment(name, s.Description), name, t)
+		}
+		return name, nil
+	}
+
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	props := make([]string, 0, len(s.Properties))
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+
+	var fields bytes.Buffer
+	for _, p := range props {
+		ps := s.Properties[p]
+		field := goName(p)
+		t, err := g.goType(name+field, ps)
+		if err != nil {
+			return "", err
+		}
+		tag := p
+		if !required[p] {
+			tag += ",omitempty"
+			if !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && t != "interface{}" {
+				t = "*" + t
+			}
+		}
+		if ps != nil && ps.Description != "" {
+			fmt.Fprintf(&fields, "\t// %v\n", oneLine(ps.Description))
+		}
+		fmt.Fprintf(&fields, "\t%v %v `json:%q`\n", field, t, tag)
+	}
+	fmt.Fprintf(&g.types, "\n%vtype %v struct {\n%v}\n", comment(name, s.Description), name, fields.String())
+	return name, nil
+}
+
+func comment(name, description string) string {
+	if description == "" {
+		return ""
+	}
+	return fmt.Sprintf("// %v %v\n", name, oneLine(description))
+}
+
+// goName returns the exported Go identifier of the JSON-RPC name s, such as
+// UserGet for "user.get" or "user_get".
+func goName(s string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+// initialisms are the words written in upper case in Go identifiers.
+var initialisms = map[string]bool{
+	"api": true, "http": true, "id": true, "ip": true, "json": true,
+	"uri": true, "url": true, "uuid": true,
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/petstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(&doc, "petstore")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	for _, want := range []string{
+		"type Pet struct {",
+		"\tID int64 `json:\"id\"`\n\t// Name of the pet.\n\tName string  `json:\"name\"`",
+		"\tTag  *string `json:\"tag,omitempty\"`",
+		"type ListPetsParams struct {",
+		"\tListPets(ctx context.Context, params ListPetsParams) ([]Pet, error)",
+		"\tGetPet(ctx context.Context, params GetPetParams) (Pet, error)",
+		"func (p *GetPetParams) UnmarshalJSON(b []byte) error {",
+		"\tStoreStats(ctx context.Context) (StoreStatsResult, error)",
+		"\tByTag   map[string]int64 `json:\"by_tag,omitempty\"`",
+		"\tUpdated *time.Time       `json:\"updated,omitempty\"`",
+		`s.HandleFunc("store.stats", svc.StoreStats)`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code doesn't contain %q:\n%s", want, src)
+		}
+	}
+
+	// the generated code must compile against the module
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := ioutil.TempDir(".", "gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "api.go"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "api_test.go"), []byte(generatedTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(gobin, "test", "./"+dir).CombinedOutput(); err != nil {
+		t.Errorf("generated code doesn't compile or work: %v\n%s\n%s", err, out, src)
+	}
+}
+
+// generatedTest checks that the generated params decode as declared.
+const generatedTest = `package petstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/echovl/jsonrpc"
+)
+
+type pets struct{ UnimplementedService }
+
+func (pets) GetPet(ctx context.Context, p GetPetParams) (Pet, error) {
+	return Pet{ID: p.ID, Name: "rex"}, nil
+}
+
+func (pets) ListPets(ctx context.Context, p ListPetsParams) ([]Pet, error) {
+	var list []Pet
+	for _, tag := range p.Tags {
+		tag := tag
+		list = append(list, Pet{ID: *p.Limit, Name: "rex", Tag: &tag})
+	}
+	return list, nil
+}
+
+func TestParams(t *testing.T) {
+	s := jsonrpc.NewServer()
+	if err := Register(s, pets{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct{ params, want string }{
+		{"[7]", "\"result\":{\"id\":7,\"name\":\"rex\"}"},
+		{"{\"id\":7}", "\"code\":-32602"},
+		{"[7,8]", "\"code\":-32602"},
+	} {
+		req := "{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"get_pet\",\"params\":" + test.params + "}"
+		if got := s.ServeMessage(context.Background(), []byte(req)); !strings.Contains(string(got), test.want) {
+			t.Errorf("%v: got %s, want %v", test.params, got, test.want)
+		}
+	}
+	for _, params := range []string{"[1,[\"a\"]]", "{\"limit\":1,\"tags\":[\"a\"]}"} {
+		req := "{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"list_pets\",\"params\":" + params + "}"
+		want := "\"result\":[{\"id\":1,\"name\":\"rex\",\"tag\":\"a\"}]"
+		if got := s.ServeMessage(context.Background(), []byte(req)); !strings.Contains(string(got), want) {
+			t.Errorf("%v: got %s, want %v", params, got, want)
+		}
+	}
+}
+`
@@ -0,0 +1,56 @@
+// Command openrpc-gen generates Go code from an OpenRPC document: the param
+// and result types of its methods, a Service interface with a method per
+// JSON-RPC method, and a Register function binding a Service to a
+// jsonrpc.Server.
+//
+// Usage:
+//
+//	openrpc-gen [-pkg name] [-o file] openrpc.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	pkg := flag.String("pkg", "api", "package name of the generated code")
+	out := flag.String("o", "", "output file, the standard output by default")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: openrpc-gen [-pkg name] [-o file] openrpc.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "openrpc-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, pkg, out string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("parsing %v: %w", path, err)
+	}
+	src, err := generate(&doc, pkg)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -0,0 +1,350 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeCodec converts the values of a type between their encoding in params
+// and results and the one of encoding/json, so that handlers take and
+// return domain types which don't encode the way clients expect, see
+// Server.RegisterCodec.
+type TypeCodec struct {
+	// Encode returns the value encoding v, of the registered type, in
+	// results. Results are encoded by encoding/json if it is nil.
+	Encode func(v interface{}) (interface{}, error)
+	// Decode returns the value of the registered type encoded as raw in
+	// params. Params are decoded by encoding/json if it is nil.
+	Decode func(raw json.RawMessage) (interface{}, error)
+}
+
+// RegisterCodec makes s encode and decode the values of the type of v with
+// c, at any depth of params and results. It applies to the methods
+// registered afterwards. As with Server.TimeFormat, values held by
+// interfaces or streamed aren't converted.
+//
+//	server.RegisterCodec(Color(0), jsonrpc.TypeCodec{
+//		Encode: func(v interface{}) (interface{}, error) { return v.(Color).String(), nil },
+//		Decode: func(raw json.RawMessage) (interface{}, error) { return parseColor(raw) },
+//	})
+func (s *Server) RegisterCodec(v interface{}, c TypeCodec) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return errors.New("jsonrpc: codec of a nil type")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s.codecs.Store(t, c)
+	return nil
+}
+
+// typeCodec is a codec with the paths to the values of its type in the
+// encoding of params or results.
+type typeCodec struct {
+	TypeCodec
+	t     reflect.Type
+	paths [][]string
+}
+
+// typeCodecs returns the codecs of s applying to the values of t.
+func (s *Server) typeCodecs(t reflect.Type) []typeCodec {
+	var codecs []typeCodec
+	s.codecs.Range(func(k, v interface{}) bool {
+		ct := k.(reflect.Type)
+		if paths := typePaths(t, ct); len(paths) > 0 {
+			codecs = append(codecs, typeCodec{TypeCodec: v.(TypeCodec), t: ct, paths: paths})
+		}
+		return true
+	})
+	return codecs
+}
+
+// codecValue is a value decoded by a codec, to be set at path in params once
+// they are decoded, see setCodecValues.
+type codecValue struct {
+	path  []string
+	value reflect.Value
+}
+
+// decodeCodecs decodes the values of params handled by codecs, which are
+// replaced by null in the returned params. The decoded values are set in the
+// params of the handler by setCodecValues, encoding/json can't decode them
+// into types it can't encode, such as structs with unexported fields.
+func decodeCodecs(params json.RawMessage, codecs []typeCodec) (json.RawMessage, []codecValue, error) {
+	var values []codecValue
+	params, err := convertCodecs(params, codecs, func(c *typeCodec, path []string, v interface{}) (interface{}, error) {
+		if c.Decode == nil {
+			return v, nil
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		d, err := c.Decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		dv := reflect.ValueOf(d)
+		if dv.IsValid() && dv.Kind() == reflect.Ptr && dv.Type().Elem() == c.t {
+			dv = dv.Elem()
+		}
+		if !dv.IsValid() || dv.Type() != c.t {
+			return nil, fmt.Errorf("codec of %v decoded a %T", c.t, d)
+		}
+		values = append(values, codecValue{append([]string(nil), path...), dv})
+		return nil, nil
+	})
+	return params, values, err
+}
+
+// setCodecValues sets the values decoded by decodeCodecs in v, the decoded
+// params.
+func setCodecValues(v reflect.Value, values []codecValue) error {
+	for _, cv := range values {
+		if err := setPath(v, cv.path, cv.value); err != nil {
+			return fmt.Errorf("%v: %w", strings.Join(cv.path, "."), err)
+		}
+	}
+	return nil
+}
+
+func setPath(v reflect.Value, path []string, value reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if len(path) == 0 {
+		v.Set(value)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok := fieldByName(v, path[0], true)
+		if !ok {
+			return errors.New("no such field")
+		}
+		return setPath(f, path[1:], value)
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i >= v.Len() {
+			return errors.New("no such element")
+		}
+		return setPath(v.Index(i), path[1:], value)
+	case reflect.Map:
+		k, err := mapKey(v.Type().Key(), path[0])
+		if err != nil {
+			return err
+		}
+		// map elements aren't addressable, the element is set back
+		e := reflect.New(v.Type().Elem()).Elem()
+		if cur := v.MapIndex(k); cur.IsValid() {
+			e.Set(cur)
+		}
+		if err := setPath(e, path[1:], value); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(k, e)
+		return nil
+	}
+	return fmt.Errorf("unexpected %v", v.Type())
+}
+
+// encodeCodecs encodes the values of ret handled by codecs in result, the
+// encoding of ret by encoding/json.
+func encodeCodecs(result json.RawMessage, ret interface{}, codecs []typeCodec) (json.RawMessage, error) {
+	rv := reflect.ValueOf(ret)
+	return convertCodecs(result, codecs, func(c *typeCodec, path []string, v interface{}) (interface{}, error) {
+		if c.Encode == nil {
+			return v, nil
+		}
+		e, ok := valueAt(rv, path)
+		if !ok {
+			return nil, fmt.Errorf("no value of %v at %v", c.t, strings.Join(path, "."))
+		}
+		return c.Encode(e.Interface())
+	})
+}
+
+// valueAt returns the value at path in v, as encoded by encoding/json.
+func valueAt(v reflect.Value, path []string) (reflect.Value, bool) {
+	for {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		if len(path) == 0 {
+			return v, true
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := fieldByName(v, path[0], false)
+			if !ok {
+				return v, false
+			}
+			v = f
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(path[0])
+			if err != nil || i >= v.Len() {
+				return v, false
+			}
+			v = v.Index(i)
+		case reflect.Map:
+			k, err := mapKey(v.Type().Key(), path[0])
+			if err != nil {
+				return v, false
+			}
+			if v = v.MapIndex(k); !v.IsValid() {
+				return v, false
+			}
+		default:
+			return v, false
+		}
+		path = path[1:]
+	}
+}
+
+// fieldByName returns the field of the struct v encoded as name by
+// encoding/json, looking into embedded structs as collectTypePaths does. Nil
+// embedded pointers are allocated if alloc is set.
+func fieldByName(v reflect.Value, name string, alloc bool) (reflect.Value, bool) {
+	t := v.Type()
+	var embedded []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" {
+			embedded = append(embedded, i)
+			continue
+		}
+		fname := f.Name
+		if n := strings.Split(tag, ",")[0]; n != "" {
+			fname = n
+		}
+		if strings.EqualFold(fname, name) {
+			return v.Field(i), true
+		}
+	}
+	// fields of embedded structs are promoted, unless shadowed
+	for _, i := range embedded {
+		e := v.Field(i)
+		if e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				if !alloc || !e.CanSet() || e.Type().Elem().Kind() != reflect.Struct {
+					continue
+				}
+				e.Set(reflect.New(e.Type().Elem()))
+			}
+			e = e.Elem()
+		}
+		if e.Kind() != reflect.Struct {
+			continue
+		}
+		if f, ok := fieldByName(e, name, alloc); ok {
+			return f, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// mapKey returns the key of type t encoded as k by encoding/json.
+func mapKey(t reflect.Type, k string) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(k).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported key type %v", t)
+}
+
+// convertCodecs replaces the values of b at the paths of codecs by the ones
+// returned by conv, given their concrete path.
+func convertCodecs(b json.RawMessage, codecs []typeCodec, conv func(c *typeCodec, path []string, v interface{}) (interface{}, error)) (json.RawMessage, error) {
+	if isNullParams(b) {
+		return b, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	for i := range codecs {
+		c := &codecs[i]
+		for _, path := range c.paths {
+			var err error
+			v, err = convertPathAt(v, path, nil, func(at []string, v interface{}) (interface{}, error) {
+				return conv(c, at, v)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// convertPathAt is convertPath passing conv the concrete path of the values,
+// the keys and indexes leading to them.
+func convertPathAt(v interface{}, path, at []string, conv func([]string, interface{}) (interface{}, error)) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if len(path) == 0 {
+		return conv(at, v)
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if path[0] != "*" && !strings.EqualFold(k, path[0]) {
+				continue
+			}
+			c, err := convertPathAt(e, path[1:], append(at, k), conv)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+			v[k] = c
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return v, nil
+		}
+		for i, e := range v {
+			c, err := convertPathAt(e, path[1:], append(at, strconv.Itoa(i)), conv)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", i, err)
+			}
+			v[i] = c
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,137 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type Shade int
+
+var shadeNames = []string{"red", "green", "blue"}
+
+type Cents int64
+
+func TestTypeCodecs(t *testing.T) {
+	server := NewServer()
+	server.RegisterCodec(Shade(0), TypeCodec{
+		Encode: func(v interface{}) (interface{}, error) {
+			return shadeNames[v.(Shade)], nil
+		},
+		Decode: func(raw json.RawMessage) (interface{}, error) {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			for i, name := range shadeNames {
+				if name == s {
+					return Shade(i), nil
+				}
+			}
+			return nil, fmt.Errorf("unknown color %q", s)
+		},
+	})
+	// amounts as decimal strings
+	server.RegisterCodec(Cents(0), TypeCodec{
+		Encode: func(v interface{}) (interface{}, error) {
+			c := v.(Cents)
+			return fmt.Sprintf("%d.%02d", c/100, c%100), nil
+		},
+		Decode: func(raw json.RawMessage) (interface{}, error) {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			var units, hundredths int64
+			if _, err := fmt.Sscanf(strings.Replace(s, ".", " ", 1), "%d %d", &units, &hundredths); err != nil {
+				return nil, err
+			}
+			return Cents(units*100 + hundredths), nil
+		},
+	})
+	type Item struct {
+		Color  Shade   `json:"color"`
+		Prices []Cents `json:"prices"`
+	}
+	server.HandleFunc("repaint", func(ctx context.Context, item Item) (Item, error) {
+		item.Color = (item.Color + 1) % 3
+		for i := range item.Prices {
+			item.Prices[i] += 100
+		}
+		return item, nil
+	})
+	server.HandleFunc("next", func(ctx context.Context, c Shade) (Shade, error) {
+		return (c + 1) % 3, nil
+	})
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"repaint","params":{"color":"green","prices":["1.50","0.99"]}}`, `{"jsonrpc":"2.0","id":1,"result":{"color":"blue","prices":["2.50","1.99"]}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"next","params":"blue"}`, `{"jsonrpc":"2.0","id":1,"result":"red"}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"next","params":"pink"}`, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":"unknown color \"pink\""}}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
+
+// Money doesn't survive encoding/json, only its codec knows its cents.
+type Money struct{ cents int64 }
+
+func TestTypeCodecsUnexportedFields(t *testing.T) {
+	server := NewServer()
+	server.RegisterCodec(Money{}, TypeCodec{
+		Encode: func(v interface{}) (interface{}, error) {
+			return v.(Money).cents, nil
+		},
+		Decode: func(raw json.RawMessage) (interface{}, error) {
+			var c int64
+			err := json.Unmarshal(raw, &c)
+			return Money{c}, err
+		},
+	})
+	type Invoice struct {
+		Total  Money            `json:"total"`
+		Lines  []Money          `json:"lines"`
+		ByUser map[string]Money `json:"by_user"`
+		Refund *Money           `json:"refund,omitempty"`
+	}
+	server.HandleFunc("double", func(ctx context.Context, inv Invoice) (Invoice, error) {
+		inv.Total.cents *= 2
+		for i := range inv.Lines {
+			inv.Lines[i].cents *= 2
+		}
+		for k, m := range inv.ByUser {
+			inv.ByUser[k] = Money{m.cents * 2}
+		}
+		if inv.Refund != nil {
+			inv.Refund.cents *= 2
+		}
+		return inv, nil
+	})
+	server.HandleFunc("answer", func(ctx context.Context) (Money, error) {
+		return Money{42}, nil
+	})
+	server.HandleFunc("negate", func(ctx context.Context, m Money) (Money, error) {
+		return Money{-m.cents}, nil
+	})
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"answer"}`, `{"jsonrpc":"2.0","id":1,"result":42}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"negate","params":7}`, `{"jsonrpc":"2.0","id":1,"result":-7}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"double","params":{"total":5,"lines":[1,2],"by_user":{"ann":3},"refund":4}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"by_user":{"ann":6},"lines":[2,4],"refund":8,"total":10}}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// coerceParams converts the values of params whose JSON type obviously
+// mismatches the one of their Go type t to it, see Server.LenientParams. The
+// values of types with a codec are left to it.
+func coerceParams(params json.RawMessage, t reflect.Type, codecs []typeCodec) (json.RawMessage, error) {
+	if isNullParams(params) {
+		return params, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, changed := coerce(v, t, codecs)
+	if !changed {
+		return params, nil
+	}
+	return json.Marshal(v)
+}
+
+// coerce returns v converted to the JSON type of t, and whether it changed.
+func coerce(v interface{}, t reflect.Type, codecs []typeCodec) (interface{}, bool) {
+	if v == nil {
+		return v, false
+	}
+	t = indirect(t)
+	for i := range codecs {
+		if codecs[i].t == t {
+			return v, false
+		}
+	}
+	if reflect.PtrTo(t).Implements(typeOfUnmarshaler) {
+		return v, false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s, ok := v.(string); ok {
+			if s = strings.TrimSpace(s); isNumber(s) {
+				return json.Number(s), true
+			}
+		}
+	case reflect.Bool:
+		switch v := v.(type) {
+		case json.Number:
+			switch v {
+			case "0":
+				return false, true
+			case "1":
+				return true, true
+			}
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, true
+			}
+		}
+	case reflect.String:
+		switch v := v.(type) {
+		case json.Number:
+			return string(v), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return v, false
+		}
+		a, ok := v.([]interface{})
+		if !ok {
+			return v, false
+		}
+		changed := false
+		for i, e := range a {
+			var c bool
+			a[i], c = coerce(e, t.Elem(), codecs)
+			changed = changed || c
+		}
+		return a, changed
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v, false
+		}
+		changed := false
+		for k, e := range m {
+			var c bool
+			m[k], c = coerce(e, t.Elem(), codecs)
+			changed = changed || c
+		}
+		return m, changed
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v, false
+		}
+		changed := false
+		// squashed fields are already nested, see unsquash
+		for _, f := range nestedJSONFields(t) {
+			if f.quoted {
+				continue
+			}
+			for k, e := range m {
+				if strings.EqualFold(k, f.name) {
+					var c bool
+					m[k], c = coerce(e, f.typ, codecs)
+					changed = changed || c
+				}
+			}
+		}
+		return m, changed
+	}
+	return v, false
+}
+
+// isNumber reports whether s is a JSON number.
+func isNumber(s string) bool {
+	return s != "" && (s[0] == '-' || '0' <= s[0] && s[0] <= '9') && json.Valid([]byte(s))
+}
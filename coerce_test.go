@@ -0,0 +1,68 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+)
+
+type Purchase struct {
+	Quantity int               `json:"quantity"`
+	Price    float64           `json:"price"`
+	Express  bool              `json:"express"`
+	Ref      string            `json:"ref"`
+	Items    []uint            `json:"items,omitempty"`
+	Flags    map[string]bool   `json:"flags,omitempty"`
+	Version  int64             `json:"version,string,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"`
+}
+
+func TestLenientParams(t *testing.T) {
+	tests := []struct {
+		lenient   bool
+		req, want string
+	}{
+		{true, `{"quantity":"42","price":" 1.5 ","express":1,"ref":1234,"items":["1",2],"flags":{"a":"true","b":0},"version":"7"}`,
+			`{"quantity":42,"price":1.5,"express":true,"ref":"1234","items":[1,2],"flags":{"a":true,"b":false},"version":"7"}`},
+		{true, `{"quantity":1,"ref":true,"extra":{"n":3}}`, `{"quantity":1,"price":0,"express":false,"ref":"true","extra":{"n":"3"}}`},
+		{true, `{"quantity":"many"}`, `{"code":-32602,"message":"Invalid params","data":{"pointer":"/quantity","expected":"integer","got":"many"}}`},
+		{true, `{"express":2}`, `{"code":-32602,"message":"Invalid params","data":{"pointer":"/express","expected":"boolean","got":2}}`},
+		{false, `{"quantity":"42"}`, `{"code":-32602,"message":"Invalid params","data":{"pointer":"/quantity","expected":"integer","got":"42"}}`},
+		{false, `{"express":1}`, `{"code":-32602,"message":"Invalid params","data":{"pointer":"/express","expected":"boolean","got":1}}`},
+	}
+	for _, test := range tests {
+		server := NewServer()
+		server.LenientParams = test.lenient
+		server.HandleFunc("purchase", func(ctx context.Context, p Purchase) (Purchase, error) {
+			return p, nil
+		})
+		req := `{"jsonrpc":"2.0","id":1,"method":"purchase","params":` + test.req + `}`
+		want := `{"jsonrpc":"2.0","id":1,"result":` + test.want + `}`
+		if test.want[2:6] == "code" {
+			want = `{"jsonrpc":"2.0","id":1,"error":` + test.want + `}`
+		}
+		if got := string(server.ServeMessage(context.Background(), []byte(req))); got != want {
+			t.Errorf("lenient %v %v:\ngot: %v\nwant: %v", test.lenient, test.req, got, want)
+		}
+	}
+}
+
+func TestLenientParamsSquashed(t *testing.T) {
+	type Paging struct {
+		Limit int  `json:"limit"`
+		Desc  bool `json:"desc"`
+	}
+	type Query struct {
+		Term   string `json:"term"`
+		Paging Paging `jsonrpc:",squash"`
+	}
+	server := NewServer()
+	server.LenientParams = true
+	server.HandleFunc("search", func(ctx context.Context, q Query) (Query, error) {
+		return q, nil
+	})
+	req := `{"jsonrpc":"2.0","id":1,"method":"search","params":{"term":7,"limit":"10","desc":"true"}}`
+	want := `{"jsonrpc":"2.0","id":1,"result":{"desc":true,"limit":10,"term":"7"}}`
+	if got := string(server.ServeMessage(context.Background(), []byte(req))); got != want {
+		t.Errorf("got: %v\nwant: %v", got, want)
+	}
+}
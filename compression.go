@@ -0,0 +1,154 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// minCompressedSize is the size of the smallest response compressed by
+// servers with Compression, smaller ones wouldn't shrink much.
+const minCompressedSize = 1 << 10
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// gzipBytes appends the gzip compression of b to buf.
+func gzipBytes(buf *bytes.Buffer, b []byte) error {
+	zw := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(zw)
+	zw.Reset(buf)
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// acceptsGzip reports whether the client of r accepts gzip encoded
+// responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding := strings.TrimSpace(v)
+		if i := strings.IndexByte(coding, ';'); i >= 0 {
+			if q := strings.TrimSpace(coding[i+1:]); q == "q=0" || q == "q=0.0" {
+				continue
+			}
+			coding = strings.TrimSpace(coding[:i])
+		}
+		if strings.EqualFold(coding, "gzip") {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRequestBody replaces the body of r by its decompression if it is
+// gzip encoded. It reports false, having answered the request, if the
+// encoding isn't supported.
+func decodeRequestBody(rw http.ResponseWriter, r *http.Request) bool {
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return true
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			// let the empty body be answered with a parse error
+			zr = nil
+		}
+		r.Body = &gzipBody{zr: zr, body: r.Body}
+		// the length of the decompressed body is unknown
+		r.ContentLength = -1
+		return true
+	}
+	rw.WriteHeader(http.StatusUnsupportedMediaType)
+	rw.Write([]byte("Unsupported Media Type"))
+	return false
+}
+
+// gzipBody is the decompressed body of a request or response.
+type gzipBody struct {
+	zr   *gzip.Reader // nil if the body isn't valid gzip
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil {
+		return 0, io.EOF
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
+
+// sendCompressedResponse is like sendResponse, compressing the response if
+// it is large enough.
+func sendCompressedResponse(ctx context.Context, rw http.ResponseWriter, resps []*Response, batch bool) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeResponses(buf, resps, batch); err != nil {
+		log.Printf("jsonrpc: sending response%s: %v", logID(ctx), err)
+		return
+	}
+	rw.Header().Add("Vary", "Accept-Encoding")
+	if buf.Len() < minCompressedSize {
+		writeResponse(ctx, rw, buf.Bytes())
+		return
+	}
+	zbuf := getBuffer()
+	defer putBuffer(zbuf)
+	if err := gzipBytes(zbuf, buf.Bytes()); err != nil {
+		log.Printf("jsonrpc: compressing response%s: %v", logID(ctx), err)
+		writeResponse(ctx, rw, buf.Bytes())
+		return
+	}
+	rw.Header().Set("Content-Encoding", "gzip")
+	writeResponse(ctx, rw, zbuf.Bytes())
+}
+
+// WithCompression gzips the request bodies of at least minSize bytes and
+// asks for compressed responses, for large payloads over slow links. The
+// server must accept compressed requests, see Server.Compression.
+func WithCompression(minSize int) ClientOption {
+	return func(c *Client) {
+		c.compressMin = minSize
+		if c.compressMin <= 0 {
+			c.compressMin = 1
+		}
+	}
+}
+
+// compressBody returns the body of a request sending b, compressed if
+// compression is enabled and b is large enough, and reports whether it is.
+func (c *Client) compressBody(b []byte) ([]byte, bool, error) {
+	if c.compressMin == 0 || len(b) < c.compressMin {
+		return b, false, nil
+	}
+	var buf bytes.Buffer
+	if err := gzipBytes(&buf, b); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// decodeResponseBody returns the decompressed body of hres.
+func decodeResponseBody(hres *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(hres.Header.Get("Content-Encoding"), "gzip") {
+		return hres.Body, nil
+	}
+	zr, err := gzip.NewReader(hres.Body)
+	if err != nil {
+		hres.Body.Close()
+		return nil, err
+	}
+	return &gzipBody{zr: zr, body: hres.Body}, nil
+}
@@ -0,0 +1,100 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	s := NewServer()
+	s.Compression = true
+	s.HandleFunc("echo", func(ctx context.Context, msg string) (string, error) {
+		return msg, nil
+	})
+	var reqEncoding, respEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		reqEncoding = r.Header.Get("Content-Encoding")
+		s.ServeHTTP(rw, r)
+		respEncoding = rw.Header().Get("Content-Encoding")
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, WithCompression(100))
+
+	for _, msg := range []string{"short", strings.Repeat("long ", 1000)} {
+		resp, err := client.Call(context.Background(), "echo", msg)
+		if err != nil {
+			t.Fatalf("call: %v", err)
+		}
+		var got string
+		if err := resp.Decode(&got); err != nil || got != msg {
+			t.Errorf("invalid echo of %d bytes: %d bytes, %v", len(msg), len(got), err)
+		}
+		want := ""
+		if len(msg) > 100 {
+			want = "gzip"
+		}
+		if reqEncoding != want || respEncoding != want {
+			t.Errorf("%d bytes: invalid encodings:\ngot: %q, %q\nwant: %q", len(msg), reqEncoding, respEncoding, want)
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Encoding", "br")
+	s.ServeHTTP(rw, req)
+	if rw.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("invalid status for an unsupported encoding:\ngot: %v\nwant: 415", rw.Code)
+	}
+	rw = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	s.ServeHTTP(rw, req)
+	if want := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`; rw.Body.String() != want {
+		t.Errorf("invalid response to a corrupt body:\ngot: %v\nwant: %v", rw.Body.String(), want)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for h, want := range map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"deflate, GZIP":        true,
+		"br;q=1.0, gzip;q=0.5": true,
+		"gzip;q=0":             false,
+		"identity":             false,
+	} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", h)
+		if got := acceptsGzip(r); got != want {
+			t.Errorf("%q: got %v, want %v", h, got, want)
+		}
+	}
+}
+
+func TestCompressionMaxBytes(t *testing.T) {
+	s := NewServer()
+	s.Compression = true
+	s.Limits.MaxBytes = 4 << 10
+	s.HandleFunc("echo", func(ctx context.Context, msg string) (string, error) {
+		t.Errorf("echo called")
+		return msg, nil
+	})
+
+	// a small body inflating to a megabyte
+	var buf bytes.Buffer
+	gzipBytes(&buf, []byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":"`+strings.Repeat("a", 1<<20)+`"}`))
+	if int64(buf.Len()) > s.Limits.MaxBytes {
+		t.Fatalf("compressed body of %v bytes", buf.Len())
+	}
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	s.ServeHTTP(rw, req)
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("invalid status:\ngot: %v\nwant: 413", rw.Code)
+	}
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Config is the configuration of a server, usually read from a JSON file by
+// LoadConfig. YAML files can be used by converting them to JSON first, e.g.
+// with sigs.k8s.io/yaml, as the fields are matched by their json tags.
+type Config struct {
+	// Addr is the address to listen on, such as ":4545".
+	Addr string `json:"addr"`
+	// TLS enables HTTPS when set.
+	TLS *TLSConfig `json:"tls,omitempty"`
+	H2C bool       `json:"h2c,omitempty"`
+	// CORS allows cross-origin requests, see Server.CORS.
+	CORS *CORSFileConfig `json:"cors,omitempty"`
+
+	ReadHeaderTimeout Duration `json:"read_header_timeout,omitempty"`
+	ReadTimeout       Duration `json:"read_timeout,omitempty"`
+	WriteTimeout      Duration `json:"write_timeout,omitempty"`
+	IdleTimeout       Duration `json:"idle_timeout,omitempty"`
+	ReusePort         bool     `json:"reuse_port,omitempty"`
+
+	MaxConnections        int             `json:"max_connections,omitempty"`
+	MaxConcurrentRequests int             `json:"max_concurrent_requests,omitempty"`
+	AdmissionQueue        int             `json:"admission_queue,omitempty"`
+	AdmissionTimeout      Duration        `json:"admission_timeout,omitempty"`
+	RateLimit             ClientRateLimit `json:"rate_limit,omitempty"`
+	Limits                DecodeLimits    `json:"limits,omitempty"`
+	IDPolicy              IDPolicy        `json:"id_policy,omitempty"`
+	LenientParams         bool            `json:"lenient_params,omitempty"`
+
+	RequestIDs        bool     `json:"request_ids,omitempty"`
+	ContextHeaders    []string `json:"context_headers,omitempty"`
+	Profiling         bool     `json:"profiling,omitempty"`
+	SlowCallThreshold Duration `json:"slow_call_threshold,omitempty"`
+	StatsWindow       Duration `json:"stats_window,omitempty"`
+	ExposeErrors      bool     `json:"expose_errors,omitempty"`
+}
+
+// TLSConfig holds the certificate served over HTTPS.
+type TLSConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+// CORSFileConfig holds the settings of CORSConfig which can be read from a
+// file.
+type CORSFileConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedHeaders   []string `json:"allowed_headers,omitempty"`
+	ExposedHeaders   []string `json:"exposed_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	MaxAge           Duration `json:"max_age,omitempty"`
+}
+
+// Duration is a time.Duration read from a string such as "1m30s".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10s\": %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads the JSON configuration in the file path. Unknown fields
+// are rejected to catch typos, as are CORS settings allowing credentials
+// from any origin.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: reading config: %w", err)
+	}
+	cfg := &Config{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("jsonrpc: parsing config %v: %w", path, err)
+	}
+	if cfg.CORS != nil {
+		if err := cfg.CORS.check(); err != nil {
+			return nil, fmt.Errorf("jsonrpc: parsing config %v: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// NewServerFromConfig returns a new Server configured by cfg, opts being
+// applied afterwards.
+func NewServerFromConfig(cfg *Config, opts ...ServerOption) *Server {
+	s := &Server{
+		H2C:                   cfg.H2C,
+		ReadHeaderTimeout:     time.Duration(cfg.ReadHeaderTimeout),
+		ReadTimeout:           time.Duration(cfg.ReadTimeout),
+		WriteTimeout:          time.Duration(cfg.WriteTimeout),
+		IdleTimeout:           time.Duration(cfg.IdleTimeout),
+		ReusePort:             cfg.ReusePort,
+		MaxConnections:        cfg.MaxConnections,
+		MaxConcurrentRequests: cfg.MaxConcurrentRequests,
+		AdmissionQueue:        cfg.AdmissionQueue,
+		AdmissionTimeout:      time.Duration(cfg.AdmissionTimeout),
+		RateLimit:             cfg.RateLimit,
+		Limits:                cfg.Limits,
+		IDPolicy:              cfg.IDPolicy,
+		LenientParams:         cfg.LenientParams,
+		RequestIDs:            cfg.RequestIDs,
+		ContextHeaders:        cfg.ContextHeaders,
+		Profiling:             cfg.Profiling,
+		SlowCallThreshold:     time.Duration(cfg.SlowCallThreshold),
+		StatsWindow:           time.Duration(cfg.StatsWindow),
+		ExposeErrors:          cfg.ExposeErrors,
+	}
+	if c := cfg.CORS; c != nil {
+		s.CORS = CORSConfig{
+			AllowedOrigins:   c.AllowedOrigins,
+			AllowedHeaders:   c.AllowedHeaders,
+			ExposedHeaders:   c.ExposedHeaders,
+			AllowCredentials: c.AllowCredentials,
+			MaxAge:           time.Duration(c.MaxAge),
+		}
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListenAndServeConfig serves s on the address of cfg, over HTTPS if cfg
+// has a TLS certificate.
+func (s *Server) ListenAndServeConfig(cfg *Config) error {
+	if cfg.TLS != nil {
+		return s.ListenAndServeTLS(cfg.Addr, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}
+	return s.ListenAndServe(cfg.Addr)
+}
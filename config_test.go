@@ -0,0 +1,54 @@
+package jsonrpc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonrpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.json")
+	ioutil.WriteFile(path, []byte(`{
+		"addr": ":4545",
+		"tls": {"cert_file": "cert.pem", "key_file": "key.pem"},
+		"cors": {"allowed_origins": ["*"], "max_age": "1h"},
+		"read_timeout": "5s",
+		"max_concurrent_requests": 10,
+		"admission_timeout": "250ms",
+		"rate_limit": {"per_second": 5, "burst": 10},
+		"limits": {"max_depth": 8},
+		"request_ids": true
+	}`), 0600)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Addr != ":4545" || cfg.TLS == nil || cfg.TLS.KeyFile != "key.pem" {
+		t.Errorf("invalid config: %+v", cfg)
+	}
+
+	s := NewServerFromConfig(cfg, WithSlowCallThreshold(time.Second))
+	if s.ReadTimeout != 5*time.Second || s.AdmissionTimeout != 250*time.Millisecond ||
+		s.MaxConcurrentRequests != 10 || s.RateLimit.Burst != 10 || s.Limits.MaxDepth != 8 || !s.RequestIDs ||
+		len(s.CORS.AllowedOrigins) != 1 || s.CORS.MaxAge != time.Hour || s.SlowCallThreshold != time.Second {
+		t.Errorf("invalid server: %+v", s)
+	}
+
+	for _, bad := range []string{`{"adr": ":4545"}`, `{"read_timeout": 5}`, `{"read_timeout": "5 parsecs"}`,
+		`{"cors": {"allowed_origins": ["*"], "allow_credentials": true}}`} {
+		ioutil.WriteFile(path, []byte(bad), 0600)
+		if _, err := LoadConfig(path); err == nil || !strings.HasPrefix(err.Error(), "jsonrpc: parsing config") {
+			t.Errorf("%v: expected a parsing error, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package jsonrpc
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the cross-origin requests allowed by the server,
+// from browsers on other origins. The zero value allows none.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed, such as
+	// "https://app.example.com". "*" allows any origin and a "*" in a
+	// pattern matches any sequence of characters, like in
+	// "https://*.example.com". Origins match case insensitively.
+	//
+	// "*" is ignored if AllowCredentials is set: any site could otherwise
+	// make calls with the cookies of its visitors.
+	AllowedOrigins []string
+	// AllowOrigin, if set, reports whether origin is allowed when it
+	// matches none of AllowedOrigins.
+	AllowOrigin func(origin string) bool
+	// AllowedHeaders are the request headers allowed besides Content-Type.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by scripts, such as
+	// X-Request-ID.
+	ExposedHeaders []string
+	// AllowCredentials allows requests carrying cookies or HTTP
+	// authentication. The allowed origins must then be listed, or matched
+	// by patterns or AllowOrigin, "*" allows none.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the answer to a preflight
+	// request, zero leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// errCORSAnyOrigin rejects configurations allowing credentials from any
+// origin.
+var errCORSAnyOrigin = errors.New(`cors: allow_credentials can't be used with the "*" origin`)
+
+// check reports an error if c allows credentials from any origin.
+func (c *CORSFileConfig) check() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			return errCORSAnyOrigin
+		}
+	}
+	return nil
+}
+
+func (c *CORSConfig) enabled() bool {
+	return len(c.AllowedOrigins) > 0 || c.AllowOrigin != nil
+}
+
+// allowed reports whether origin is allowed.
+func (c *CORSConfig) allowed(origin string) bool {
+	lower := strings.ToLower(origin)
+	for _, p := range c.AllowedOrigins {
+		if p == "*" {
+			if !c.AllowCredentials {
+				return true
+			}
+			continue
+		}
+		if matchOrigin(strings.ToLower(p), lower) {
+			return true
+		}
+	}
+	return c.AllowOrigin != nil && c.AllowOrigin(origin)
+}
+
+// matchOrigin reports whether origin matches pattern, in which "*" matches
+// any sequence of characters.
+func matchOrigin(pattern, origin string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == origin
+	}
+	if !strings.HasPrefix(origin, parts[0]) {
+		return false
+	}
+	origin = origin[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(origin, part)
+		if i < 0 {
+			return false
+		}
+		origin = origin[i+len(part):]
+	}
+	return strings.HasSuffix(origin, parts[len(parts)-1])
+}
+
+// handle adds the CORS headers to the response to r and reports whether r
+// was a preflight request, which is then answered.
+func (c *CORSConfig) handle(rw http.ResponseWriter, r *http.Request) bool {
+	h := rw.Header()
+	h.Add("Vary", "Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowed(origin) {
+		if preflight {
+			rw.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(c.ExposedHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		return false
+	}
+	h.Set("Access-Control-Allow-Methods", http.MethodPost)
+	h.Set("Access-Control-Allow-Headers", strings.Join(append([]string{"Content-Type"}, c.AllowedHeaders...), ", "))
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	rw.WriteHeader(http.StatusNoContent)
+	return true
+}
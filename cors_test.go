@@ -0,0 +1,107 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	server := NewServer()
+	server.CORS = CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowOrigin:      func(origin string) bool { return origin == "http://localhost:3000" },
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+	server.HandleFunc("random", random)
+
+	tests := []struct {
+		name, method, origin string
+		status               int
+		headers              map[string]string
+	}{
+		{"preflight", "OPTIONS", "https://app.example.com", 204, map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Methods":     "POST",
+			"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "3600",
+			"Access-Control-Expose-Headers":    "",
+		}},
+		{"wildcard", "OPTIONS", "https://API.eu.example.org", 204, map[string]string{
+			"Access-Control-Allow-Origin": "https://API.eu.example.org",
+		}},
+		{"callback", "OPTIONS", "http://localhost:3000", 204, map[string]string{
+			"Access-Control-Allow-Origin": "http://localhost:3000",
+		}},
+		{"forbidden preflight", "OPTIONS", "https://example.org.evil.com", 403, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"actual", "POST", "https://app.example.com", 200, map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Expose-Headers":    RequestIDHeader,
+			"Access-Control-Allow-Methods":     "",
+			"Vary":                             "Origin",
+		}},
+		{"forbidden", "POST", "https://evil.com", 200, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"same origin", "POST", "", 200, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"random"}`))
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if test.method == "OPTIONS" {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, r)
+		if rw.Code != test.status {
+			t.Errorf("%v: invalid status:\ngot: %v\nwant: %v", test.name, rw.Code, test.status)
+		}
+		for k, want := range test.headers {
+			if got := rw.Header().Get(k); got != want {
+				t.Errorf("%v: invalid %v:\ngot: %q\nwant: %q", test.name, k, got, want)
+			}
+		}
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	server := NewServer()
+	server.CORS.AllowedOrigins = []string{"*"}
+	server.HandleFunc("random", random)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	allowOrigin := func() string {
+		req, _ := http.NewRequestWithContext(context.Background(), "POST", ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"random"}`))
+		req.Header.Set("Origin", "https://anywhere.com")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("Access-Control-Allow-Origin")
+	}
+	if got := allowOrigin(); got != "https://anywhere.com" {
+		t.Errorf("invalid Access-Control-Allow-Origin:\ngot: %q\nwant: https://anywhere.com", got)
+	}
+
+	// any site could call with the cookies of its visitors
+	server.CORS.AllowCredentials = true
+	if got := allowOrigin(); got != "" {
+		t.Errorf("any origin allowed with credentials: %q", got)
+	}
+}
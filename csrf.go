@@ -0,0 +1,105 @@
+package jsonrpc
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultCSRFTokenHeader is the header carrying the double-submit token when
+// CSRFConfig.TokenHeader is empty.
+const DefaultCSRFTokenHeader = "X-CSRF-Token"
+
+// CSRFConfig configures defenses against cross-site request forgery, for
+// endpoints called from browsers with cookie sessions. Requests failing any
+// of the enabled checks are answered with 403. The zero value checks
+// nothing.
+type CSRFConfig struct {
+	// RequireHeader, if set, is a header requests must carry, such as
+	// X-Requested-With. Browsers don't let other origins send custom
+	// headers without a CORS preflight.
+	RequireHeader string
+	// CheckOrigin rejects requests whose Origin header, or Referer header
+	// if it is missing, is neither the host of the request nor allowed by
+	// Server.CORS. Requests carrying neither header are accepted, as sent
+	// by non-browser clients.
+	CheckOrigin bool
+	// TokenCookie, if set, enables the double-submit token check: requests
+	// must carry the value of this cookie in TokenHeader. Pages set the
+	// cookie with Token.
+	TokenCookie string
+	// TokenHeader defaults to DefaultCSRFTokenHeader.
+	TokenHeader string
+}
+
+func (c *CSRFConfig) enabled() bool {
+	return c.RequireHeader != "" || c.CheckOrigin || c.TokenCookie != ""
+}
+
+// Token returns the double-submit token of the browser making r, setting
+// the TokenCookie cookie on rw if it has none yet. Call it from the handler
+// serving the page and hand the token to scripts, which send it in
+// TokenHeader.
+func (c *CSRFConfig) Token(rw http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(c.TokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	token := randomID(32)
+	http.SetCookie(rw, &http.Cookie{
+		Name:     c.TokenCookie,
+		Value:    token,
+		Path:     "/",
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// check reports whether r passes the enabled checks, given the CORS
+// configuration of the server.
+func (c *CSRFConfig) check(r *http.Request, cors *CORSConfig) bool {
+	if c.RequireHeader != "" && r.Header.Get(c.RequireHeader) == "" {
+		return false
+	}
+	if c.CheckOrigin && !c.sameOrigin(r, cors) {
+		return false
+	}
+	if c.TokenCookie != "" {
+		cookie, err := r.Cookie(c.TokenCookie)
+		if err != nil || cookie.Value == "" {
+			return false
+		}
+		header := c.TokenHeader
+		if header == "" {
+			header = DefaultCSRFTokenHeader
+		}
+		if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.Header.Get(header))) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CSRFConfig) sameOrigin(r *http.Request, cors *CORSConfig) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		ref := r.Header.Get("Referer")
+		if ref == "" {
+			return origin == ""
+		}
+		u, err := url.Parse(ref)
+		if err != nil {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return cors.enabled() && cors.allowed(origin)
+}
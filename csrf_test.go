@@ -0,0 +1,64 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	server := NewServer()
+	server.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	server.CSRF = CSRFConfig{
+		RequireHeader: "X-Requested-With",
+		CheckOrigin:   true,
+		TokenCookie:   "csrf",
+	}
+	server.HandleFunc("random", random)
+
+	rw := httptest.NewRecorder()
+	token := server.CSRF.Token(rw, httptest.NewRequest("GET", "/", nil))
+	cookies := rw.Result().Cookies()
+	if token == "" || len(cookies) != 1 || cookies[0].Value != token {
+		t.Fatalf("invalid token cookie: %q %v", token, cookies)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	if again := server.CSRF.Token(httptest.NewRecorder(), r); again != token {
+		t.Errorf("token not reused:\ngot: %v\nwant: %v", again, token)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		cookie  string
+		status  int
+	}{
+		{"valid", map[string]string{"Origin": "https://api.example.com"}, token, 200},
+		{"cors origin", map[string]string{"Origin": "https://app.example.com"}, token, 200},
+		{"referer", map[string]string{"Referer": "https://api.example.com/app"}, token, 200},
+		{"no origin", nil, token, 200},
+		{"missing header", map[string]string{"X-Requested-With": ""}, token, 403},
+		{"cross origin", map[string]string{"Origin": "https://evil.com"}, token, 403},
+		{"cross referer", map[string]string{"Referer": "https://evil.com/"}, token, 403},
+		{"missing cookie", nil, "", 403},
+		{"wrong token", map[string]string{DefaultCSRFTokenHeader: "guess"}, token, 403},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "https://api.example.com/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"random"}`))
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+		r.Header.Set(DefaultCSRFTokenHeader, token)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "csrf", Value: test.cookie})
+		}
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, r)
+		if rw.Code != test.status {
+			t.Errorf("%v: invalid status:\ngot: %v\nwant: %v", test.name, rw.Code, test.status)
+		}
+	}
+}
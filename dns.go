@@ -0,0 +1,121 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// DefaultDNSInterval is the default re-resolution interval of a DNSResolver.
+const DefaultDNSInterval = 30 * time.Second
+
+// DNSResolver expands a host name into one endpoint per A/AAAA record, or per
+// SRV target when Service is set. It implements Watcher by re-resolving the
+// name every Interval.
+type DNSResolver struct {
+	// Scheme and Path are used to build the endpoint URLs, e.g. "http" and
+	// "/rpc". Scheme defaults to "http".
+	Scheme string
+	Path   string
+
+	// Host is the name to resolve. For SRV lookups _Service._Proto.Host is
+	// queried, otherwise Host is resolved to its addresses and Port is used.
+	Host    string
+	Port    string
+	Service string
+	Proto   string
+
+	// Interval between re-resolutions, DefaultDNSInterval if zero.
+	Interval time.Duration
+
+	// Resolver is used for the lookups, net.DefaultResolver if nil.
+	Resolver *net.Resolver
+
+	lookup dnsLookup
+}
+
+type dnsLookup interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Resolve looks up the endpoints of r.
+func (r *DNSResolver) Resolve(ctx context.Context) ([]string, error) {
+	lookup := r.lookup
+	if lookup == nil {
+		lookup = net.DefaultResolver
+		if r.Resolver != nil {
+			lookup = r.Resolver
+		}
+	}
+
+	var hostports []string
+	if r.Service != "" {
+		_, srvs, err := lookup.LookupSRV(ctx, r.Service, r.Proto, r.Host)
+		if err != nil {
+			return nil, err
+		}
+		// SRV records are sorted by priority, keep them in that order
+		for _, srv := range srvs {
+			hostports = append(hostports, net.JoinHostPort(srv.Target, strconv.Itoa(int(srv.Port))))
+		}
+	} else {
+		addrs, err := lookup.LookupHost(ctx, r.Host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if r.Port == "" {
+				hostports = append(hostports, addr)
+				continue
+			}
+			hostports = append(hostports, net.JoinHostPort(addr, r.Port))
+		}
+	}
+
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	endpoints := make([]string, 0, len(hostports))
+	for _, hp := range hostports {
+		u := url.URL{Scheme: scheme, Host: hp, Path: r.Path}
+		endpoints = append(endpoints, u.String())
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no records found for %v", r.Host)
+	}
+	return endpoints, nil
+}
+
+// Watch re-resolves the endpoints every r.Interval and calls update when they
+// change. Lookup failures are logged and the previous endpoints kept.
+func (r *DNSResolver) Watch(ctx context.Context, update func([]string)) error {
+	ticker := time.NewTicker(durationOr(r.Interval, DefaultDNSInterval))
+	defer ticker.Stop()
+
+	var last []string
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		endpoints, err := r.Resolve(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("jsonrpc: resolving %v: %v", r.Host, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(endpoints, last) {
+			update(endpoints)
+			last = endpoints
+		}
+	}
+}
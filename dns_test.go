@@ -0,0 +1,73 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeDNS struct {
+	mu    sync.Mutex
+	hosts []string
+	srvs  []*net.SRV
+}
+
+func (f *fakeDNS) LookupHost(ctx context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hosts, nil
+}
+
+func (f *fakeDNS) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return "", f.srvs, nil
+}
+
+func TestDNSResolver(t *testing.T) {
+	dns := &fakeDNS{
+		hosts: []string{"10.0.0.1", "10.0.0.2"},
+		srvs:  []*net.SRV{{Target: "a.example.com.", Port: 8080}, {Target: "b.example.com.", Port: 8081}},
+	}
+
+	r := &DNSResolver{Host: "rpc.example.com", Port: "4545", Path: "/api", lookup: dns}
+	got, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	want := []string{"http://10.0.0.1:4545/api", "http://10.0.0.2:4545/api"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid A endpoints:\ngot: %v\nwant: %v", got, want)
+	}
+
+	r = &DNSResolver{Scheme: "https", Service: "jsonrpc", Proto: "tcp", Host: "example.com", lookup: dns}
+	got, err = r.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	want = []string{"https://a.example.com.:8080", "https://b.example.com.:8081"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid SRV endpoints:\ngot: %v\nwant: %v", got, want)
+	}
+}
+
+func TestDNSResolverWatch(t *testing.T) {
+	dns := &fakeDNS{hosts: []string{"10.0.0.1"}}
+	r := &DNSResolver{Host: "rpc.example.com", Interval: time.Millisecond, lookup: dns}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	updates := make(chan []string, 10)
+	go r.Watch(ctx, func(eps []string) { updates <- eps })
+
+	if got := <-updates; !reflect.DeepEqual(got, []string{"http://10.0.0.1"}) {
+		t.Errorf("invalid first update: %v", got)
+	}
+	dns.mu.Lock()
+	dns.hosts = []string{"10.0.0.2"}
+	dns.mu.Unlock()
+	if got := <-updates; !reflect.DeepEqual(got, []string{"http://10.0.0.2"}) {
+		t.Errorf("invalid second update: %v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// DocsConfig configures the documentation served by Server.Docs.
+type DocsConfig struct {
+	Info OpenRPCInfo
+	// Authorize, if set, reports whether r may read the documentation,
+	// unauthorized requests are answered with 401.
+	Authorize func(r *http.Request) bool
+}
+
+// Docs returns a handler serving a documentation page of the methods of s
+// generated from its OpenRPC document: params and results with their schema,
+// example params and error codes. The document itself is served as JSON
+// with the "format=json" query parameter. Mount it at any path:
+//
+//	http.Handle("/docs", server.Docs(jsonrpc.DocsConfig{Info: info}))
+func (s *Server) Docs(cfg DocsConfig) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if cfg.Authorize != nil && !cfg.Authorize(r) {
+			http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		doc := s.OpenRPC(cfg.Info)
+		if r.URL.Query().Get("format") == "json" {
+			rw.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(rw).Encode(doc); err != nil {
+				log.Printf("jsonrpc: sending docs: %v", err)
+			}
+			return
+		}
+
+		examples := make(map[string]string)
+		for _, m := range s.Methods() {
+			if len(m.Example) > 0 {
+				examples[m.Name] = string(m.Example)
+			}
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := struct {
+			*OpenRPCDocument
+			Examples map[string]string
+		}{doc, examples}
+		if err := docsTemplate.Execute(rw, data); err != nil {
+			log.Printf("jsonrpc: rendering docs: %v", err)
+		}
+	})
+}
+
+var docsTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"schema": func(v interface{}) string {
+		b, _ := json.MarshalIndent(v, "", "  ")
+		return string(b)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Info.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; max-width: 60em; }
+pre { background: #f4f4f4; padding: 1em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: .3em .6em; text-align: left; vertical-align: top; }
+section { border-top: 1px solid #ddd; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Info.Title}} <small>{{.Info.Version}}</small></h1>
+{{with .Info.Description}}<p>{{.}}</p>{{end}}
+<ul>
+{{range .Methods}}<li><a href="#{{.Name}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{range .Methods}}
+<section id="{{.Name}}">
+<h2>{{.Name}}</h2>
+{{if .Params}}<h3>Params <small>{{.ParamStructure}}</small></h3>
+<table>
+<tr><th>Name</th><th>Required</th><th>Schema</th></tr>
+{{range .Params}}<tr><td>{{.Name}}</td><td>{{if .Required}}yes{{end}}</td><td><pre>{{schema .Schema}}</pre></td></tr>
+{{end}}</table>
+{{with index $.Examples .Name}}<h3>Example params</h3>
+<pre>{{.}}</pre>{{end}}
+{{else}}<p>No params.</p>
+{{end}}
+{{with .Result}}<h3>Result</h3>
+<pre>{{schema .Schema}}</pre>{{end}}
+{{if .Errors}}<h3>Errors</h3>
+<table>
+<tr><th>Code</th><th>Description</th></tr>
+{{range .Errors}}<tr><td>{{.Code}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{end}}
+</section>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,102 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+)
+
+// WithErrorMapper sets Server.ErrorMapper.
+func WithErrorMapper(mapper func(error) *Error) ServerOption {
+	return func(s *Server) {
+		s.ErrorMapper = mapper
+	}
+}
+
+// ErrorCode is an application error code declared with RegisterError.
+type ErrorCode struct {
+	Code        int
+	Description string
+}
+
+// RegisterError declares an application error code returned by handlers.
+// Once codes are registered, a handler returning an *Error whose code is
+// neither registered nor reserved by the specification is logged and answered
+// with Internal error. Reserved codes can't be registered.
+func (s *Server) RegisterError(code int, description string) error {
+	if code >= CodeReservedMin && code <= CodeReservedMax {
+		return fmt.Errorf("jsonrpc: error code %v is reserved", code)
+	}
+	if _, loaded := s.errorCodes.LoadOrStore(code, description); loaded {
+		return fmt.Errorf("jsonrpc: error code %v already registered", code)
+	}
+	atomic.StoreInt32(&s.hasErrorCodes, 1)
+	return nil
+}
+
+// ErrorCodes returns the registered error codes sorted by code.
+func (s *Server) ErrorCodes() []ErrorCode {
+	var codes []ErrorCode
+	s.errorCodes.Range(func(k, v interface{}) bool {
+		codes = append(codes, ErrorCode{k.(int), v.(string)})
+		return true
+	})
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// checkErrorCode reports whether the error e returned by the handler of
+// method uses a known code.
+func (s *Server) checkErrorCode(method string, e *Error) bool {
+	if atomic.LoadInt32(&s.hasErrorCodes) == 0 || e.Code >= CodeReservedMin && e.Code <= CodeReservedMax {
+		return true
+	}
+	if _, ok := s.errorCodes.Load(e.Code); ok {
+		return true
+	}
+	log.Printf("jsonrpc: %v returned unregistered error code %v", method, e.Code)
+	return false
+}
+
+// serverError returns the server error sent to the client for the unexpected
+// error err returned by the handler of req, see Server.ExposeErrors.
+func (s *Server) serverError(ctx context.Context, req *request, err error) *Error {
+	if s.ExposeErrors {
+		return &Error{Code: CodeServerError, Message: err.Error()}
+	}
+	ref := randomID(8)
+	log.Printf("jsonrpc: %v failed%s, reference %v: %v", req.Method, logID(ctx), ref, err)
+	e := &Error{Code: CodeServerError, Message: "Server error", Data: errorReference{Reference: ref}}
+	var p ErrorDataProvider
+	if errors.As(err, &p) {
+		// data provided by err is meant for clients, it is sent along
+		e.Data = withReference(p.ErrorData(), ref)
+	}
+	return e
+}
+
+// errorReference is the data of server errors whose message is hidden, Data
+// being the data provided by the error if it isn't a JSON object.
+type errorReference struct {
+	Reference string      `json:"reference"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// withReference returns data with the reference member added if it is
+// encoded as a JSON object, or wrapped in an errorReference.
+func withReference(data interface{}, ref string) interface{} {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return errorReference{Reference: ref}
+	}
+	var m map[string]json.RawMessage
+	if json.Unmarshal(b, &m) != nil || m == nil {
+		return errorReference{Reference: ref, Data: json.RawMessage(b)}
+	}
+	m["reference"], _ = json.Marshal(ref)
+	return m
+}
@@ -0,0 +1,123 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterError(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("fail", func(ctx context.Context, code int) (int, error) {
+		return 0, &Error{Code: code, Message: "failed"}
+	})
+	call := func(code string) string {
+		return string(server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"fail","params":`+code+`}`)))
+	}
+
+	// any code is accepted until one is registered
+	if got, want := call("7"), `{"jsonrpc":"2.0","id":1,"error":{"code":7,"message":"failed"}}`; got != want {
+		t.Errorf("no registry:\ngot: %v\nwant: %v", got, want)
+	}
+
+	if err := server.RegisterError(42, "Not enough credit"); err != nil {
+		t.Fatalf("RegisterError: %v", err)
+	}
+	if err := server.RegisterError(42, "Again"); err == nil {
+		t.Errorf("RegisterError: expected an error for a duplicate code")
+	}
+	if err := server.RegisterError(CodeInvalidParams, "Reserved"); err == nil {
+		t.Errorf("RegisterError: expected an error for a reserved code")
+	}
+	server.RegisterError(-1, "Negative")
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"42", `{"jsonrpc":"2.0","id":1,"error":{"code":42,"message":"failed"}}`},
+		{"-32001", `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"failed"}}`},
+		{"7", `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error"}}`},
+	}
+	for _, tt := range tests {
+		if got := call(tt.code); got != tt.want {
+			t.Errorf("code %v:\ngot: %v\nwant: %v", tt.code, got, tt.want)
+		}
+	}
+
+	want := []ErrorCode{{-1, "Negative"}, {42, "Not enough credit"}}
+	if got := server.ErrorCodes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ErrorCodes:\ngot: %v\nwant: %v", got, want)
+	}
+}
+
+func TestErrorMapper(t *testing.T) {
+	errNotFound := errors.New("sql: no rows in result set")
+	server := NewServer()
+	server.ErrorMapper = func(err error) *Error {
+		if errors.Is(err, errNotFound) {
+			return NewError(404, "Not found", nil)
+		}
+		return nil
+	}
+	server.ExposeErrors = true
+	server.HandleFunc("fail", func(ctx context.Context, msg string) (int, error) {
+		switch msg {
+		case "not found":
+			return 0, fmt.Errorf("loading user: %w", errNotFound)
+		case "invalid":
+			return 0, ErrInvalidParams
+		}
+		return 0, errors.New(msg)
+	})
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"not found", `{"jsonrpc":"2.0","id":1,"error":{"code":404,"message":"Not found"}}`},
+		{"invalid", `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`},
+		{"other", `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"other"}}`},
+	}
+	for _, tt := range tests {
+		got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"fail","params":"`+tt.msg+`"}`))
+		if string(got) != tt.want {
+			t.Errorf("%v:\ngot: %s\nwant: %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestHiddenServerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	defer captureLog(&buf)()
+
+	server := NewServer()
+	server.HandleFunc("fail", func(ctx context.Context) (int, error) {
+		return 0, errors.New("dial tcp 10.0.0.3:5432: connection refused")
+	})
+	got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"fail"}`))
+
+	var resp struct {
+		Error struct {
+			Code    int
+			Message string
+			Data    struct{ Reference string }
+		}
+	}
+	if err := json.Unmarshal(got, &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", got, err)
+	}
+	e := resp.Error
+	if e.Code != CodeServerError || e.Message != "Server error" || len(e.Data.Reference) != 16 {
+		t.Fatalf("invalid error: %s", got)
+	}
+	logged := buf.String()
+	if !strings.Contains(logged, e.Data.Reference) || !strings.Contains(logged, "connection refused") {
+		t.Errorf("error not logged with its reference:\n%s", logged)
+	}
+}
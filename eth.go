@@ -0,0 +1,124 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// EthSubscriptions implements the eth_subscribe and eth_unsubscribe methods
+// of Ethereum nodes on top of a Broker, so that tooling written against them
+// can subscribe to servers built on this package. The subscription type, the
+// first param of eth_subscribe, is the topic subscribed to, such as
+// "newHeads", and the optional second param is a Filter. eth_subscribe
+// streams its result, the hex id of the subscription, then the events as
+// eth_subscription notifications:
+//
+//	{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":{...}}}
+//
+// Subscription ids are random. Subscriptions end with the request streaming
+// them or with eth_unsubscribe, called by the same authenticated principal,
+// see GetPrincipal, or by unauthenticated clients from the same connection.
+type EthSubscriptions struct {
+	broker *Broker
+
+	mu   sync.Mutex
+	subs map[string]ethSubscription
+}
+
+type ethSubscription struct {
+	owner  string
+	cancel context.CancelFunc
+}
+
+// HandleEthSubscriptions registers eth_subscribe and eth_unsubscribe on s,
+// delivering the events of b.
+func (s *Server) HandleEthSubscriptions(b *Broker) *EthSubscriptions {
+	e := &EthSubscriptions{broker: b, subs: make(map[string]ethSubscription)}
+	s.HandleFunc("eth_subscribe", e.subscribe)
+	s.HandleFunc("eth_unsubscribe", e.unsubscribe)
+	return e
+}
+
+type ethNotification struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+func (e *EthSubscriptions) subscribe(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error) {
+	var topic string
+	if len(params) == 0 || json.Unmarshal(params[0], &topic) != nil || topic == "" {
+		return nil, ErrInvalidParamsf("missing subscription type")
+	}
+	opts := SubscribeOptions{Topics: []string{topic}}
+	if len(params) > 1 {
+		if err := json.Unmarshal(params[1], &opts.Filter); err != nil {
+			return nil, ErrInvalidParamsf("invalid filter: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events, err := e.broker.SubscribeWith(ctx, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	id := "0x" + randomID(16)
+	e.mu.Lock()
+	e.subs[id] = ethSubscription{owner: ethOwner(ctx), cancel: cancel}
+	e.mu.Unlock()
+
+	c := make(chan interface{})
+	go func() {
+		defer close(c)
+		defer e.remove(id, "")
+		select {
+		case c <- id:
+		case <-ctx.Done():
+			return
+		}
+		for ev := range events {
+			n := Notification{Method: "eth_subscription", Params: ethNotification{Subscription: id, Result: ev.Data}}
+			select {
+			case c <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+func (e *EthSubscriptions) unsubscribe(ctx context.Context, params []string) (bool, error) {
+	if len(params) != 1 {
+		return false, ErrInvalidParamsf("expected a subscription id")
+	}
+	return e.remove(params[0], ethOwner(ctx)), nil
+}
+
+// remove ends the subscription id and reports whether it was active. Unless
+// owner is empty, subscriptions of other owners are left alone.
+func (e *EthSubscriptions) remove(id, owner string) bool {
+	e.mu.Lock()
+	sub, ok := e.subs[id]
+	if ok && owner != "" && sub.owner != owner {
+		ok = false
+	}
+	if ok {
+		delete(e.subs, id)
+	}
+	e.mu.Unlock()
+	if ok {
+		sub.cancel()
+	}
+	return ok
+}
+
+// ethOwner identifies the client of ctx: its principal if authenticated, its
+// connection otherwise.
+func ethOwner(ctx context.Context) string {
+	if p := GetPrincipal(ctx); p != nil {
+		return "principal:" + p.Subject
+	}
+	return "conn:" + RemoteAddr(ctx)
+}
@@ -0,0 +1,70 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEthSubscriptions(t *testing.T) {
+	b := NewBroker()
+	server := NewServer()
+	subs := server.HandleEthSubscriptions(b)
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["logs",{"address":"0xa"}]}`)))
+	rw := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(rw, req)
+		close(done)
+	}()
+	for b.Stats().Subscriptions == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	b.Publish("logs", map[string]string{"address": "0xa"})
+	b.Publish("logs", map[string]string{"address": "0xb"})
+	b.Publish("newHeads", map[string]string{"number": "0x1"})
+	time.Sleep(10 * time.Millisecond)
+
+	var id string
+	subs.mu.Lock()
+	for id = range subs.subs {
+	}
+	subs.mu.Unlock()
+	if len(id) != 34 {
+		t.Fatalf("invalid subscription id %q", id)
+	}
+
+	for _, test := range []struct {
+		remoteAddr, req, want string
+	}{
+		// not the owner
+		{"192.0.2.2:1234", `{"jsonrpc":"2.0","id":2,"method":"eth_unsubscribe","params":["` + id + `"]}`, `{"jsonrpc":"2.0","id":2,"result":false}`},
+		{"", `{"jsonrpc":"2.0","id":3,"method":"eth_unsubscribe","params":["` + id + `"]}`, `{"jsonrpc":"2.0","id":3,"result":true}`},
+		{"", `{"jsonrpc":"2.0","id":4,"method":"eth_unsubscribe","params":["` + id + `"]}`, `{"jsonrpc":"2.0","id":4,"result":false}`},
+		{"", `{"jsonrpc":"2.0","id":5,"method":"eth_subscribe","params":[]}`, `{"jsonrpc":"2.0","id":5,"error":{"code":-32602,"message":"Invalid params","data":"missing subscription type"}}`},
+	} {
+		rw := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(test.req)))
+		if test.remoteAddr != "" {
+			r.RemoteAddr = test.remoteAddr
+		}
+		server.ServeHTTP(rw, r)
+		if got := rw.Body.String(); got != test.want {
+			t.Errorf("invalid response to %v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("subscription not ended by eth_unsubscribe")
+	}
+	want := `{"jsonrpc":"2.0","id":1,"result":"` + id + `"}
+{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"` + id + `","result":{"address":"0xa"}}}
+`
+	if got := rw.Body.String(); got != want {
+		t.Errorf("invalid stream:\ngot: %v\nwant: %v", got, want)
+	}
+}
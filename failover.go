@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of FailoverConfig.
+const (
+	DefaultProbeMethod   = "rpc.ping"
+	DefaultProbeInterval = 10 * time.Second
+	DefaultProbeTimeout  = 2 * time.Second
+)
+
+// FailoverConfig configures the failover of a client between endpoints, see
+// WithFailover.
+type FailoverConfig struct {
+	// Endpoints are the URLs of the servers, by order of preference: the
+	// first one is the primary, the others its fallbacks.
+	Endpoints []string
+	// ProbeMethod is called on every endpoint every ProbeInterval to check
+	// its health. Any JSON-RPC response, errors included, shows that the
+	// server is up, so the method doesn't need to exist.
+	ProbeMethod   string
+	ProbeInterval time.Duration
+	ProbeTimeout  time.Duration
+}
+
+// WithFailover sends all requests to the first healthy endpoint of
+// cfg.Endpoints, instead of spreading them like WithResolver. Endpoints
+// are marked down when a request fails to reach them and up again when a
+// probe succeeds, so the client falls back when the primary is down and
+// fails back once it recovers. The primary is used if no endpoint is
+// healthy. Probes run until the client is closed.
+func WithFailover(cfg FailoverConfig) ClientOption {
+	return func(c *Client) {
+		if cfg.ProbeMethod == "" {
+			cfg.ProbeMethod = DefaultProbeMethod
+		}
+		cfg.ProbeInterval = durationOr(cfg.ProbeInterval, DefaultProbeInterval)
+		cfg.ProbeTimeout = durationOr(cfg.ProbeTimeout, DefaultProbeTimeout)
+		c.failover = &failover{cfg: cfg, down: make([]int32, len(cfg.Endpoints))}
+	}
+}
+
+type failover struct {
+	cfg  FailoverConfig
+	down []int32 // by endpoint, set while it is down
+}
+
+// endpoint returns the first healthy endpoint, the primary if none is.
+func (f *failover) endpoint() (string, error) {
+	if len(f.cfg.Endpoints) == 0 {
+		return "", errNoEndpoints
+	}
+	for i, ep := range f.cfg.Endpoints {
+		if atomic.LoadInt32(&f.down[i]) == 0 {
+			return ep, nil
+		}
+	}
+	return f.cfg.Endpoints[0], nil
+}
+
+// setDown records whether endpoint is down.
+func (f *failover) setDown(endpoint string, down bool) {
+	var v int32
+	if down {
+		v = 1
+	}
+	for i, ep := range f.cfg.Endpoints {
+		if ep == endpoint {
+			atomic.StoreInt32(&f.down[i], v)
+		}
+	}
+}
+
+// probe checks the health of the endpoints every probe interval until ctx is
+// done.
+func (f *failover) probe(ctx context.Context, c *Client) {
+	t := time.NewTicker(f.cfg.ProbeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		var wg sync.WaitGroup
+		for _, ep := range f.cfg.Endpoints {
+			wg.Add(1)
+			go func(ep string) {
+				defer wg.Done()
+				f.setDown(ep, !f.healthy(ctx, c, ep))
+			}(ep)
+		}
+		wg.Wait()
+	}
+}
+
+// healthy reports whether endpoint answers a probe, sent like the requests
+// of c so that it passes the same authentication.
+func (f *failover) healthy(ctx context.Context, c *Client, endpoint string) bool {
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.ProbeTimeout)
+	defer cancel()
+	b, err := (&request{ID: int64(0), Method: f.cfg.ProbeMethod}).bytes()
+	if err != nil {
+		return false
+	}
+	hreq, err := c.newHTTPRequest(ctx, endpoint, b)
+	if err != nil {
+		return false
+	}
+	hres, err := c.httpClient.Do(hreq)
+	if err != nil {
+		return false
+	}
+	rc, err := decodeResponseBody(hres)
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	return hres.StatusCode == http.StatusOK && decodeResponseFromReader(rc, &Response{}, false, nil) == nil
+}
@@ -0,0 +1,86 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	var primaryDown int32
+	newServer := func(name string, down *int32) *httptest.Server {
+		s := NewServer()
+		s.HandleFunc("name", func(ctx context.Context) (string, error) {
+			return name, nil
+		})
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if down != nil && atomic.LoadInt32(down) == 1 {
+				conn, _, _ := rw.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			s.ServeHTTP(rw, r)
+		}))
+	}
+	primary, fallback := newServer("primary", &primaryDown), newServer("fallback", nil)
+	defer primary.Close()
+	defer fallback.Close()
+
+	client := NewClient("", WithFailover(FailoverConfig{
+		Endpoints:     []string{primary.URL, fallback.URL},
+		ProbeInterval: 10 * time.Millisecond,
+	}))
+	defer client.Close()
+	name := func() string {
+		resp, err := client.Call(context.Background(), "name", nil)
+		if err != nil {
+			return err.Error()
+		}
+		var name string
+		resp.Decode(&name)
+		return name
+	}
+
+	if got := name(); got != "primary" {
+		t.Errorf("invalid endpoint:\ngot: %v\nwant: primary", got)
+	}
+	atomic.StoreInt32(&primaryDown, 1)
+	name() // fails and marks the primary down
+	if got := name(); got != "fallback" {
+		t.Errorf("invalid endpoint with the primary down:\ngot: %v\nwant: fallback", got)
+	}
+	atomic.StoreInt32(&primaryDown, 0)
+	deadline := time.Now().Add(time.Second)
+	for name() != "primary" {
+		if time.Now().After(deadline) {
+			t.Fatalf("no fail-back to the primary")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFailoverSignedProbes(t *testing.T) {
+	s := NewServer()
+	secrets := map[string][]byte{"billing": []byte("s3cret")}
+	primary := httptest.NewServer(VerifySignatures(SignatureConfig{Secret: func(id string) []byte { return secrets[id] }})(s))
+	defer primary.Close()
+	fallback := httptest.NewServer(s)
+	defer fallback.Close()
+
+	client := NewClient("", WithSigning("billing", []byte("s3cret")), WithFailover(FailoverConfig{
+		Endpoints:     []string{primary.URL, fallback.URL},
+		ProbeInterval: 10 * time.Millisecond,
+	}))
+	defer client.Close()
+	client.failover.setDown(primary.URL, true)
+	deadline := time.Now().Add(time.Second)
+	for ep, _ := client.failover.endpoint(); ep != primary.URL; ep, _ = client.failover.endpoint() {
+		if time.Now().After(deadline) {
+			t.Fatalf("unsigned probes: primary left down")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
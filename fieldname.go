@@ -0,0 +1,211 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// FieldMatching is how the keys of params objects are matched to the fields
+// of params structs, see Server.FieldMatching.
+type FieldMatching int
+
+const (
+	// MatchFold matches keys case insensitively, as encoding/json does.
+	MatchFold FieldMatching = iota
+	// MatchExact matches keys case sensitively.
+	MatchExact
+	// MatchLoose matches keys ignoring case, underscores and dashes, so
+	// that "user_id", "user-id" and "userId" all match a field UserID.
+	MatchLoose
+)
+
+func (m FieldMatching) match(key, name string) bool {
+	switch m {
+	case MatchExact:
+		return key == name
+	case MatchLoose:
+		return looseName(key) == looseName(name)
+	}
+	return strings.EqualFold(key, name)
+}
+
+func looseName(s string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+}
+
+// jsonrpcTag returns the name and the options of the jsonrpc tag of f, such
+// as `jsonrpc:"user_id,redact"`. The options may be given alone, as in
+// `jsonrpc:"redact"`.
+func jsonrpcTag(f reflect.StructField) (name string, opts []string) {
+	opts = strings.Split(f.Tag.Get("jsonrpc"), ",")
+	switch opts[0] {
+	case "redact", "squash":
+		return "", opts
+	}
+	return opts[0], opts[1:]
+}
+
+func hasTagOption(opts []string, o string) bool {
+	for _, opt := range opts {
+		if opt == o {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldNames are the fields of the objects in the encoding of a type, whose
+// names in params and results may differ from those of encoding/json.
+type fieldNames struct {
+	root *nameNode
+	// renamed reports whether any field is named by a jsonrpc tag.
+	renamed bool
+}
+
+// nameNode is a type holding structs: a struct, with the nodes of its fields,
+// or a slice, array or map, with the node of its elements. Nodes of
+// recursive types are shared.
+type nameNode struct {
+	fields   []jsonField
+	children map[string]*nameNode // by field name, nil for containers
+	elem     *nameNode
+}
+
+// fieldNamesOf returns the field names of t.
+func fieldNamesOf(t reflect.Type) fieldNames {
+	var n fieldNames
+	n.root = buildNameNode(t, &n, map[reflect.Type]*nameNode{})
+	return n
+}
+
+func buildNameNode(t reflect.Type, n *fieldNames, nodes map[reflect.Type]*nameNode) *nameNode {
+	if t == nil {
+		return nil
+	}
+	t = indirect(t)
+	if t.Implements(typeOfMarshaler) || reflect.PtrTo(t).Implements(typeOfUnmarshaler) {
+		return nil
+	}
+	if node, ok := nodes[t]; ok {
+		return node
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		elem := buildNameNode(t.Elem(), n, nodes)
+		if elem == nil {
+			return nil
+		}
+		return &nameNode{elem: elem}
+	case reflect.Struct:
+	default:
+		return nil
+	}
+	node := &nameNode{fields: jsonFields(t), children: map[string]*nameNode{}}
+	nodes[t] = node
+	for _, f := range node.fields {
+		if f.wire != f.name {
+			n.renamed = true
+		}
+		if child := buildNameNode(f.typ, n, nodes); child != nil {
+			node.children[f.name] = child
+		}
+	}
+	return node
+}
+
+// convertsFieldNames reports whether the keys of the objects of n are
+// converted from or to the names of encoding/json.
+func (s *Server) convertsFieldNames(n fieldNames) bool {
+	return n.renamed || s.FieldMatching != MatchFold && n.root != nil
+}
+
+// decodeFieldNames renames the keys of the objects of params matching fields
+// to the names of encoding/json. Keys encoding/json would match to a field
+// they don't match are dropped.
+func (s *Server) decodeFieldNames(params json.RawMessage, n fieldNames) (json.RawMessage, error) {
+	return convertFieldNames(params, n.root, func(m map[string]interface{}, fields []jsonField) map[string]interface{} {
+		out := make(map[string]interface{}, len(m))
+	keys:
+		for k, v := range m {
+			for _, f := range fields {
+				if k == f.wire || s.FieldMatching.match(k, f.wire) {
+					if _, ok := out[f.name]; !ok || k == f.wire {
+						out[f.name] = v
+					}
+					continue keys
+				}
+			}
+			for _, f := range fields {
+				if strings.EqualFold(k, f.name) {
+					continue keys
+				}
+			}
+			out[k] = v
+		}
+		return out
+	}, nil)
+}
+
+// encodeFieldNames renames the keys of the objects of result from the names
+// of encoding/json.
+func encodeFieldNames(result json.RawMessage, n fieldNames) (json.RawMessage, error) {
+	return convertFieldNames(result, n.root, nil, func(m map[string]interface{}, fields []jsonField) map[string]interface{} {
+		for _, f := range fields {
+			if v, ok := m[f.name]; ok && f.wire != f.name {
+				delete(m, f.name)
+				m[f.wire] = v
+			}
+		}
+		return m
+	})
+}
+
+type renameFunc func(m map[string]interface{}, fields []jsonField) map[string]interface{}
+
+// convertFieldNames renames the keys of the objects of b with before, then
+// those of their fields, then with after.
+func convertFieldNames(b json.RawMessage, root *nameNode, before, after renameFunc) (json.RawMessage, error) {
+	if root == nil || isNullParams(b) || firstByte(b) != '{' && firstByte(b) != '[' {
+		return b, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(renameFields(v, root, before, after))
+}
+
+func renameFields(v interface{}, node *nameNode, before, after renameFunc) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if node.children == nil {
+			for k, e := range v {
+				v[k] = renameFields(e, node.elem, before, after)
+			}
+			return v
+		}
+		if before != nil {
+			v = before(v, node.fields)
+		}
+		for name, child := range node.children {
+			if e, ok := v[name]; ok {
+				v[name] = renameFields(e, child, before, after)
+			}
+		}
+		if after != nil {
+			v = after(v, node.fields)
+		}
+		return v
+	case []interface{}:
+		if node.elem != nil {
+			for i, e := range v {
+				v[i] = renameFields(e, node.elem, before, after)
+			}
+		}
+	}
+	return v
+}
@@ -0,0 +1,44 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+)
+
+type Account struct {
+	UserID   int64    `jsonrpc:"user_id"`
+	Nickname string   `json:"nick" jsonrpc:"display_name"`
+	Email    string   `json:"email"`
+	Tags     []string `json:"tags,omitempty"`
+	Parent   *Account `json:"parent,omitempty"`
+}
+
+func TestFieldNames(t *testing.T) {
+	tests := []struct {
+		matching  FieldMatching
+		req, want string
+	}{
+		{MatchFold, `{"user_id":1,"display_name":"bob","email":"b@x","parent":{"user_id":2}}`, `{"display_name":"bob","email":"b@x","parent":{"display_name":"","email":"","user_id":3},"user_id":2}`},
+		{MatchFold, `{"User_ID":1,"Email":"b@x"}`, `{"display_name":"","email":"b@x","user_id":2}`},
+		// the names of encoding/json don't match renamed fields
+		{MatchFold, `{"UserID":1,"nick":"bob","email":"b@x"}`, `{"display_name":"","email":"b@x","user_id":1}`},
+		{MatchExact, `{"User_ID":1,"Email":"b@x","display_name":"bob"}`, `{"display_name":"bob","email":"","user_id":1}`},
+		{MatchLoose, `{"userId":1,"display-name":"bob","EMAIL":"b@x"}`, `{"display_name":"bob","email":"b@x","user_id":2}`},
+	}
+	for _, test := range tests {
+		server := NewServer()
+		server.FieldMatching = test.matching
+		server.HandleFunc("touch", func(ctx context.Context, a Account) (Account, error) {
+			a.UserID++
+			if a.Parent != nil {
+				a.Parent.UserID++
+			}
+			return a, nil
+		})
+		req := `{"jsonrpc":"2.0","id":1,"method":"touch","params":` + test.req + `}`
+		want := `{"jsonrpc":"2.0","id":1,"result":` + test.want + `}`
+		if got := string(server.ServeMessage(context.Background(), []byte(req))); got != want {
+			t.Errorf("%v %v:\ngot: %v\nwant: %v", test.matching, test.req, got, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Filter selects the events delivered to a subscription by the value of
+// their data, evaluated by the server so that clients interested in a small
+// part of a busy topic don't receive the rest. Keys are dot separated paths
+// into the data as encoded in JSON, such as "customer.country". Values are
+// either the value expected at the path or an object of comparisons:
+//
+//	{"status": "paid", "amount": {"gte": 100, "lt": 1000}, "country": {"in": ["FR", "DE"]}}
+//
+// Comparisons are "eq", "ne", "gt", "gte", "lt", "lte" and "in". Ranges
+// compare numbers or strings. An event is delivered if all the conditions
+// hold.
+type Filter map[string]interface{}
+
+type condition struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+// compile returns the conditions of f, sorted for stable evaluation.
+func (f Filter) compile() ([]condition, error) {
+	var conds []condition
+	for key, v := range f {
+		path := strings.Split(key, ".")
+		ops, ok := v.(map[string]interface{})
+		if !ok || !isComparison(ops) {
+			conds = append(conds, condition{path: path, op: "eq", value: normalize(v)})
+			continue
+		}
+		for op, operand := range ops {
+			operand = normalize(operand)
+			switch op {
+			case "gt", "gte", "lt", "lte":
+				switch operand.(type) {
+				case float64, string:
+				default:
+					return nil, fmt.Errorf("filter %v: %v needs a number or a string", key, op)
+				}
+			case "in":
+				if _, ok := operand.([]interface{}); !ok {
+					return nil, fmt.Errorf("filter %v: in needs an array", key)
+				}
+			}
+			conds = append(conds, condition{path: path, op: op, value: operand})
+		}
+	}
+	sort.Slice(conds, func(i, j int) bool {
+		a, b := strings.Join(conds[i].path, "."), strings.Join(conds[j].path, ".")
+		return a < b || a == b && conds[i].op < conds[j].op
+	})
+	return conds, nil
+}
+
+func isComparison(ops map[string]interface{}) bool {
+	if len(ops) == 0 {
+		return false
+	}
+	for op := range ops {
+		switch op {
+		case "eq", "ne", "gt", "gte", "lt", "lte", "in":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// normalize returns v as decoded by encoding/json, so that values set from
+// Go compare with the data of events.
+func normalize(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, bool, float64, string:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	json.Unmarshal(b, &n)
+	return n
+}
+
+// matchFilter reports whether data, as decoded by encoding/json, satisfies
+// all of conds.
+func matchFilter(conds []condition, data interface{}) bool {
+	for _, c := range conds {
+		v, ok := lookupPath(data, c.path)
+		if !ok {
+			if c.op == "ne" {
+				continue
+			}
+			return false
+		}
+		if !c.holds(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupPath(v interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func (c *condition) holds(v interface{}) bool {
+	switch c.op {
+	case "eq":
+		return reflect.DeepEqual(v, c.value)
+	case "ne":
+		return !reflect.DeepEqual(v, c.value)
+	case "in":
+		for _, e := range c.value.([]interface{}) {
+			if reflect.DeepEqual(v, e) {
+				return true
+			}
+		}
+		return false
+	}
+	cmp, ok := compareValues(v, c.value)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "gt":
+		return cmp > 0
+	case "gte":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	default: // "lte"
+		return cmp <= 0
+	}
+}
+
+// compareValues compares two numbers or two strings.
+func compareValues(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	}
+	return 0, false
+}
@@ -0,0 +1,15 @@
+//go:build go1.24
+// +build go1.24
+
+package jsonrpc
+
+import "net/http"
+
+// enableH2C allows srv to accept HTTP/2 connections without TLS.
+func enableH2C(srv *http.Server) error {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	srv.Protocols = &p
+	return nil
+}
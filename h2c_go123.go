@@ -0,0 +1,13 @@
+//go:build !go1.24
+// +build !go1.24
+
+package jsonrpc
+
+import (
+	"errors"
+	"net/http"
+)
+
+func enableH2C(srv *http.Server) error {
+	return errors.New("jsonrpc: h2c requires go1.24 or later")
+}
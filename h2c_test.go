@@ -0,0 +1,23 @@
+//go:build go1.24
+// +build go1.24
+
+package jsonrpc
+
+import "testing"
+
+func TestEnableH2C(t *testing.T) {
+	server := NewServer()
+	srv, err := server.newHTTPServer(":0")
+	if err != nil {
+		t.Fatalf("creating http server: %v", err)
+	}
+	if srv.ReadTimeout != DefaultReadTimeout || srv.IdleTimeout != DefaultIdleTimeout {
+		t.Errorf("default timeouts not applied: read %v, idle %v", srv.ReadTimeout, srv.IdleTimeout)
+	}
+	if err := enableH2C(srv); err != nil {
+		t.Fatalf("enabling h2c: %v", err)
+	}
+	if srv.Protocols == nil || !srv.Protocols.UnencryptedHTTP2() {
+		t.Errorf("h2c not enabled")
+	}
+}
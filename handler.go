@@ -0,0 +1,181 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// handlerFunc executes a handler with the JSON encoded params of a request.
+// It returns an error wrapping errServerInvalidParams if params can't be
+// decoded into the handler argument, see decodeError.
+type handlerFunc func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// UseNumber decodes the numbers of the params of a dynamic method, one taking
+// a map[string]interface{}, an interface{} or a slice of them, as
+// json.Number values instead of float64, so that large integers survive
+// generic proxies.
+func UseNumber() MethodOption {
+	return func(h *handlerType) {
+		h.useNumber = true
+	}
+}
+
+// compileHandler builds the handlerFunc of h once, at registration time.
+// Common signatures are called directly, others through reflection, as are
+// handlers whose params hold values of types with a codec. The params of
+// dynamic handlers are decoded with json.Number values if useNumber is set.
+func compileHandler(h reflect.Value, ptype reflect.Type, codecs []typeCodec, useNumber bool) handlerFunc {
+	if len(codecs) > 0 {
+		return reflectHandler(h, ptype, codecs, useNumber)
+	}
+	decodeDynamic := decodeParams
+	if useNumber {
+		decodeDynamic = decodeDynamicParams
+	}
+	switch f := h.Interface().(type) {
+	case func(context.Context) (interface{}, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return f(ctx)
+		}
+	case func(context.Context) (string, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return f(ctx)
+		}
+	case func(context.Context, string) (string, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var p string
+			if err := decodeParams(params, &p); invalidParams(ctx, params, err, p == "") {
+				return nil, decodeError(params, typeOfString, err)
+			}
+			return f(ctx, p)
+		}
+	case func(context.Context, int) (int, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var p int
+			if err := decodeParams(params, &p); invalidParams(ctx, params, err, p == 0) {
+				return nil, decodeError(params, typeOfInt, err)
+			}
+			return f(ctx, p)
+		}
+	case func(context.Context, map[string]interface{}) (interface{}, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var p map[string]interface{}
+			if err := decodeDynamic(params, &p); invalidParams(ctx, params, err, p == nil) {
+				return nil, errServerInvalidParams
+			}
+			return f(ctx, p)
+		}
+	case func(context.Context, interface{}) (interface{}, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var p interface{}
+			if err := decodeDynamic(params, &p); invalidParams(ctx, params, err, p == nil) {
+				return nil, errServerInvalidParams
+			}
+			return f(ctx, p)
+		}
+	case func(context.Context, json.RawMessage) (interface{}, error):
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			if isNullParams(params) && !allowsNullParams(ctx) {
+				return nil, errServerInvalidParams
+			}
+			return f(ctx, params)
+		}
+	}
+	return reflectHandler(h, ptype, nil, useNumber)
+}
+
+// reflectHandler returns a handlerFunc calling h through reflection. The
+// values of params handled by codecs are decoded by them, see decodeCodecs.
+func reflectHandler(h reflect.Value, ptype reflect.Type, codecs []typeCodec, useNumber bool) handlerFunc {
+	if ptype == nil {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return results(h.Call([]reflect.Value{reflect.ValueOf(ctx)}))
+		}
+	}
+
+	isPtr := ptype.Kind() == reflect.Ptr
+	elem := ptype
+	if isPtr {
+		elem = ptype.Elem()
+	}
+	decode := decodeParams
+	if useNumber && isDynamic(elem) {
+		decode = decodeDynamicParams
+	}
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var values []codecValue
+		if len(codecs) > 0 {
+			var err error
+			if params, values, err = decodeCodecs(params, codecs); err != nil {
+				return nil, ErrInvalidParamsf("%v", err)
+			}
+		}
+		pvalue := reflect.New(elem)
+		if len(values) == 1 && len(values[0].path) == 0 {
+			// the params are a value of the type of a codec
+			pvalue.Elem().Set(values[0].value)
+		} else {
+			// QUESTION: if pvalue doesnt change params should be invalid?
+			err := decode(params, pvalue.Interface())
+			if err == nil && len(values) > 0 {
+				err = setCodecValues(pvalue.Elem(), values)
+			}
+			if invalidParams(ctx, params, err, pvalue.Elem().IsZero()) {
+				return nil, decodeError(params, elem, err)
+			}
+		}
+		if !isPtr {
+			pvalue = pvalue.Elem()
+		}
+		return results(h.Call([]reflect.Value{reflect.ValueOf(ctx), pvalue}))
+	}
+}
+
+// decodeParams unmarshals params into v, missing and null params are invalid.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if isNullParams(params) {
+		return errServerInvalidParams
+	}
+	return json.Unmarshal(params, v)
+}
+
+// decodeDynamicParams is decodeParams for params of dynamic types, such as
+// map[string]interface{}, decoding numbers as json.Number, see UseNumber.
+func decodeDynamicParams(params json.RawMessage, v interface{}) error {
+	if isNullParams(params) {
+		return errServerInvalidParams
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// isDynamic reports whether t is an interface, or a map or slice of them.
+func isDynamic(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice:
+		return t.Elem().Kind() == reflect.Interface && t.Elem().NumMethod() == 0
+	}
+	return t.Kind() == reflect.Interface && t.NumMethod() == 0
+}
+
+// invalidParams reports whether params are invalid given the error decoding
+// them and whether they decoded to the zero value. Null and zero params are
+// accepted where allowed, see ServeLSP.
+func invalidParams(ctx context.Context, params json.RawMessage, err error, zero bool) bool {
+	if err == nil && !zero {
+		return false
+	}
+	return !allowsNullParams(ctx) || err != nil && !isNullParams(params)
+}
+
+func isNullParams(params json.RawMessage) bool {
+	return params == nil || string(params) == string(null)
+}
+
+func results(ret []reflect.Value) (interface{}, error) {
+	err, _ := ret[1].Interface().(error)
+	return ret[0].Interface(), err
+}
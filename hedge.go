@@ -0,0 +1,63 @@
+package jsonrpc
+
+import (
+	"context"
+	"time"
+)
+
+type hedgeKey struct{}
+
+// WithHedging sends the call a second time if it isn't answered within delay,
+// or right away if the first request fails to reach the server, and returns
+// the first response, cancelling the other request. With WithResolver, the
+// second request goes to the next endpoint. It trades load for tail latency
+// and must only be used with idempotent methods. Notifications and batches
+// are not hedged.
+func WithHedging(delay time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.hedge = delay
+	}
+}
+
+// exchange sends req and decodes its response into resp, hedging the request
+// if the call asked for it.
+func (c *Client) exchange(ctx context.Context, req *request, resp *Response) error {
+	delay, ok := ctx.Value(hedgeKey{}).(time.Duration)
+	if !ok {
+		return c.roundTrip(ctx, req, resp)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		resp *Response
+		err  error
+	}
+	results := make(chan result, 2)
+	send := func() {
+		r := &Response{}
+		results <- result{r, c.roundTrip(ctx, req, r)}
+	}
+	go send()
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	pending, hedged := 1, false
+	var err error
+	for pending > 0 {
+		select {
+		case <-t.C:
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				*resp = *r.resp
+				return nil
+			}
+			err = r.err
+		}
+		if !hedged {
+			hedged = true
+			pending++
+			go send()
+		}
+	}
+	return err
+}
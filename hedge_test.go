@@ -0,0 +1,49 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHedging(t *testing.T) {
+	cancelled := make(chan struct{})
+	slow := NewServer()
+	slow.HandleFunc("get", func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			close(cancelled)
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "slow", nil
+		}
+	})
+	fast := NewServer()
+	fast.HandleFunc("get", func(ctx context.Context) (string, error) {
+		return "fast", nil
+	})
+	ts1, ts2 := httptest.NewServer(slow), httptest.NewServer(fast)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	client := NewClient("", WithResolver(StaticResolver{ts1.URL, ts2.URL}))
+	defer client.Close()
+	start := time.Now()
+	resp, err := client.Call(context.Background(), "get", nil, WithHedging(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("get: error not expected: %v", err)
+	}
+	var got string
+	if err := resp.Decode(&got); err != nil || got != "fast" {
+		t.Errorf("got %q (%v), want the hedged response", got, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("hedged call took %v", d)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("slow request not cancelled")
+	}
+}
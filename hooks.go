@@ -0,0 +1,133 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// RequestInfo describes a request received by the server. Params are
+// redacted, see Server.Redact.
+type RequestInfo struct {
+	ID           interface{}
+	Method       string
+	Params       json.RawMessage
+	Notification bool
+}
+
+// Hooks are observational callbacks fired by the server for every request,
+// notifications and requests which failed to decode included. Unlike
+// handlers wrappers they can't alter the outcome. Hooks are called on the
+// goroutine serving the request and must not retain the Response they are
+// given.
+type Hooks struct {
+	// OnRequest is called with every decoded request before its method is
+	// executed.
+	OnRequest func(ctx context.Context, req *RequestInfo)
+	// OnResponse is called once a request was executed, or failed to
+	// decode. resp is nil for notifications, req is nil when the message
+	// couldn't be decoded at all.
+	OnResponse func(ctx context.Context, req *RequestInfo, resp *Response)
+	// OnError is called when a request fails, notifications included, with
+	// the error returned by the handler or the *Error sent to the client.
+	OnError func(ctx context.Context, req *RequestInfo, err error)
+	// OnPanic is called when a handler panics, before Internal error is
+	// returned to the client. The panic is logged if OnPanic is nil.
+	OnPanic func(ctx context.Context, p *PanicInfo)
+	// OnBatch is called once a batch was executed, after the hooks of its
+	// requests. Empty batches, answered with a single Invalid Request error,
+	// and batches of notifications, answered with no content, are reported
+	// with no responses.
+	OnBatch func(ctx context.Context, b *BatchInfo)
+}
+
+// BatchInfo describes a batch received by the server.
+type BatchInfo struct {
+	// Requests is the number of entries of the batch, notifications and
+	// invalid entries included.
+	Requests int
+	// Responses is the number of responses sent back.
+	Responses int
+}
+
+// PanicInfo describes a panic recovered from a handler.
+type PanicInfo struct {
+	Value  interface{}
+	Stack  []byte
+	Method string
+	ID     interface{}
+}
+
+// panicError is the error reported to OnError for a recovered panic.
+type panicError struct {
+	value interface{}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprint("panic: ", e.value)
+}
+
+// recoverPanic reports the panic v raised by the handler of req.
+func (s *Server) recoverPanic(ctx context.Context, req *request, v interface{}) error {
+	p := &PanicInfo{Value: v, Stack: debug.Stack(), Method: req.Method, ID: req.ID}
+	if s.Hooks.OnPanic != nil {
+		s.Hooks.OnPanic(ctx, p)
+	} else {
+		log.Printf("jsonrpc: panic serving %v%s: %v\n%s", p.Method, logID(ctx), p.Value, p.Stack)
+	}
+	return &panicError{v}
+}
+
+func (h *Hooks) enabled() bool {
+	return h.OnRequest != nil || h.OnResponse != nil || h.OnError != nil
+}
+
+func (h *Hooks) onBatch(ctx context.Context, requests, responses int) {
+	if h.OnBatch != nil {
+		h.OnBatch(ctx, &BatchInfo{Requests: requests, Responses: responses})
+	}
+}
+
+func (h *Hooks) onRequest(ctx context.Context, req *RequestInfo) {
+	if h.OnRequest != nil {
+		h.OnRequest(ctx, req)
+	}
+}
+
+// onDone fires OnError, if err is set, and OnResponse.
+func (h *Hooks) onDone(ctx context.Context, req *RequestInfo, resp *Response, err error) {
+	if err != nil && h.OnError != nil {
+		h.OnError(ctx, req, err)
+	}
+	if h.OnResponse != nil {
+		h.OnResponse(ctx, req, resp)
+	}
+}
+
+func (s *Server) newRequestInfo(req *request) *RequestInfo {
+	if req == nil {
+		return nil
+	}
+	return &RequestInfo{
+		ID:           req.ID,
+		Method:       req.Method,
+		Params:       s.Redact(req.Method, req.Params),
+		Notification: req.isNotification,
+	}
+}
+
+// decodeError returns the response to a message which couldn't be decoded
+// into a valid request, req being what could be decoded if anything.
+func (s *Server) decodeError(ctx context.Context, req *request, err *Error) *Response {
+	var id interface{}
+	if req != nil {
+		id = req.ID
+	}
+	resp := errResponse(id, s.localize(ctx, err))
+	if s.Hooks.enabled() {
+		s.Hooks.onDone(ctx, s.newRequestInfo(req), resp, err)
+	}
+	return resp
+}
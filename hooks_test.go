@@ -0,0 +1,105 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.HandleFunc("fail", func(ctx context.Context, n int) (int, error) {
+		return 0, errors.New("failed")
+	})
+	server.Hooks = Hooks{
+		OnRequest: func(ctx context.Context, req *RequestInfo) {
+			events = append(events, fmt.Sprintf("request %v %v", req.Method, req.Notification))
+		},
+		OnResponse: func(ctx context.Context, req *RequestInfo, resp *Response) {
+			if resp == nil {
+				events = append(events, "response <nil>")
+				return
+			}
+			events = append(events, fmt.Sprintf("response %v %v", resp.ID(), resp.Err()))
+		},
+		OnError: func(ctx context.Context, req *RequestInfo, err error) {
+			events = append(events, fmt.Sprintf("error %v", err))
+		},
+	}
+
+	msgs := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`,
+		`{"jsonrpc":"2.0","method":"fail","params":1}`,
+		`{"jsonrpc":"2.0","id":2`,
+		`{"jsonrpc":"2.0","id":3}`,
+	}
+	for _, msg := range msgs {
+		server.ServeMessage(context.Background(), []byte(msg))
+	}
+
+	want := []string{
+		"request sum false",
+		"response 1 <nil>",
+		"request fail true",
+		"error failed",
+		"response <nil>",
+		"error jsonrpc: parse error",
+		"response null jsonrpc: parse error",
+		"error jsonrpc: invalid request",
+		"response 3 jsonrpc: invalid request",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("invalid hook events:\ngot: %q\nwant: %q", events, want)
+	}
+}
+
+func TestOnPanic(t *testing.T) {
+	var info *PanicInfo
+	var hookErr error
+	server := NewServer()
+	server.HandleFunc("crash", func(ctx context.Context) (int, error) {
+		panic("boom")
+	})
+	server.Hooks.OnPanic = func(ctx context.Context, p *PanicInfo) { info = p }
+	server.Hooks.OnError = func(ctx context.Context, req *RequestInfo, err error) { hookErr = err }
+
+	got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":"x","method":"crash"}`))
+	want := `{"jsonrpc":"2.0","id":"x","error":{"code":-32603,"message":"Internal error"}}`
+	if string(got) != want {
+		t.Errorf("invalid jsonrpc response: \ngot: %s\nwant: %v\n", got, want)
+	}
+	if info == nil || info.Value != "boom" || info.Method != "crash" || info.ID != "x" {
+		t.Fatalf("invalid panic info: %+v", info)
+	}
+	if !strings.Contains(string(info.Stack), "TestOnPanic") {
+		t.Errorf("stack doesn't contain the handler:\n%s", info.Stack)
+	}
+	if hookErr == nil || hookErr.Error() != "panic: boom" {
+		t.Errorf("invalid OnError error: %v", hookErr)
+	}
+}
+
+func TestOnBatch(t *testing.T) {
+	var batches []BatchInfo
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.Hooks.OnBatch = func(ctx context.Context, b *BatchInfo) { batches = append(batches, *b) }
+
+	for _, msg := range []string{
+		`[{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}},{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}},1]`,
+		`[{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}]`,
+		`[]`,
+		`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`,
+	} {
+		server.ServeMessage(context.Background(), []byte(msg))
+	}
+	want := []BatchInfo{{3, 2}, {1, 0}, {0, 0}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("invalid batches:\ngot: %v\nwant: %v", batches, want)
+	}
+}
@@ -0,0 +1,90 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+)
+
+type (
+	headerKey      struct{}
+	httpContextKey struct{}
+)
+
+// httpContext carries the HTTP exchange of a request. It is its own context
+// to save an allocation per request.
+type httpContext struct {
+	context.Context
+	r  *http.Request
+	rw http.ResponseWriter
+}
+
+func (c *httpContext) Value(key interface{}) interface{} {
+	if key == (httpContextKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// HTTPRequest returns the HTTP request being served, nil if the request
+// didn't come over HTTP. Its body must not be read.
+func HTTPRequest(ctx context.Context) *http.Request {
+	if hc, ok := ctx.Value(httpContextKey{}).(*httpContext); ok {
+		return hc.r
+	}
+	return nil
+}
+
+// SetResponseHeader sets the header key of the HTTP response to value. It
+// does nothing if the request didn't come over HTTP. It must be called before
+// the handler returns, streaming handlers must call it before sending their
+// first value. Headers are shared by the requests of a batch.
+func SetResponseHeader(ctx context.Context, key, value string) {
+	if hc, ok := ctx.Value(httpContextKey{}).(*httpContext); ok {
+		hc.rw.Header().Set(key, value)
+	}
+}
+
+// SetCookie adds a Set-Cookie header to the HTTP response, with the same
+// restrictions as SetResponseHeader.
+func SetCookie(ctx context.Context, cookie *http.Cookie) {
+	if hc, ok := ctx.Value(httpContextKey{}).(*httpContext); ok {
+		http.SetCookie(hc.rw, cookie)
+	}
+}
+
+// RemoteAddr returns the network address of the HTTP client, as set in
+// http.Request.RemoteAddr, "" if the request didn't come over HTTP.
+func RemoteAddr(ctx context.Context) string {
+	if r := HTTPRequest(ctx); r != nil {
+		return r.RemoteAddr
+	}
+	return ""
+}
+
+// Header returns the first value of the incoming HTTP header name if it is
+// listed in Server.ContextHeaders, "" otherwise.
+func Header(ctx context.Context, name string) string {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	return h.Get(name)
+}
+
+// HeaderValues returns all the values of the incoming HTTP header name if it
+// is listed in Server.ContextHeaders.
+func HeaderValues(ctx context.Context, name string) []string {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	return h[textproto.CanonicalMIMEHeaderKey(name)]
+}
+
+// withHeaders returns a copy of ctx carrying the headers of r allowed by
+// s.ContextHeaders.
+func (s *Server) withHeaders(ctx context.Context, r *http.Request) context.Context {
+	h := make(http.Header, len(s.ContextHeaders))
+	for _, name := range s.ContextHeaders {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if v := r.Header[key]; len(v) > 0 {
+			h[key] = v
+		}
+	}
+	return context.WithValue(ctx, headerKey{}, h)
+}
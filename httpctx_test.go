@@ -0,0 +1,104 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextHeaders(t *testing.T) {
+	var forwarded []string
+	var agent, secret string
+	server := NewServer()
+	server.ContextHeaders = []string{"x-forwarded-for", "User-Agent"}
+	server.HandleFunc("headers", func(ctx context.Context) (int, error) {
+		forwarded = HeaderValues(ctx, "X-Forwarded-For")
+		agent, secret = Header(ctx, "user-agent"), Header(ctx, "Authorization")
+		return 0, nil
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"headers"}`))
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("Authorization", "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+
+	if want := []string{"10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(forwarded, want) {
+		t.Errorf("invalid X-Forwarded-For:\ngot: %v\nwant: %v", forwarded, want)
+	}
+	if agent != "test" {
+		t.Errorf("invalid User-Agent:\ngot: %q\nwant: test", agent)
+	}
+	if secret != "" {
+		t.Errorf("header not in the allowlist exposed: %q", secret)
+	}
+	if h := Header(context.Background(), "User-Agent"); h != "" {
+		t.Errorf("header outside of a request: %q", h)
+	}
+}
+
+func TestHTTPRequest(t *testing.T) {
+	var req *http.Request
+	var addr string
+	server := NewServer()
+	server.HandleFunc("request", func(ctx context.Context) (int, error) {
+		req, addr = HTTPRequest(ctx), RemoteAddr(ctx)
+		return 0, nil
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/rpc", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"request"}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+
+	if req == nil || req.URL.Path != "/rpc" {
+		t.Fatalf("invalid request: %v", req)
+	}
+	if !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Errorf("invalid remote address: %q", addr)
+	}
+
+	server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"request"}`))
+	if req != nil || addr != "" {
+		t.Errorf("request outside of HTTP:\ngot: %v, %q\nwant: nil", req, addr)
+	}
+}
+
+func TestSetResponseHeader(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("login", func(ctx context.Context) (int, error) {
+		SetResponseHeader(ctx, "X-Session", "abc")
+		SetCookie(ctx, &http.Cookie{Name: "session", Value: "abc"})
+		return 0, nil
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"login"}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if h := resp.Header.Get("X-Session"); h != "abc" {
+		t.Errorf("invalid header:\ngot: %q\nwant: abc", h)
+	}
+	if c := resp.Cookies(); len(c) != 1 || c[0].Name != "session" || c[0].Value != "abc" {
+		t.Errorf("invalid cookies: %v", c)
+	}
+
+	// no-op outside of HTTP
+	server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"login"}`))
+}
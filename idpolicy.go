@@ -0,0 +1,87 @@
+package jsonrpc
+
+import "math"
+
+// IDPolicy constrains the ids of requests, see Server.IDPolicy. Requests
+// breaking it are answered with ErrInvalidRequest, the reason as data. A
+// zero field means no constraint, but ids which aren't strings, numbers or
+// null are rejected whatever the policy.
+type IDPolicy struct {
+	// RequireString rejects calls whose id isn't a string.
+	RequireString bool `json:"require_string,omitempty"`
+	// RequireUUID rejects calls whose id isn't a UUID string, such as
+	// "f47ac10b-58cc-4372-a567-0e02b2c3d479".
+	RequireUUID bool `json:"require_uuid,omitempty"`
+	// ForbidFractional rejects numeric ids with a fractional part, which
+	// the specification discourages.
+	ForbidFractional bool `json:"forbid_fractional,omitempty"`
+	// ForbidNull rejects requests with a null id, which are otherwise
+	// handled as notifications.
+	ForbidNull bool `json:"forbid_null,omitempty"`
+	// Check, if set, returns why the id of a call is rejected, if it is.
+	// It is given strings and float64 numbers, ids of other types being
+	// always rejected.
+	Check func(id interface{}) error `json:"-"`
+}
+
+// check returns the error answering req if its id breaks the policy.
+func (p *IDPolicy) check(req *request) *Error {
+	if req.nullID {
+		if p.ForbidNull {
+			return idPolicyError("null id")
+		}
+		return nil
+	}
+	if req.isNotification {
+		return nil
+	}
+	switch id := req.ID.(type) {
+	case string:
+		if p.RequireUUID && !isUUID(id) {
+			return idPolicyError("id is not a UUID")
+		}
+	case float64:
+		switch {
+		case p.RequireString || p.RequireUUID:
+			return idPolicyError("id is not a string")
+		case p.ForbidFractional && id != math.Trunc(id):
+			return idPolicyError("fractional id")
+		}
+	default:
+		// objects, arrays and booleans aren't ids, the response gets a
+		// null id like those to requests whose id can't be told
+		req.ID = nil
+		return idPolicyError("id is not a string or a number")
+	}
+	if p.Check != nil {
+		if err := p.Check(req.ID); err != nil {
+			return idPolicyError(err.Error())
+		}
+	}
+	return nil
+}
+
+func idPolicyError(reason string) *Error {
+	return &Error{Code: CodeInvalidRequest, Message: ErrInvalidRequest.Message, Data: reason}
+}
+
+// isUUID reports whether s is a UUID in its canonical textual form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+			continue
+		}
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
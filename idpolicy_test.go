@@ -0,0 +1,43 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIDPolicy(t *testing.T) {
+	pong := func(ctx context.Context) (string, error) { return "pong", nil }
+	tests := []struct {
+		policy    IDPolicy
+		req, want string
+	}{
+		{IDPolicy{}, `{"jsonrpc":"2.0","id":1.5,"method":"ping"}`, `{"jsonrpc":"2.0","id":1.5,"result":"pong"}`},
+		{IDPolicy{ForbidFractional: true}, `{"jsonrpc":"2.0","id":1.5,"method":"ping"}`, `{"jsonrpc":"2.0","id":1.5,"error":{"code":-32600,"message":"Invalid Request","data":"fractional id"}}`},
+		{IDPolicy{ForbidFractional: true}, `{"jsonrpc":"2.0","id":2,"method":"ping"}`, `{"jsonrpc":"2.0","id":2,"result":"pong"}`},
+		{IDPolicy{RequireString: true}, `{"jsonrpc":"2.0","id":2,"method":"ping"}`, `{"jsonrpc":"2.0","id":2,"error":{"code":-32600,"message":"Invalid Request","data":"id is not a string"}}`},
+		{IDPolicy{RequireString: true}, `{"jsonrpc":"2.0","method":"ping"}`, ``},
+		{IDPolicy{RequireUUID: true}, `{"jsonrpc":"2.0","id":"abc","method":"ping"}`, `{"jsonrpc":"2.0","id":"abc","error":{"code":-32600,"message":"Invalid Request","data":"id is not a UUID"}}`},
+		{IDPolicy{RequireUUID: true}, `{"jsonrpc":"2.0","id":"f47ac10b-58cc-4372-a567-0e02b2c3d479","method":"ping"}`, `{"jsonrpc":"2.0","id":"f47ac10b-58cc-4372-a567-0e02b2c3d479","result":"pong"}`},
+		{IDPolicy{}, `{"jsonrpc":"2.0","id":null,"method":"ping"}`, ``},
+		{IDPolicy{ForbidNull: true}, `{"jsonrpc":"2.0","id":null,"method":"ping"}`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"null id"}}`},
+		{IDPolicy{ForbidNull: true}, `[{"jsonrpc":"2.0","id":null,"method":"ping"},{"jsonrpc":"2.0","method":"ping"}]`, `[{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"null id"}}]`},
+		{IDPolicy{RequireString: true}, `{"jsonrpc":"2.0","id":{"a":1},"method":"ping"}`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"id is not a string or a number"}}`},
+		{IDPolicy{RequireString: true}, `{"jsonrpc":"2.0","id":true,"method":"ping"}`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"id is not a string or a number"}}`},
+		{IDPolicy{}, `{"jsonrpc":"2.0","id":[1],"method":"ping"}`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"id is not a string or a number"}}`},
+		{IDPolicy{Check: func(id interface{}) error {
+			if s, _ := id.(string); len(s) > 4 {
+				return errors.New("id too long")
+			}
+			return nil
+		}}, `{"jsonrpc":"2.0","id":"abcde","method":"ping"}`, `{"jsonrpc":"2.0","id":"abcde","error":{"code":-32600,"message":"Invalid Request","data":"id too long"}}`},
+	}
+	for _, test := range tests {
+		server := NewServer()
+		server.HandleFunc("ping", pong)
+		server.IDPolicy = test.policy
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// IDError describes a response whose id doesn't answer the request it was
+// received for, see ClientHooks.OnIDError. It wraps ErrInvalidResponse.
+type IDError struct {
+	// Endpoint is the URL the request was sent to.
+	Endpoint string
+	// ID is the id of the response.
+	ID interface{}
+	// Expected is the id of the request, nil for batches.
+	Expected interface{}
+	// Duplicate is set for a second response to a request of a batch.
+	Duplicate bool
+	// InFlight is set if ID is that of another request in flight: the
+	// response was misrouted.
+	InFlight bool
+}
+
+func (e *IDError) Error() string {
+	switch {
+	case e.Duplicate:
+		return fmt.Sprintf("%v: duplicate response to %v", ErrInvalidResponse, e.ID)
+	case e.Expected != nil:
+		return fmt.Sprintf("%v: id %v answers another request than %v", ErrInvalidResponse, e.ID, e.Expected)
+	}
+	return fmt.Sprintf("%v: unexpected response id %v", ErrInvalidResponse, e.ID)
+}
+
+func (e *IDError) Unwrap() error {
+	return ErrInvalidResponse
+}
+
+// nextID returns an id no request in flight uses, and tracks it until
+// releaseID is called.
+func (c *Client) nextID() interface{} {
+	for {
+		id := atomic.AddInt64(&c.next, 1)
+		if _, used := c.inflight.LoadOrStore(id, struct{}{}); !used {
+			return id
+		}
+	}
+}
+
+// releaseID forgets the id of a request no longer in flight.
+func (c *Client) releaseID(id interface{}) {
+	c.inflight.Delete(id)
+}
+
+// reportID fires the OnIDError hook for the response id received from
+// endpoint, answering the request expected or a batch if nil.
+func (c *Client) reportID(ctx context.Context, endpoint string, id, expected interface{}, duplicate bool) *IDError {
+	err := &IDError{Endpoint: endpoint, ID: id, Expected: expected, Duplicate: duplicate}
+	if n, ok := id.(float64); ok && n == float64(int64(n)) {
+		_, err.InFlight = c.inflight.Load(int64(n))
+	}
+	if c.hooks.OnIDError != nil {
+		c.hooks.OnIDError(ctx, err)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIDErrors(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	var reported []*IDError
+	hooks := ClientHooks{OnIDError: func(ctx context.Context, err *IDError) {
+		reported = append(reported, err)
+	}}
+	ctx := context.Background()
+	client := NewClient(ts.URL, WithHooks(hooks))
+
+	// ids start at 1
+	body = `[{"jsonrpc":"2.0","id":1,"result":"a"},{"jsonrpc":"2.0","id":1,"result":"dup"},{"jsonrpc":"2.0","id":99,"result":"unknown"},{"jsonrpc":"2.0","id":2,"result":"b"}]`
+	resps, err := client.Batch(ctx, []BatchRequest{{Method: "a"}, {Method: "b"}})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	var a, b string
+	resps[0].Decode(&a)
+	resps[1].Decode(&b)
+	if a != "a" || b != "b" {
+		t.Errorf("got results %q and %q, want a and b", a, b)
+	}
+	if len(reported) != 2 || !reported[0].Duplicate || reported[1].ID != float64(99) || reported[1].Endpoint != ts.URL {
+		t.Errorf("reported %+v, want a duplicate and an unknown id", reported)
+	}
+
+	reported = nil
+	body = `{"jsonrpc":"2.0","id":7,"result":"c"}`
+	if _, err := client.Call(ctx, "c", nil); err != nil {
+		t.Errorf("call: error not expected: %v", err)
+	}
+	if len(reported) != 1 || reported[0].Expected != int64(3) {
+		t.Errorf("reported %+v, want id 7 instead of 3", reported)
+	}
+
+	reported = nil
+	strict := NewClient(ts.URL, WithHooks(hooks), WithStrictResponses())
+	if _, err := strict.Call(ctx, "c", nil); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("got error %v, want ErrInvalidResponse", err)
+	}
+	if len(reported) != 1 {
+		t.Errorf("reported %+v, want id 7 instead of 1", reported)
+	}
+}
+
+func TestNextIDSkipsInFlight(t *testing.T) {
+	client := NewClient("")
+	client.inflight.Store(int64(2), struct{}{})
+	if id := client.nextID(); id != int64(1) {
+		t.Errorf("got id %v, want 1", id)
+	}
+	if id := client.nextID(); id != int64(3) {
+		t.Errorf("got id %v, want 3 as 2 is in flight", id)
+	}
+	if err := client.reportID(context.Background(), "", float64(3), int64(1), false); !err.InFlight {
+		t.Errorf("response to 3 not reported as misrouted")
+	}
+}
@@ -0,0 +1,50 @@
+package jsonrpc
+
+import "context"
+
+// ClientRequest is a call or a notification made by a client, as seen by
+// its interceptors.
+type ClientRequest struct {
+	Method string
+	Params interface{}
+	// Notification is set for notifications, which get a nil Response.
+	Notification bool
+}
+
+// Invoker executes a request made by a client.
+type Invoker func(ctx context.Context, req *ClientRequest) (*Response, error)
+
+// Interceptor wraps the requests made by a client, the counterpart of server
+// middleware. It may change req, call next any number of times, retrying
+// after refreshing a token for instance, or answer without calling it, from
+// a cache for instance.
+type Interceptor func(ctx context.Context, req *ClientRequest, next Invoker) (*Response, error)
+
+// Use adds interceptors to the client. The first one added sees the requests
+// first. It should be called before the client is used. Batches are not
+// intercepted.
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interceptors = append(c.interceptors, interceptors...)
+	// wrap from the last one so that the first one runs first
+	chain := Invoker(c.transmit)
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		next, ic := chain, c.interceptors[i]
+		chain = func(ctx context.Context, req *ClientRequest) (*Response, error) {
+			return ic(ctx, req, next)
+		}
+	}
+	c.invoker = chain
+}
+
+// invoke executes req through the interceptors of the client.
+func (c *Client) invoke(ctx context.Context, req *ClientRequest) (*Response, error) {
+	c.mu.Lock()
+	invoker := c.invoker
+	c.mu.Unlock()
+	if invoker == nil {
+		return c.transmit(ctx, req)
+	}
+	return invoker(ctx, req)
+}
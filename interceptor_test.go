@@ -0,0 +1,54 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientInterceptors(t *testing.T) {
+	s := NewServer()
+	s.HandleFunc("sum", sum)
+	s.HandleFunc("counter", (&state{}).increaseCounter)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var trace []string
+	client := NewClient(ts.URL)
+	client.Use(func(ctx context.Context, req *ClientRequest, next Invoker) (*Response, error) {
+		trace = append(trace, "log "+req.Method)
+		return next(ctx, req)
+	})
+	cache := map[string]*Response{}
+	client.Use(func(ctx context.Context, req *ClientRequest, next Invoker) (*Response, error) {
+		key := req.Method
+		if resp, ok := cache[key]; ok {
+			trace = append(trace, "cached "+key)
+			return resp, nil
+		}
+		if req.Method == "add" {
+			req.Method = "sum"
+		}
+		resp, err := next(ctx, req)
+		if err == nil && !req.Notification {
+			cache[key] = resp
+		}
+		return resp, err
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		resp, err := client.Call(ctx, "add", Args{1, 2})
+		var reply Reply
+		if err != nil || resp.Decode(&reply) != nil || reply.C != 3 {
+			t.Errorf("call %v: %v, %v", i, reply, err)
+		}
+	}
+	if err := client.Notify(ctx, "counter", 1); err != nil {
+		t.Errorf("notify: %v", err)
+	}
+	if want := []string{"log add", "log add", "cached add", "log counter"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("invalid trace:\ngot: %q\nwant: %q", trace, want)
+	}
+}
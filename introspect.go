@@ -0,0 +1,53 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// MethodInfo describes a registered method.
+type MethodInfo struct {
+	Name string `json:"name"`
+	// Params and Result are the Go types of the params and result, Params
+	// is empty for methods without params.
+	Params string `json:"params,omitempty"`
+	Result string `json:"result"`
+	// Stream is set for streaming methods.
+	Stream bool `json:"stream,omitempty"`
+	// Example is the zero value of the params, as a template to fill.
+	Example json.RawMessage `json:"example,omitempty"`
+	// Deprecation is set for deprecated methods, see Deprecated.
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+}
+
+// Methods returns the methods registered in s sorted by name.
+func (s *Server) Methods() []MethodInfo {
+	var methods []MethodInfo
+	s.handler.Range(func(k, v interface{}) bool {
+		h := v.(handlerType)
+		m := MethodInfo{Name: k.(string), Result: h.rtype.String(), Stream: h.stream, Deprecation: h.deprecation}
+		if h.ptype != nil {
+			m.Params = h.ptype.String()
+			m.Example = exampleParams(h.ptype)
+		}
+		methods = append(methods, m)
+		return true
+	})
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	return methods
+}
+
+// exampleParams returns the encoded zero value of t, pointers being
+// allocated so that their fields show.
+func exampleParams(t reflect.Type) json.RawMessage {
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.Ptr {
+		v.Set(reflect.New(t.Elem()))
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,43 @@
+package jsonrpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.HandleFunc("random", random)
+	server.HandleFunc("ticks", func(ctx context.Context) (<-chan int, error) { return nil, nil })
+
+	want := []MethodInfo{
+		{Name: "random", Result: "jsonrpc.Reply"},
+		{Name: "sum", Params: "jsonrpc.Args", Result: "jsonrpc.Reply", Example: []byte(`{"A":0,"B":0}`)},
+		{Name: "ticks", Result: "<-chan int", Stream: true},
+	}
+	if got := server.Methods(); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid methods:\ngot: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestPlayground(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	rw := httptest.NewRecorder()
+	server.Playground("/rpc").ServeHTTP(rw, httptest.NewRequest("GET", "/playground", nil))
+	body, _ := ioutil.ReadAll(rw.Body)
+	if ct := rw.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("invalid content type: %v", ct)
+	}
+	for _, want := range []string{`<option value="sum">sum</option>`, `const endpoint = "/rpc"`, `"example":{"A":0,"B":0}`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("playground doesn't contain %v:\n%s", want, body)
+		}
+	}
+}
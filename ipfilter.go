@@ -0,0 +1,156 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// IPFilterConfig configures an IPFilter. Addresses are CIDR ranges, such as
+// "10.0.0.0/8", or single IPs.
+type IPFilterConfig struct {
+	// Allow, if not empty, lists the only clients allowed.
+	Allow []string
+	// Deny lists the clients rejected, even if allowed.
+	Deny []string
+	// TrustedProxies lists the reverse proxies whose X-Forwarded-For header
+	// is trusted to find the address of the client.
+	TrustedProxies []string
+	// Status answers rejected requests, defaults to 403.
+	Status int
+	// Message is the body of the answers to rejected requests, defaults to
+	// the text of Status.
+	Message string
+}
+
+// IPFilter rejects requests by client address, see Server.IPFilter.
+type IPFilter struct {
+	allow, deny, trusted []*net.IPNet
+	status               int
+	message              string
+}
+
+// NewIPFilter returns the IPFilter configured by cfg.
+func NewIPFilter(cfg IPFilterConfig) (*IPFilter, error) {
+	f := &IPFilter{status: cfg.Status, message: cfg.Message}
+	if f.status == 0 {
+		f.status = http.StatusForbidden
+	}
+	if f.message == "" {
+		f.message = http.StatusText(f.status)
+	}
+	var err error
+	if f.allow, err = parseCIDRs(cfg.Allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = parseCIDRs(cfg.Deny); err != nil {
+		return nil, err
+	}
+	if f.trusted, err = parseCIDRs(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parseCIDRs(addrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(addrs))
+	for _, a := range addrs {
+		if !strings.Contains(a, "/") {
+			ip := net.ParseIP(a)
+			if ip == nil {
+				return nil, fmt.Errorf("jsonrpc: invalid address %q", a)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(a)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: invalid address range %q", a)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether requests from ip are allowed.
+func (f *IPFilter) Allowed(ip net.IP) bool {
+	if ip == nil || containsIP(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || containsIP(f.allow, ip)
+}
+
+// ClientIP returns the address of the client making r: the remote address
+// of the connection or, if it is a trusted proxy, the last address in
+// X-Forwarded-For which isn't one.
+func (f *IPFilter) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(f.trusted, ip) {
+		return ip
+	}
+	hops := r.Header[textproto.CanonicalMIMEHeaderKey("X-Forwarded-For")]
+	for i := len(hops) - 1; i >= 0; i-- {
+		addrs := strings.Split(hops[i], ",")
+		for j := len(addrs) - 1; j >= 0; j-- {
+			hop := net.ParseIP(strings.TrimSpace(addrs[j]))
+			if hop == nil {
+				// forged or garbled, the client can't be told
+				return nil
+			}
+			if ip = hop; !containsIP(f.trusted, ip) {
+				return ip
+			}
+		}
+	}
+	return ip
+}
+
+// clientIP returns the address of the client making r, as told by the
+// IPFilter of s if it has one.
+func (s *Server) clientIP(r *http.Request) string {
+	f := s.IPFilter
+	if f == nil {
+		f = &IPFilter{}
+	}
+	return f.ClientIP(r).String()
+}
+
+// check reports whether r is allowed, answering it otherwise.
+func (f *IPFilter) check(rw http.ResponseWriter, r *http.Request) bool {
+	if f.Allowed(f.ClientIP(r)) {
+		return true
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.WriteHeader(f.status)
+	rw.Write([]byte(f.message))
+	return false
+}
+
+// Wrap returns a handler serving the requests allowed by f with h, for
+// handlers other than Server. It is a Middleware.
+func (f *IPFilter) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if f.check(rw, r) {
+			h.ServeHTTP(rw, r)
+		}
+	})
+}
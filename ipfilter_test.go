@@ -0,0 +1,56 @@
+package jsonrpc
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	if _, err := NewIPFilter(IPFilterConfig{Allow: []string{"10.0.0.0/33"}}); err == nil {
+		t.Errorf("invalid range accepted")
+	}
+	f, err := NewIPFilter(IPFilterConfig{
+		Allow:          []string{"10.0.0.0/8", "2001:db8::/32"},
+		Deny:           []string{"10.0.0.13"},
+		TrustedProxies: []string{"192.168.0.0/16"},
+		Status:         404,
+		Message:        "nope",
+	})
+	if err != nil {
+		t.Fatalf("NewIPFilter: %v", err)
+	}
+	server := NewServer()
+	server.IPFilter = f
+	server.HandleFunc("random", random)
+
+	tests := []struct {
+		name, remote string
+		forwarded    []string
+		status       int
+	}{
+		{"allowed", "10.1.2.3:1234", nil, 200},
+		{"ipv6", "[2001:db8::1]:1234", nil, 200},
+		{"denied", "10.0.0.13:1234", nil, 404},
+		{"not allowed", "8.8.8.8:1234", nil, 404},
+		{"via proxy", "192.168.1.1:1234", []string{"8.8.8.8, 10.1.2.3", "192.168.1.2"}, 200},
+		{"forged via proxy", "192.168.1.1:1234", []string{"10.1.2.3, 8.8.8.8"}, 404},
+		{"garbled via proxy", "192.168.1.1:1234", []string{"10.1.2.3, junk"}, 404},
+		{"untrusted proxy", "8.8.8.8:1234", []string{"10.1.2.3"}, 404},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"random"}`))
+		r.RemoteAddr = test.remote
+		for _, v := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, r)
+		if rw.Code != test.status {
+			t.Errorf("%v: invalid status:\ngot: %v\nwant: %v", test.name, rw.Code, test.status)
+		}
+		if rw.Code == 404 && rw.Body.String() != "nope" {
+			t.Errorf("%v: invalid body: %q", test.name, rw.Body)
+		}
+	}
+}
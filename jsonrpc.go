@@ -1,17 +1,40 @@
 package jsonrpc
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeServerError is the code of the errors returned by handlers which
+	// aren't an *Error.
+	CodeServerError = -32000
+
+	// Implementation-defined server errors use the codes from
+	// CodeServerErrorMin to CodeServerErrorMax.
+	CodeServerErrorMin = -32099
+	CodeServerErrorMax = -32000
+
+	// The codes from CodeReservedMin to CodeReservedMax are reserved by the
+	// specification, application errors must use codes outside of it.
+	CodeReservedMin = -32768
+	CodeReservedMax = -32000
+)
+
 var (
-	ErrorParseError   = &Error{-32700, "Parse error", nil}
-	ErrInvalidRequest = &Error{-32600, "Invalid Request", nil}
-	ErrMethodNotFound = &Error{-32601, "Method not found", nil}
-	ErrInvalidParams  = &Error{-32602, "Invalid params", nil}
-	ErrInternalError  = &Error{-32603, "Internal error", nil}
-	//ErrServerError    = Error{-32000, "Parse error", nil}
+	ErrorParseError   = &Error{CodeParseError, "Parse error", nil}
+	ErrInvalidRequest = &Error{CodeInvalidRequest, "Invalid Request", nil}
+	ErrMethodNotFound = &Error{CodeMethodNotFound, "Method not found", nil}
+	ErrInvalidParams  = &Error{CodeInvalidParams, "Invalid params", nil}
+	ErrInternalError  = &Error{CodeInternalError, "Internal error", nil}
 )
 
 // Error represents a JSON-RPC error, it implements the error interface.
@@ -25,3 +48,98 @@ type Error struct {
 func (e *Error) Error() string {
 	return fmt.Sprint("jsonrpc: ", strings.ToLower(e.Message))
 }
+
+// Is reports whether target is an *Error with the same code, so that
+// errors.Is(err, ErrInvalidParams) holds for any Invalid params error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithCause returns an error answered with e whose chain continues with
+// cause, which is not sent to the client, so that errors.Is and errors.As
+// find both, as well as the data of an ErrorDataProvider cause.
+func WithCause(e *Error, cause error) error {
+	return &causedError{e, cause}
+}
+
+type causedError struct {
+	err   *Error
+	cause error
+}
+
+func (e *causedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *causedError) Is(target error) bool {
+	return e.err.Is(target)
+}
+
+func (e *causedError) Unwrap() error {
+	return e.cause
+}
+
+func (e *causedError) As(target interface{}) bool {
+	if t, ok := target.(**Error); ok {
+		*t = e.err
+		return true
+	}
+	return false
+}
+
+// AsError returns the first *Error in the chain of err, see errors.As.
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// ErrInvalidParamsf returns an Invalid params error whose data describes the
+// problem, formatted according to format.
+func ErrInvalidParamsf(format string, a ...interface{}) *Error {
+	return &Error{Code: CodeInvalidParams, Message: ErrInvalidParams.Message, Data: fmt.Sprintf(format, a...)}
+}
+
+// NewServerError returns an implementation-defined server error. It panics if
+// code isn't in the server error range.
+func NewServerError(code int, msg string, data interface{}) *Error {
+	if code < CodeServerErrorMin || code > CodeServerErrorMax {
+		panic(fmt.Sprintf("jsonrpc: server error code %v out of range [%v, %v]", code, CodeServerErrorMin, CodeServerErrorMax))
+	}
+	return &Error{Code: code, Message: msg, Data: data}
+}
+
+// NewError returns an application error. It panics if code is in the range
+// reserved by the specification.
+func NewError(code int, msg string, data interface{}) *Error {
+	if code >= CodeReservedMin && code <= CodeReservedMax {
+		panic(fmt.Sprintf("jsonrpc: error code %v is reserved", code))
+	}
+	return &Error{Code: code, Message: msg, Data: data}
+}
+
+// ErrorDataProvider is implemented by errors contributing the data member of
+// the error sent to the client, such as field errors or retry hints, without
+// being an *Error.
+type ErrorDataProvider interface {
+	ErrorData() interface{}
+}
+
+// withErrorData returns e with the data provided by the chain of cause if e
+// has none.
+func withErrorData(e *Error, cause error) *Error {
+	if e.Data != nil {
+		return e
+	}
+	var p ErrorDataProvider
+	if !errors.As(cause, &p) {
+		return e
+	}
+	// e may be shared, such as ErrInvalidParams
+	d := *e
+	d.Data = p.ErrorData()
+	return &d
+}
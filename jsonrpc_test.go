@@ -0,0 +1,117 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestErrorConstructors(t *testing.T) {
+	got := ErrInvalidParamsf("missing %v", "name")
+	want := &Error{Code: CodeInvalidParams, Message: "Invalid params", Data: "missing name"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ErrInvalidParamsf:\ngot: %v\nwant: %v", got, want)
+	}
+
+	if e := NewServerError(-32001, "Busy", nil); e.Code != -32001 || e.Message != "Busy" {
+		t.Errorf("NewServerError: invalid error %+v", e)
+	}
+	if e := NewError(42, "Not enough credit", 3); e.Code != 42 || e.Data != 3 {
+		t.Errorf("NewError: invalid error %+v", e)
+	}
+
+	mustPanic := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%v: expected a panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("NewServerError(-32100)", func() { NewServerError(-32100, "", nil) })
+	mustPanic("NewServerError(1)", func() { NewServerError(1, "", nil) })
+	mustPanic("NewError(-32602)", func() { NewError(CodeInvalidParams, "", nil) })
+	mustPanic("NewError(-32768)", func() { NewError(CodeReservedMin, "", nil) })
+}
+
+func TestErrorWrapping(t *testing.T) {
+	e := &Error{Code: 42, Message: "Not enough credit"}
+	wrapped := fmt.Errorf("charging: %w", WithCause(e, io.EOF))
+	if !errors.Is(wrapped, io.EOF) {
+		t.Errorf("errors.Is: cause not found")
+	}
+	if !errors.Is(ErrInvalidParamsf("bad"), ErrInvalidParams) {
+		t.Errorf("errors.Is: errors with the same code should match")
+	}
+	if errors.Is(wrapped, ErrInvalidParams) {
+		t.Errorf("errors.Is: errors with different codes shouldn't match")
+	}
+	if got, ok := AsError(wrapped); !ok || got != e {
+		t.Errorf("AsError:\ngot: %v, %v\nwant: %v", got, ok, e)
+	}
+	if _, ok := AsError(io.EOF); ok {
+		t.Errorf("AsError: unexpected *Error in io.EOF")
+	}
+
+	server := NewServer()
+	server.HandleFunc("charge", func(ctx context.Context) (int, error) {
+		return 0, wrapped
+	})
+	got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"charge"}`))
+	want := `{"jsonrpc":"2.0","id":1,"error":{"code":42,"message":"Not enough credit"}}`
+	if string(got) != want {
+		t.Errorf("wrapped handler error:\ngot: %s\nwant: %v", got, want)
+	}
+}
+
+type fieldError struct {
+	Field string `json:"field"`
+}
+
+func (e *fieldError) Error() string          { return "invalid " + e.Field }
+func (e *fieldError) ErrorData() interface{} { return e }
+
+type reasonError string
+
+func (e reasonError) Error() string          { return string(e) }
+func (e reasonError) ErrorData() interface{} { return string(e) }
+
+var reference = regexp.MustCompile(`"reference":"[0-9a-f]{16}"`)
+
+func TestErrorDataProvider(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("fail", func(ctx context.Context, mode string) (int, error) {
+		err := &fieldError{"name"}
+		switch mode {
+		case "rpc":
+			return 0, WithCause(&Error{Code: CodeInvalidParams, Message: "Invalid params"}, err)
+		case "data":
+			return 0, WithCause(&Error{Code: 1, Message: "Failed", Data: "kept"}, err)
+		case "reason":
+			return 0, reasonError("quota exceeded")
+		}
+		return 0, fmt.Errorf("validating: %w", err)
+	})
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		// hidden server errors keep their reference
+		{"plain", `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"Server error","data":{"field":"name","reference":"*"}}}`},
+		{"reason", `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"Server error","data":{"reference":"*","data":"quota exceeded"}}}`},
+		{"rpc", `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"field":"name"}}}`},
+		{"data", `{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"Failed","data":"kept"}}`},
+	}
+	for _, tt := range tests {
+		got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"fail","params":"`+tt.mode+`"}`))
+		got = reference.ReplaceAll(got, []byte(`"reference":"*"`))
+		if string(got) != tt.want {
+			t.Errorf("%v:\ngot: %s\nwant: %v", tt.mode, got, tt.want)
+		}
+	}
+}
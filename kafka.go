@@ -0,0 +1,100 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KafkaMessage is a Kafka record carrying a JSON-RPC request or response.
+// The record key is used as correlation id, responses are produced with the
+// key of their request.
+type KafkaMessage struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// KafkaReader consumes requests, it mirrors the consumer group API of
+// kafka-go's Reader.
+type KafkaReader interface {
+	FetchMessage(ctx context.Context) (KafkaMessage, error)
+	CommitMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaWriter produces responses to the response topic.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaConfig configures ServeKafka.
+type KafkaConfig struct {
+	// BatchSize is the number of requests whose responses are written and
+	// offsets committed together. Defaults to 1.
+	BatchSize int
+	// BatchTimeout is how long a partial batch waits for more requests
+	// before being flushed. Defaults to 100ms.
+	BatchTimeout time.Duration
+}
+
+// ServeKafka serves the requests read from r and writes their responses to w.
+// Requests are served one at a time in the order they are fetched, which
+// preserves per-partition ordering, and offsets are only committed once the
+// responses of a batch have been written. ServeKafka returns when ctx is done
+// or r or w fail.
+func (s *Server) ServeKafka(ctx context.Context, r KafkaReader, w KafkaWriter, cfg KafkaConfig) error {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	cfg.BatchTimeout = durationOr(cfg.BatchTimeout, 100*time.Millisecond)
+
+	var reqs, resps []KafkaMessage
+	flush := func() error {
+		if len(resps) > 0 {
+			if err := w.WriteMessages(ctx, resps...); err != nil {
+				return fmt.Errorf("jsonrpc: kafka: writing responses: %w", err)
+			}
+		}
+		if len(reqs) > 0 {
+			if err := r.CommitMessages(ctx, reqs...); err != nil {
+				return fmt.Errorf("jsonrpc: kafka: committing requests: %w", err)
+			}
+		}
+		reqs, resps = reqs[:0], resps[:0]
+		return nil
+	}
+
+	for {
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		if len(reqs) > 0 {
+			fetchCtx, cancel = context.WithTimeout(ctx, cfg.BatchTimeout)
+		}
+		msg, err := r.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if fetchCtx.Err() != nil {
+				// batch timeout
+				if err := flush(); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("jsonrpc: kafka: fetching request: %w", err)
+		}
+
+		reqs = append(reqs, msg)
+		if resp := s.ServeMessage(ctx, msg.Value); resp != nil {
+			resps = append(resps, KafkaMessage{Key: msg.Key, Value: resp})
+		}
+		if len(reqs) >= cfg.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
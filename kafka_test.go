@@ -0,0 +1,74 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeKafka struct {
+	pending   []KafkaMessage
+	written   []KafkaMessage
+	committed []KafkaMessage
+	commits   int
+	cancel    context.CancelFunc
+}
+
+func (f *fakeKafka) FetchMessage(ctx context.Context) (KafkaMessage, error) {
+	if len(f.pending) == 0 {
+		if len(f.committed) == 3 {
+			f.cancel()
+		}
+		<-ctx.Done()
+		return KafkaMessage{}, ctx.Err()
+	}
+	msg := f.pending[0]
+	f.pending = f.pending[1:]
+	return msg, nil
+}
+
+func (f *fakeKafka) CommitMessages(ctx context.Context, msgs ...KafkaMessage) error {
+	f.committed = append(f.committed, msgs...)
+	f.commits++
+	return nil
+}
+
+func (f *fakeKafka) WriteMessages(ctx context.Context, msgs ...KafkaMessage) error {
+	f.written = append(f.written, msgs...)
+	return nil
+}
+
+func TestServeKafka(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	k := &fakeKafka{cancel: cancel, pending: []KafkaMessage{
+		{Offset: 1, Key: []byte("a"), Value: []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`)},
+		{Offset: 2, Key: []byte("b"), Value: []byte(`{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}`)},
+		{Offset: 3, Key: []byte("c"), Value: []byte(`{"jsonrpc":"2.0","id":2,"method":"sum","params":{"A":2,"B":2}}`)},
+	}}
+
+	err := server.ServeKafka(ctx, k, k, KafkaConfig{BatchSize: 2, BatchTimeout: time.Millisecond})
+	if err != context.Canceled {
+		t.Errorf("invalid serve error:\ngot: %v\nwant: %v", err, context.Canceled)
+	}
+
+	want := []KafkaMessage{
+		{Key: []byte("a"), Value: []byte(`{"jsonrpc":"2.0","id":1,"result":{"C":3}}`)},
+		{Key: []byte("c"), Value: []byte(`{"jsonrpc":"2.0","id":2,"result":{"C":4}}`)},
+	}
+	if len(k.written) != len(want) {
+		t.Fatalf("invalid number of responses:\ngot: %v\nwant: %v", len(k.written), len(want))
+	}
+	for i := range want {
+		if string(k.written[i].Key) != string(want[i].Key) || string(k.written[i].Value) != string(want[i].Value) {
+			t.Errorf("invalid response:\ngot: %s %s\nwant: %s %s", k.written[i].Key, k.written[i].Value, want[i].Key, want[i].Value)
+		}
+	}
+	// one full batch of two requests and a partial batch flushed on timeout
+	if len(k.committed) != 3 || k.commits != 2 {
+		t.Errorf("invalid commits:\ngot: %v messages in %v commits\nwant: 3 messages in 2 commits", len(k.committed), k.commits)
+	}
+}
@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DecodeLimits bounds the params of a request before they are decoded, to
+// defend against JSON bombs. A zero field means no limit.
+type DecodeLimits struct {
+	// MaxBytes is the maximum size in bytes of a request or batch, before
+	// and after decompression. It is enforced while the body is read, HTTP
+	// requests exceeding it are answered with 413 Request Entity Too Large.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// MaxDepth is the maximum nesting depth of arrays and objects.
+	MaxDepth int `json:"max_depth,omitempty"`
+	// MaxArrayLength is the maximum number of elements of an array.
+	MaxArrayLength int `json:"max_array_length,omitempty"`
+	// MaxStringLength is the maximum size in bytes of a string, as encoded.
+	MaxStringLength int `json:"max_string_length,omitempty"`
+}
+
+// limitData is the data of the error returned for params exceeding a limit.
+type limitData struct {
+	Limit string `json:"limit"`
+	Max   int    `json:"max"`
+}
+
+func limitError(limit string, max int) *Error {
+	return &Error{Code: ErrorParseError.Code, Message: "Parse limit exceeded", Data: limitData{limit, max}}
+}
+
+// check returns an error if params exceed one of the limits. It scans params
+// without decoding them.
+func (l *DecodeLimits) check(params json.RawMessage) *Error {
+	if l.MaxDepth <= 0 && l.MaxArrayLength <= 0 && l.MaxStringLength <= 0 {
+		return nil
+	}
+
+	// number of elements of each open container, -1 for objects
+	var counts []int
+	inString, escaped := false, false
+	strLen := 0
+	for _, c := range params {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				continue
+			}
+			strLen++
+			if l.MaxStringLength > 0 && strLen > l.MaxStringLength {
+				return limitError("string_length", l.MaxStringLength)
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString, strLen = true, 0
+		case '[', '{':
+			if l.MaxDepth > 0 && len(counts) >= l.MaxDepth {
+				return limitError("depth", l.MaxDepth)
+			}
+			if c == '[' {
+				counts = append(counts, 1)
+			} else {
+				counts = append(counts, -1)
+			}
+		case ']', '}':
+			if len(counts) > 0 {
+				counts = counts[:len(counts)-1]
+			}
+		case ',':
+			top := len(counts) - 1
+			if top < 0 || counts[top] < 0 {
+				continue
+			}
+			counts[top]++
+			if l.MaxArrayLength > 0 && counts[top] > l.MaxArrayLength {
+				return limitError("array_length", l.MaxArrayLength)
+			}
+		}
+	}
+	return nil
+}
+
+// errBodyTooLarge is returned by the reads of a limitedBody exceeding its
+// limit.
+var errBodyTooLarge = errors.New("jsonrpc: request body too large")
+
+// limitedBody is a request body cut off once it exceeds a byte budget, see
+// DecodeLimits.MaxBytes.
+type limitedBody struct {
+	io.ReadCloser
+	left     int64
+	exceeded bool
+}
+
+func newLimitedBody(body io.ReadCloser, max int64) *limitedBody {
+	return &limitedBody{ReadCloser: body, left: max}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > b.left+1 {
+		// one more byte tells whether the body exceeds the limit
+		p = p[:b.left+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err == errBodyTooLarge {
+		// the body read from, the compressed one, exceeded the limit
+		b.exceeded = true
+	}
+	if int64(n) <= b.left {
+		b.left -= int64(n)
+		return n, err
+	}
+	n, b.left, b.exceeded = int(b.left), 0, true
+	return n, errBodyTooLarge
+}
+
+// requestTooLarge answers a request whose body exceeds DecodeLimits.MaxBytes.
+func requestTooLarge(rw http.ResponseWriter) {
+	rw.Header().Set("Connection", "close")
+	rw.WriteHeader(http.StatusRequestEntityTooLarge)
+	rw.Write([]byte("Request Entity Too Large"))
+}
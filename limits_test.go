@@ -0,0 +1,94 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	server := NewServer()
+	server.Limits = DecodeLimits{MaxDepth: 3, MaxArrayLength: 3, MaxStringLength: 5}
+	server.HandleFunc("echo", func(ctx context.Context, params interface{}) (interface{}, error) {
+		return params, nil
+	})
+
+	tests := []struct {
+		name   string
+		params string
+		resp   string
+	}{
+		{
+			name:   "within_limits",
+			params: `{"a":[[1,2,3],"abcde","a\"c"]}`,
+			resp:   `{"jsonrpc":"2.0","id":1,"result":{"a":[[1,2,3],"abcde","a\"c"]}}`,
+		},
+		{
+			name:   "depth",
+			params: `[[[[1]]]]`,
+			resp:   `{"jsonrpc":"2.0","id":1,"error":{"code":-32700,"message":"Parse limit exceeded","data":{"limit":"depth","max":3}}}`,
+		},
+		{
+			name:   "array_length",
+			params: `{"a":[1,2,3,4],"b":1,"c":2,"d":3}`,
+			resp:   `{"jsonrpc":"2.0","id":1,"error":{"code":-32700,"message":"Parse limit exceeded","data":{"limit":"array_length","max":3}}}`,
+		},
+		{
+			name:   "string_length",
+			params: `["a,b,c,d"]`,
+			resp:   `{"jsonrpc":"2.0","id":1,"error":{"code":-32700,"message":"Parse limit exceeded","data":{"limit":"string_length","max":5}}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":`+tc.params+`}`))
+			if string(got) != tc.resp {
+				t.Errorf("invalid jsonrpc response: \ngot: %s\nwant: %v\n", got, tc.resp)
+			}
+		})
+	}
+}
+
+func TestDecodeLimitsMaxBytes(t *testing.T) {
+	server := NewServer()
+	server.Limits = DecodeLimits{MaxBytes: 64}
+	var calls int32
+	server.HandleFunc("echo", func(ctx context.Context, s string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return s, nil
+	})
+	small := `{"jsonrpc":"2.0","id":1,"method":"echo","params":"a"}`
+	large := `[{"jsonrpc":"2.0","id":1,"method":"echo","params":"a"},{"jsonrpc":"2.0","id":2,"method":"echo","params":"b"}]`
+
+	got := server.ServeMessage(context.Background(), []byte(large))
+	if want := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse limit exceeded","data":{"limit":"bytes","max":64}}}`; string(got) != want {
+		t.Errorf("invalid response:\ngot: %s\nwant: %v", got, want)
+	}
+
+	for _, test := range []struct {
+		name          string
+		body          string
+		contentLength int64
+		status        int
+	}{
+		{"within_limit", small, int64(len(small)), 200},
+		{"content_length", large, int64(len(large)), 413},
+		// the length isn't known in advance
+		{"chunked", large, -1, 413},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+			req.ContentLength = test.contentLength
+			rw := httptest.NewRecorder()
+			server.ServeHTTP(rw, req)
+			if rw.Code != test.status {
+				t.Errorf("status %v, want %v: %v", rw.Code, test.status, rw.Body)
+			}
+		})
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("%v calls, want 1", n)
+	}
+}
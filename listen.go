@@ -0,0 +1,165 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeouts applied by ListenAndServe and ListenAndServeTLS when the
+// corresponding Server field is zero.
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+)
+
+var errServerListening = errors.New("jsonrpc: server already listening")
+
+// ListenAndServe listens on the TCP network address addr and serves JSON-RPC
+// requests until Shutdown is called. If s.H2C is set, HTTP/2 cleartext
+// connections are accepted as well.
+func (s *Server) ListenAndServe(addr string) error {
+	srv, err := s.newHTTPServer(addr)
+	if err != nil {
+		return err
+	}
+	if s.H2C {
+		if err := enableH2C(srv); err != nil {
+			s.releaseHTTPServer(srv)
+			return err
+		}
+	}
+	l, err := s.listen(addr)
+	if err != nil {
+		s.releaseHTTPServer(srv)
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// ListenAndServeTLS acts like ListenAndServe but serves HTTPS using the given
+// certificate and key files. HTTP/2 is negotiated automatically.
+func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	srv, err := s.newHTTPServer(addr)
+	if err != nil {
+		return err
+	}
+	l, err := s.listen(addr)
+	if err != nil {
+		s.releaseHTTPServer(srv)
+		return err
+	}
+	return srv.ServeTLS(l, certFile, keyFile)
+}
+
+// Shutdown gracefully stops a server started with ListenAndServe or
+// ListenAndServeTLS, waiting for in-flight calls and queued notifications
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.httpServer
+	s.httpServer, s.listener = nil, nil
+	s.mu.Unlock()
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	return s.waitNotifications(ctx)
+}
+
+func (s *Server) newHTTPServer(addr string) (*http.Server, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpServer != nil {
+		return nil, errServerListening
+	}
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: durationOr(s.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       durationOr(s.ReadTimeout, DefaultReadTimeout),
+		WriteTimeout:      durationOr(s.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       durationOr(s.IdleTimeout, DefaultIdleTimeout),
+	}
+	return s.httpServer, nil
+}
+
+// releaseHTTPServer forgets srv, which failed to start listening, so that
+// the server can be started again.
+func (s *Server) releaseHTTPServer(srv *http.Server) {
+	s.mu.Lock()
+	if s.httpServer == srv {
+		s.httpServer = nil
+	}
+	s.mu.Unlock()
+}
+
+// listen announces on addr, or takes over the listener inherited for addr
+// from the parent process, see Restart, limiting the number of simultaneous
+// connections to s.MaxConnections if set.
+func (s *Server) listen(addr string) (net.Listener, error) {
+	l, err := inheritedListener(addr)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		if addr == "" {
+			addr = ":http"
+		}
+		lc := net.ListenConfig{}
+		if s.ReusePort {
+			lc.Control = reusePort
+		}
+		if l, err = lc.Listen(context.Background(), "tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+	if s.MaxConnections > 0 {
+		l = &limitListener{Listener: l, sem: make(chan struct{}, s.MaxConnections)}
+	}
+	return l, nil
+}
+
+// limitListener blocks Accept while n connections are open.
+type limitListener struct {
+	net.Listener
+	sem chan struct{}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	l.sem <- struct{}{}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: c, release: func() { <-l.sem }}, nil
+}
+
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,114 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListenAndServeShutdown(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("random", random)
+
+	addr := "127.0.0.1:4546"
+	done := make(chan error, 1)
+	go func() { done <- server.ListenAndServe(addr) }()
+
+	client := NewClient("http://" + addr)
+	var err error
+	for i := 0; i < 100; i++ {
+		if _, err = client.Call(context.Background(), "random", nil); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("calling server: %v", err)
+	}
+
+	if err := server.ListenAndServe(addr); !errors.Is(err, errServerListening) {
+		t.Errorf("second ListenAndServe:\ngot: %v\nwant: %v", err, errServerListening)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("ListenAndServe after shutdown:\ngot: %v\nwant: %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestListenAndServeRetry(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := busy.Addr().String()
+
+	server := NewServer()
+	if err := server.ListenAndServe(addr); err == nil || errors.Is(err, errServerListening) {
+		t.Fatalf("ListenAndServe on a busy address: got %v", err)
+	}
+	busy.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- server.ListenAndServe(addr) }()
+	for listening := false; !listening; time.Sleep(5 * time.Millisecond) {
+		server.mu.Lock()
+		listening = server.listener != nil
+		server.mu.Unlock()
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("ListenAndServe after a failed one:\ngot: %v\nwant: %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestLimitListener(t *testing.T) {
+	server := NewServer()
+	server.MaxConnections = 1
+	l, err := server.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatalf("second connection accepted over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(time.Second):
+		t.Fatalf("second connection not accepted after the first was closed")
+	}
+}
@@ -0,0 +1,106 @@
+package jsonrpc
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessageCatalog holds translated error messages keyed by language, such as
+// "fr" or "pt-BR", then by error code.
+type MessageCatalog map[string]map[int]string
+
+type languageKey struct{}
+
+// WithLanguage returns a copy of ctx selecting the language of error
+// messages, overriding the Accept-Language header of HTTP requests.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// languages returns the languages accepted for the request of ctx, in order
+// of preference.
+func languages(ctx context.Context) []string {
+	if lang, ok := ctx.Value(languageKey{}).(string); ok {
+		return []string{lang}
+	}
+	if r := HTTPRequest(ctx); r != nil {
+		return parseAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+	return nil
+}
+
+// parseAcceptLanguage returns the languages of an Accept-Language header
+// sorted by decreasing quality.
+func parseAcceptLanguage(h string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(h, ",") {
+		fields := strings.Split(part, ";")
+		l := lang{tag: strings.TrimSpace(fields[0]), q: 1}
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if q, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					l.q = q
+				}
+			}
+		}
+		if l.tag != "" && l.tag != "*" && l.q > 0 {
+			langs = append(langs, l)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// messages returns the messages of the first language of langs found in c,
+// falling back to the base language of each, "fr" for "fr-CH".
+func (c MessageCatalog) messages(langs []string) map[int]string {
+	for _, tag := range langs {
+		for {
+			for lang, msgs := range c {
+				if strings.EqualFold(lang, tag) {
+					return msgs
+				}
+			}
+			i := strings.LastIndexByte(tag, '-')
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return nil
+}
+
+// localize returns e with its message translated to the language of the
+// request of ctx, if s.Messages has one.
+func (s *Server) localize(ctx context.Context, e *Error) *Error {
+	if len(s.Messages) == 0 {
+		return e
+	}
+	msg, ok := s.Messages.messages(languages(ctx))[e.Code]
+	if !ok {
+		return e
+	}
+	// e may be shared, such as ErrInvalidParams
+	l := *e
+	l.Message = msg
+	return &l
+}
+
+// localizeError translates the error of resp, if any.
+func (s *Server) localizeError(ctx context.Context, resp *Response) {
+	if resp != nil && resp.error != nil {
+		resp.error = s.localize(ctx, resp.error)
+	}
+}
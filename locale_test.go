@@ -0,0 +1,74 @@
+package jsonrpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := parseAcceptLanguage("en;q=0.8, fr-CH, fr;q=0.9, *;q=0.5, de;q=0")
+	want := []string{"fr-CH", "fr", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid languages:\ngot: %v\nwant: %v", got, want)
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.Messages = MessageCatalog{
+		"fr":    {CodeMethodNotFound: "Méthode introuvable", CodeInvalidParams: "Paramètres invalides"},
+		"pt-BR": {CodeMethodNotFound: "Método não encontrado"},
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	post := func(lang, body string) string {
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(body))
+		req.Header.Set("Accept-Language", lang)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	tests := []struct {
+		lang string
+		body string
+		want string
+	}{
+		{"fr-CH, en;q=0.5", `{"jsonrpc":"2.0","id":1,"method":"unknown"}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Méthode introuvable"}}`},
+		{"pt-BR", `{"jsonrpc":"2.0","id":1,"method":"unknown"}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Método não encontrado"}}`},
+		// no translation for the code
+		{"pt-BR", `{"jsonrpc":"2.0","id":1,"method":"sum"}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`},
+		{"de", `{"jsonrpc":"2.0","id":1,"method":"unknown"}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`},
+		{"fr", `{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"C":3}}`},
+	}
+	for _, tt := range tests {
+		if got := post(tt.lang, tt.body); got != tt.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", tt.lang, got, tt.want)
+		}
+	}
+	if ErrMethodNotFound.Message != "Method not found" {
+		t.Errorf("shared error modified: %v", ErrMethodNotFound.Message)
+	}
+
+	ctx := WithLanguage(context.Background(), "fr")
+	got := server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"sum"}`))
+	if want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Paramètres invalides"}}`; string(got) != want {
+		t.Errorf("WithLanguage:\ngot: %s\nwant: %v", got, want)
+	}
+}
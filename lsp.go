@@ -0,0 +1,232 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CodeRequestCancelled is the code of the error answering the requests
+// cancelled with $/cancelRequest, see ServeLSP.
+const CodeRequestCancelled = -32800
+
+// ErrRequestCancelled answers the requests cancelled with $/cancelRequest
+// whose handler failed.
+var ErrRequestCancelled = &Error{Code: CodeRequestCancelled, Message: "Request cancelled"}
+
+// DefaultLSPMaxMessage bounds the size of the messages read by ServeLSP when
+// Server.Limits.MaxBytes isn't set.
+const DefaultLSPMaxMessage = 64 << 20
+
+type lspConnKey struct{}
+
+type nullParamsKey struct{}
+
+// allowsNullParams reports whether handlers taking params are called with
+// their zero value when the params of a request are missing, null or empty.
+func allowsNullParams(ctx context.Context) bool {
+	return ctx.Value(nullParamsKey{}) != nil
+}
+
+// ServeLSP serves the messages read from r and writes the responses to w with
+// the conventions of the Language Server Protocol, so that language servers
+// can be built on s. It is usually called with os.Stdin and os.Stdout. It
+// returns once r is exhausted and the pending requests are answered.
+//
+// Messages are framed by a Content-Length header. Notifications are served in
+// order, before the next message is read, as text synchronization requires,
+// and never answered, so unknown $/ notifications are ignored. Requests are
+// served concurrently and can be cancelled with $/cancelRequest: their
+// context is cancelled and, if they fail, ErrRequestCancelled answers them.
+// Handlers taking params are called with their zero value when params are
+// null or empty, see NotifyClient to push notifications to the editor.
+// A message larger than s.Limits.MaxBytes, or DefaultLSPMaxMessage, ends the
+// connection with an error.
+func (s *Server) ServeLSP(ctx context.Context, r io.Reader, w io.Writer) error {
+	c := &lspConn{s: s, w: w, pending: make(map[string]context.CancelFunc)}
+	ctx = context.WithValue(ctx, lspConnKey{}, c)
+	ctx = context.WithValue(ctx, nullParamsKey{}, true)
+	max := s.Limits.MaxBytes
+	if max <= 0 {
+		max = DefaultLSPMaxMessage
+	}
+	tr := textproto.NewReader(bufio.NewReader(r))
+	for {
+		msg, err := readLSPMessage(tr, max)
+		if err != nil {
+			c.wg.Wait()
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("jsonrpc: lsp: reading message: %w", err)
+		}
+		c.serve(ctx, msg)
+	}
+}
+
+// NotifyClient sends a notification for method to the client of the
+// connection serving the request of ctx, such as
+// textDocument/publishDiagnostics to an editor, see ServeLSP.
+func NotifyClient(ctx context.Context, method string, params interface{}) error {
+	c, ok := ctx.Value(lspConnKey{}).(*lspConn)
+	if !ok {
+		return errors.New("jsonrpc: no client connection to notify")
+	}
+	b, err := encodeNotification(method, params)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: marshaling params: %w", err)
+	}
+	return c.writeMessage(b)
+}
+
+// readLSPMessage returns the content of the next message of r, at most max
+// bytes long.
+func readLSPMessage(r *textproto.Reader, max int64) ([]byte, error) {
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	if int64(n) > max {
+		return nil, fmt.Errorf("message of %d bytes exceeds the limit of %d", n, max)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r.R, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+type lspConn struct {
+	s *Server
+
+	wmu sync.Mutex
+	w   io.Writer
+
+	mu      sync.Mutex
+	pending map[string]context.CancelFunc // by encoded request id
+	wg      sync.WaitGroup
+}
+
+func (c *lspConn) serve(ctx context.Context, msg []byte) {
+	if firstByte(msg) != '{' {
+		if resp := c.s.ServeMessage(ctx, msg); resp != nil {
+			c.writeMessage(resp)
+		}
+		return
+	}
+	req, err := unmarshalRequest(msg)
+	if errors.Is(err, errInvalidEncodedJSON) {
+		c.writeResponse(c.s.decodeError(ctx, nil, ErrorParseError))
+		return
+	}
+	if errors.Is(err, errInvalidDecodedMessage) {
+		// responses to requests sent by the server aren't supported
+		if req.msg.Method == "" && (req.msg.Result != nil || req.msg.Error != nil) {
+			releaseRequest(req)
+			return
+		}
+		c.writeResponse(c.s.decodeError(ctx, req, ErrInvalidRequest))
+		releaseRequest(req)
+		return
+	}
+	if err := c.s.IDPolicy.check(req); err != nil {
+		c.writeResponse(c.s.decodeError(ctx, req, err))
+		releaseRequest(req)
+		return
+	}
+	if req.Method == "$/cancelRequest" {
+		c.cancel(req.Params)
+		releaseRequest(req)
+		return
+	}
+	if req.isNotification {
+		if resp := c.s.dispatch(ctx, req); resp != nil {
+			releaseResponses([]*Response{resp})
+		}
+		releaseRequest(req)
+		return
+	}
+
+	key, _ := json.Marshal(req.ID)
+	rctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.pending[string(key)] = cancel
+	c.mu.Unlock()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer releaseRequest(req)
+		resp := c.s.dispatch(rctx, req)
+		c.mu.Lock()
+		delete(c.pending, string(key))
+		c.mu.Unlock()
+		if rctx.Err() != nil && ctx.Err() == nil && resp.error != nil {
+			releaseResponses([]*Response{resp})
+			resp = errResponse(req.ID, ErrRequestCancelled)
+		}
+		cancel()
+		c.writeResponse(resp)
+	}()
+}
+
+// cancel cancels the pending request identified in the params of a
+// $/cancelRequest notification.
+func (c *lspConn) cancel(params json.RawMessage) {
+	var p struct {
+		ID interface{} `json:"id"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.ID == nil {
+		return
+	}
+	key, _ := json.Marshal(p.ID)
+	c.mu.Lock()
+	cancel := c.pending[string(key)]
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (c *lspConn) writeResponse(resp *Response) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	err := resp.encode(buf)
+	releaseResponses([]*Response{resp})
+	if err != nil {
+		log.Printf("jsonrpc: lsp: encoding response: %v", err)
+		return
+	}
+	c.writeMessage(buf.Bytes())
+}
+
+func (c *lspConn) writeMessage(b []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		log.Printf("jsonrpc: lsp: writing message: %v", err)
+		return err
+	}
+	if _, err := c.w.Write(b); err != nil {
+		log.Printf("jsonrpc: lsp: writing message: %v", err)
+		return err
+	}
+	return nil
+}
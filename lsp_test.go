@@ -0,0 +1,80 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestServeLSP(t *testing.T) {
+	type InitializeParams struct {
+		RootURI string `json:"rootUri"`
+	}
+	type DidOpenParams struct {
+		URI string `json:"uri"`
+	}
+	server := NewServer()
+	server.HandleFunc("initialize", func(ctx context.Context, p InitializeParams) (string, error) {
+		return "root:" + p.RootURI, nil
+	})
+	server.HandleFunc("textDocument/didOpen", func(ctx context.Context, p DidOpenParams) (interface{}, error) {
+		return nil, NotifyClient(ctx, "textDocument/publishDiagnostics", map[string]interface{}{"uri": p.URI, "diagnostics": []string{}})
+	})
+	server.HandleFunc("slow", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	var in bytes.Buffer
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":null}`,
+		`{"jsonrpc":"2.0","id":"a","method":"slow"}`,
+		`{"jsonrpc":"2.0","method":"$/setTrace","params":{"value":"off"}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"uri":"file:///a.go"}}`,
+		`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":"a"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"$/unknown"}`,
+		`{"jsonrpc":"2.0","id":3,"result":null}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	var out bytes.Buffer
+	if err := server.ServeLSP(context.Background(), &in, &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	var got []string
+	r := textproto.NewReader(bufio.NewReader(&out))
+	for {
+		msg, err := readLSPMessage(r, DefaultLSPMaxMessage)
+		if err != nil {
+			break
+		}
+		got = append(got, string(msg))
+	}
+	sort.Strings(got)
+	want := []string{
+		`{"jsonrpc":"2.0","id":"a","error":{"code":-32800,"message":"Request cancelled"}}`,
+		`{"jsonrpc":"2.0","id":1,"result":"root:"}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found"}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[],"uri":"file:///a.go"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("invalid messages:\ngot: %v\nwant: %v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if err := server.ServeLSP(context.Background(), strings.NewReader("Content-Length: 10\r\n\r\n{}"), &out); err == nil {
+		t.Errorf("truncated message accepted")
+	}
+	server.Limits.MaxBytes = 1 << 10
+	if err := server.ServeLSP(context.Background(), strings.NewReader("Content-Length: 1099511627776\r\n\r\n{}"), &out); err == nil {
+		t.Errorf("oversized message accepted")
+	}
+	if err := NotifyClient(context.Background(), "window/logMessage", nil); err == nil {
+		t.Errorf("notified without a connection")
+	}
+}
@@ -1,9 +1,14 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"strconv"
+	"sync"
 )
 
 var (
@@ -12,6 +17,26 @@ var (
 	null                     = json.RawMessage([]byte("null"))
 )
 
+// bufferPool holds the buffers used to encode messages.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// don't keep huge buffers around
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
 type rawMessage struct {
 	Version string          `json:"jsonrpc"`
 	ID      interface{}     `json:"id,omitempty"`
@@ -19,6 +44,9 @@ type rawMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
 	Error   *Error          `json:"error,omitempty"`
+	// APIVersion is the version extension member of requests, see
+	// VersionMember.
+	APIVersion json.RawMessage `json:"version,omitempty"`
 }
 
 // request represents a JSON-RPC request received by a server or to be send by a client.
@@ -27,6 +55,65 @@ type request struct {
 	Method         string
 	Params         json.RawMessage
 	isNotification bool
+	// nullID is set for requests with a null id, which are handled as
+	// notifications, see IDPolicy.
+	nullID bool
+	// version is the encoded version extension member, see VersionMember.
+	version json.RawMessage
+	// msg is the scratch message requests are decoded into.
+	msg rawMessage
+}
+
+// Requests and responses built by the server are pooled, they are released
+// once the response has been written.
+var (
+	requestPool = sync.Pool{
+		New: func() interface{} {
+			return new(request)
+		},
+	}
+	responsePool = sync.Pool{
+		New: func() interface{} {
+			return new(Response)
+		},
+	}
+)
+
+func getRequest() *request {
+	return requestPool.Get().(*request)
+}
+
+func releaseRequest(r *request) {
+	r.reset()
+	requestPool.Put(r)
+}
+
+// reset clears r for reuse. Params are dropped rather than reused since
+// handlers taking a json.RawMessage may keep them.
+func (r *request) reset() {
+	*r = request{}
+}
+
+func getResponse() *Response {
+	return responsePool.Get().(*Response)
+}
+
+func releaseResponses(resps []*Response) {
+	for _, r := range resps {
+		r.reset()
+		responsePool.Put(r)
+	}
+}
+
+// reset clears r for reuse.
+func (r *Response) reset() {
+	if r.reader != nil {
+		closeReader(r.reader)
+	}
+	if r.done != nil {
+		r.done()
+	}
+	*r = Response{}
 }
 
 func (r *request) bytes() ([]byte, error) {
@@ -39,11 +126,65 @@ func (r *request) bytes() ([]byte, error) {
 	return json.Marshal(msg)
 }
 
+// encodeWriter is implemented by *bytes.Buffer and *bufio.Writer, which
+// defer write errors to the caller.
+type encodeWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// encodeID appends the JSON encoding of id to buf.
+func encodeID(buf encodeWriter, id interface{}) error {
+	// fast paths for the ids produced by the client and the json decoder
+	var tmp [20]byte
+	switch v := id.(type) {
+	case int64:
+		buf.Write(strconv.AppendInt(tmp[:0], v, 10))
+		return nil
+	case float64:
+		if v == float64(int64(v)) && v < 1e15 && v > -1e15 {
+			buf.Write(strconv.AppendInt(tmp[:0], int64(v), 10))
+			return nil
+		}
+	case json.RawMessage:
+		buf.Write(v)
+		return nil
+	}
+	b, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// encodeNotification returns the JSON encoding of a notification for method.
+func encodeNotification(method string, params interface{}) ([]byte, error) {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	req := &request{Method: method, Params: p, isNotification: true}
+	return req.bytes()
+}
+
 // Response represents the Response from a JSON-RPC request.
 type Response struct {
 	id     interface{}
 	result json.RawMessage
 	error  *Error
+	// stream is the channel returned by a streaming handler, see sendStream.
+	stream reflect.Value
+	// reader is the reader of a RawResult, copied verbatim if rawReader is
+	// set, or of a Base64Result. It is closed by reset.
+	reader    io.Reader
+	rawReader bool
+	// meta is the encoded extension member, name included, see ResponseMeta.
+	meta []byte
+	// done, if set, releases what the call holds until its stream or reader
+	// is written, such as its admission slot. It is called by reset.
+	done func()
 }
 
 func (r *Response) ID() interface{} {
@@ -70,17 +211,77 @@ func (r *Response) Decode(v interface{}) error {
 
 // bytes returns the JSON encoded representation of the Response.
 func (r *Response) bytes() ([]byte, error) {
-	msg := rawMessage{
-		Version: "2.0",
-		ID:      r.id,
-		Result:  r.result,
-		Error:   r.error,
+	var buf bytes.Buffer
+	if err := r.encode(&buf); err != nil {
+		return nil, err
 	}
-	return json.Marshal(msg)
+	return buf.Bytes(), nil
+}
+
+// encode appends the JSON encoded representation of the Response to buf.
+// The result is already encoded, so it is copied as is instead of being
+// marshaled a second time.
+func (r *Response) encode(buf encodeWriter) error {
+	if r.stream.IsValid() {
+		result, err := collectStream(r.stream)
+		if err != nil {
+			return err
+		}
+		r.result, r.stream = result, reflect.Value{}
+	}
+	buf.WriteString(`{"jsonrpc":"2.0"`)
+	if r.id != nil {
+		buf.WriteString(`,"id":`)
+		if err := encodeID(buf, r.id); err != nil {
+			return err
+		}
+	}
+	if r.reader != nil {
+		buf.WriteString(`,"result":`)
+		if err := copyReaderResult(buf, r.reader, r.rawReader); err != nil {
+			return err
+		}
+	} else if len(r.result) > 0 {
+		buf.WriteString(`,"result":`)
+		buf.Write(r.result)
+	}
+	if r.error != nil {
+		b, err := json.Marshal(r.error)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(`,"error":`)
+		buf.Write(b)
+	}
+	if len(r.meta) > 0 {
+		buf.WriteByte(',')
+		buf.Write(r.meta)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// encodeResponses appends resps to buf, as a JSON array if batch is set.
+func encodeResponses(buf *bytes.Buffer, resps []*Response, batch bool) error {
+	if !batch {
+		return resps[0].encode(buf)
+	}
+	buf.WriteByte('[')
+	for i, resp := range resps {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := resp.encode(buf); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
 }
 
 func errResponse(id interface{}, err *Error) *Response {
-	resp := &Response{id: id, result: nil, error: err}
+	resp := getResponse()
+	resp.id, resp.error = id, err
 	// If there was an error in detecting the id in the Request object, ID should be Null
 	if id == nil {
 		resp.id = null
@@ -89,35 +290,118 @@ func errResponse(id interface{}, err *Error) *Response {
 }
 
 // decodeResponseFromReader decodes a JSON-encoded response from r and stores it in resp.
-func decodeResponseFromReader(r io.Reader, resp *Response) error {
-	msg := &rawMessage{}
+// If strict is set, responses which don't follow the specification or don't
+// answer the request id are rejected, see WithStrictResponses.
+func decodeResponseFromReader(r io.Reader, resp *Response, strict bool, id interface{}) error {
+	msg := &rawMessage{ID: absentID{}}
 	if err := json.NewDecoder(r).Decode(msg); err != nil {
 		return errInvalidEncodedJSON
 	}
-	result, err := json.Marshal(msg.Result)
-	if err != nil || msg.Method != "" {
+	_, absent := msg.ID.(absentID)
+	if absent {
+		msg.ID = nil
+	}
+	if msg.Method != "" {
 		resp.id = msg.ID
 		return errInvalidDecodedMessage
 	}
+	if strict {
+		if err := checkResponse(msg); err != nil {
+			return err
+		}
+		switch {
+		case absent:
+			return fmt.Errorf("%w: id is missing", ErrInvalidResponse)
+		case msg.ID == nil && msg.Error != nil:
+			// the server couldn't tell the id of the request
+		case !sameID(msg.ID, id):
+			return &IDError{ID: msg.ID, Expected: id}
+		}
+	}
 
 	resp.id = msg.ID
-	resp.result = result
+	resp.result = msg.Result
+	if resp.result == nil {
+		resp.result = null
+	}
 	resp.error = msg.Error
 
 	return nil
 }
 
-// decodeRequestFromReader decodes a JSON-encoded body and returns a request message.
-func decodeRequestFromReader(r io.Reader) (*request, error) {
-	msg := &rawMessage{}
-	if err := json.NewDecoder(r).Decode(msg); err != nil {
+// ErrInvalidResponse is wrapped by the errors of clients rejecting responses
+// which don't follow the specification, see WithStrictResponses.
+var ErrInvalidResponse = errors.New("invalid response")
+
+// checkResponse reports how msg, decoded from a response, doesn't follow the
+// specification.
+func checkResponse(msg *rawMessage) error {
+	switch {
+	case msg.Version != "2.0":
+		return fmt.Errorf("%w: jsonrpc version %q instead of \"2.0\"", ErrInvalidResponse, msg.Version)
+	case msg.Result != nil && msg.Error != nil:
+		return fmt.Errorf("%w: both result and error are set", ErrInvalidResponse)
+	case msg.Result == nil && msg.Error == nil:
+		return fmt.Errorf("%w: neither result nor error is set", ErrInvalidResponse)
+	case msg.Error != nil && msg.Error.Message == "":
+		return fmt.Errorf("%w: error without message", ErrInvalidResponse)
+	}
+	return nil
+}
+
+// sameID reports whether the ids a and b, decoded or not, are equal.
+func sameID(a, b interface{}) bool {
+	ea, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	eb, err := json.Marshal(b)
+	return err == nil && bytes.Equal(ea, eb)
+}
+
+// decodeRequest decodes the next request message from dec. Malformed JSON
+// yields errInvalidEncodedJSON, valid JSON which isn't a request object
+// errInvalidDecodedMessage.
+// The returned request comes from requestPool.
+func decodeRequest(dec *json.Decoder) (*request, error) {
+	req := getRequest()
+	req.msg.ID = absentID{}
+	return newRequest(req, dec.Decode(&req.msg))
+}
+
+// unmarshalRequest is like decodeRequest for a message held in memory.
+func unmarshalRequest(b []byte) (*request, error) {
+	req := getRequest()
+	req.msg.ID = absentID{}
+	return newRequest(req, json.Unmarshal(b, &req.msg))
+}
+
+// absentID is the id of messages before they are decoded. It is left as is
+// when they have no id and replaced by nil when their id is null.
+type absentID struct{}
+
+// newRequest fills req from its decoded message given the error encountered
+// while decoding it. req is released on parse errors.
+func newRequest(req *request, err error) (*request, error) {
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			req.reset()
+			return req, errInvalidDecodedMessage
+		}
+		releaseRequest(req)
 		return nil, errInvalidEncodedJSON
 	}
 
-	req := &request{ID: msg.ID, Method: msg.Method, Params: msg.Params}
-	if msg.ID == nil {
+	msg := &req.msg
+	switch msg.ID.(type) {
+	case absentID:
+		msg.ID = nil
 		req.isNotification = true
+	case nil:
+		req.isNotification, req.nullID = true, true
 	}
+	req.ID, req.Method, req.Params, req.version = msg.ID, msg.Method, msg.Params, msg.APIVersion
 	//id, ok := parseID(msg.ID)
 	if msg.Method == "" {
 		return req, errInvalidDecodedMessage
@@ -125,6 +409,34 @@ func decodeRequestFromReader(r io.Reader) (*request, error) {
 	return req, nil
 }
 
+// firstByte returns the first non whitespace byte of b, or 0.
+func firstByte(b []byte) byte {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c
+	}
+	return 0
+}
+
+// peekReader returns the first non whitespace byte of r and a reader
+// yielding the whole content of r.
+func peekReader(r io.Reader) (byte, io.Reader, error) {
+	var b [1]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b[0], io.MultiReader(bytes.NewReader(b[:]), r), nil
+	}
+}
+
 func parseID(id interface{}) (interface{}, bool) {
 	if id == nil {
 		return nil, true
@@ -0,0 +1,96 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// ResponseMeta configures an extension member added to responses for
+// diagnostics, such as the server version, timing or warnings. Strict clients
+// may reject unknown members, so it is disabled by default.
+type ResponseMeta struct {
+	// Member is the name of the extension member, such as "meta". The
+	// extension is disabled if it is empty.
+	Member string
+	// Fields are added to the extension of every response.
+	Fields map[string]interface{}
+	// Timing adds the time spent executing the request as "duration".
+	Timing bool
+}
+
+// WithResponseMeta sets Server.Meta.
+func WithResponseMeta(meta ResponseMeta) ServerOption {
+	return func(s *Server) {
+		s.Meta = meta
+	}
+}
+
+type metaKey struct{}
+
+// metaValues are the extension fields set by a handler.
+type metaValues struct {
+	mu       sync.Mutex
+	fields   map[string]interface{}
+	warnings []string
+}
+
+// SetMeta sets the field key of the extension member of the response to
+// value. It does nothing if the extension is disabled or the request is a
+// notification.
+func SetMeta(ctx context.Context, key string, value interface{}) {
+	if m, ok := ctx.Value(metaKey{}).(*metaValues); ok {
+		m.mu.Lock()
+		if m.fields == nil {
+			m.fields = make(map[string]interface{})
+		}
+		m.fields[key] = value
+		m.mu.Unlock()
+	}
+}
+
+// AddWarning appends msg to the "warnings" field of the extension member of
+// the response, see SetMeta.
+func AddWarning(ctx context.Context, msg string) {
+	if m, ok := ctx.Value(metaKey{}).(*metaValues); ok {
+		m.mu.Lock()
+		m.warnings = append(m.warnings, msg)
+		m.mu.Unlock()
+	}
+}
+
+// setMeta encodes the extension member of resp from the configured fields
+// and the values set by the handler.
+func (s *Server) setMeta(resp *Response, m *metaValues, d time.Duration) {
+	fields := make(map[string]interface{}, len(s.Meta.Fields)+len(m.fields)+2)
+	for k, v := range s.Meta.Fields {
+		fields[k] = v
+	}
+	if s.Meta.Timing {
+		fields["duration"] = d.String()
+	}
+	m.mu.Lock()
+	for k, v := range m.fields {
+		fields[k] = v
+	}
+	if len(m.warnings) > 0 {
+		fields["warnings"] = m.warnings
+	}
+	m.mu.Unlock()
+	if len(fields) == 0 {
+		return
+	}
+
+	member, err := json.Marshal(s.Meta.Member)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		log.Printf("jsonrpc: encoding response meta: %v", err)
+		return
+	}
+	resp.meta = append(append(member, ':'), b...)
+}
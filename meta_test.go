@@ -0,0 +1,55 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestResponseMeta(t *testing.T) {
+	server := NewServer()
+	server.Meta = ResponseMeta{
+		Member: "meta",
+		Fields: map[string]interface{}{"version": "1.2.0"},
+		Timing: true,
+	}
+	server.HandleFunc("sum", func(ctx context.Context, args Args) (Reply, error) {
+		SetMeta(ctx, "cached", true)
+		AddWarning(ctx, "A is deprecated")
+		return sum(ctx, args)
+	})
+
+	got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`))
+	var resp struct {
+		Result Reply
+		Meta   struct {
+			Version  string
+			Duration string
+			Cached   bool
+			Warnings []string
+		}
+	}
+	if err := json.Unmarshal(got, &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", got, err)
+	}
+	m := resp.Meta
+	if resp.Result.C != 3 || m.Version != "1.2.0" || m.Duration == "" || !m.Cached || len(m.Warnings) != 1 {
+		t.Errorf("invalid meta: %s", got)
+	}
+
+	got = server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}`))
+	if got != nil {
+		t.Errorf("notification answered: %s", got)
+	}
+
+	// disabled by default
+	server = NewServer()
+	server.HandleFunc("sum", func(ctx context.Context, args Args) (Reply, error) {
+		SetMeta(ctx, "cached", true)
+		return sum(ctx, args)
+	})
+	got = server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`))
+	if want := `{"jsonrpc":"2.0","id":1,"result":{"C":3}}`; string(got) != want {
+		t.Errorf("meta not disabled:\ngot: %s\nwant: %v", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MethodHandler executes a method from its raw params and returns its
+// result, see MethodMiddleware.
+type MethodHandler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// MethodMiddleware wraps the handler of a method, for behavior specific to
+// some methods such as caching, authorization or validation. It may answer
+// without calling next, with an *Error or with a value encoded as the
+// result. Middleware of streaming methods must return the channel returned
+// by next or an error.
+type MethodMiddleware func(next MethodHandler) MethodHandler
+
+// WithMiddleware wraps the method in middleware, the first one seeing the
+// requests first. Middleware runs after the request was authorized and
+// admitted, before the params are decoded, and panics are recovered as
+// they are for handlers.
+func WithMiddleware(middleware ...MethodMiddleware) MethodOption {
+	return func(h *handlerType) {
+		h.middleware = append(h.middleware, middleware...)
+	}
+}
+
+// wrapMiddleware wraps the handler of h in its middleware.
+func (h *handlerType) wrapMiddleware() {
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		h.call = handlerFunc(h.middleware[i](MethodHandler(h.call)))
+	}
+}
@@ -0,0 +1,69 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMethodMiddleware(t *testing.T) {
+	var calls int
+	double := func(ctx context.Context, n int) (int, error) {
+		calls++
+		return 2 * n, nil
+	}
+	var order []string
+	trace := func(name string) MethodMiddleware {
+		return func(next MethodHandler) MethodHandler {
+			return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				order = append(order, name)
+				return next(ctx, params)
+			}
+		}
+	}
+	cache := make(map[string]interface{})
+	cached := func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			if v, ok := cache[string(params)]; ok {
+				return v, nil
+			}
+			v, err := next(ctx, params)
+			if err == nil {
+				cache[string(params)] = v
+			}
+			return v, err
+		}
+	}
+	positive := func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			if strings.HasPrefix(string(params), "-") {
+				return nil, ErrInvalidParamsf("expected a positive number")
+			}
+			return next(ctx, params)
+		}
+	}
+
+	server := NewServer()
+	server.HandleFunc("double", double, WithMiddleware(trace("a"), trace("b")), WithMiddleware(positive, cached))
+	server.HandleFunc("plain", double)
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"double","params":2}`, `{"jsonrpc":"2.0","id":1,"result":4}`},
+		{`{"jsonrpc":"2.0","id":2,"method":"double","params":2}`, `{"jsonrpc":"2.0","id":2,"result":4}`},
+		{`{"jsonrpc":"2.0","id":3,"method":"double","params":-2}`, `{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"Invalid params","data":"expected a positive number"}}`},
+		{`{"jsonrpc":"2.0","id":4,"method":"plain","params":-2}`, `{"jsonrpc":"2.0","id":4,"result":-4}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %v times, want 2", calls)
+	}
+	if got := strings.Join(order, ""); got != "ababab" {
+		t.Errorf("middleware ran in order %v, want ababab", got)
+	}
+}
@@ -0,0 +1,40 @@
+package jsonrpc
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// MQTTPublisher is used to publish responses to MQTT topics. Wrapping a paho
+// client only requires waiting on the token returned by its Publish method.
+type MQTTPublisher interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+}
+
+// MQTTResponseTopic is the default mapping from a request topic to its
+// response topic: a trailing "/request" segment is replaced by "/response",
+// otherwise "/response" is appended. So "devices/42/request" is answered on
+// "devices/42/response".
+func MQTTResponseTopic(topic string) string {
+	return strings.TrimSuffix(topic, "/request") + "/response"
+}
+
+// MQTTHandler returns a function serving the JSON-RPC request received on
+// topic and publishing its response with the given QoS to the topic chosen by
+// responseTopic, MQTTResponseTopic if nil. Clients correlate responses using
+// the JSON-RPC id.
+func (s *Server) MQTTHandler(pub MQTTPublisher, qos byte, responseTopic func(string) string) func(topic string, payload []byte) {
+	if responseTopic == nil {
+		responseTopic = MQTTResponseTopic
+	}
+	return func(topic string, payload []byte) {
+		resp := s.ServeMessage(context.Background(), payload)
+		if resp == nil {
+			return
+		}
+		if err := pub.Publish(responseTopic(topic), qos, false, resp); err != nil {
+			log.Printf("jsonrpc: mqtt: publishing response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package jsonrpc
+
+import "testing"
+
+type mqttMsg struct {
+	topic   string
+	qos     byte
+	payload string
+}
+
+type fakeMQTT struct {
+	msgs []mqttMsg
+}
+
+func (f *fakeMQTT) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	f.msgs = append(f.msgs, mqttMsg{topic, qos, string(payload)})
+	return nil
+}
+
+func TestMQTTHandler(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	pub := &fakeMQTT{}
+	h := server.MQTTHandler(pub, 1, nil)
+	h("devices/42/request", []byte(`{"jsonrpc":"2.0","id":7,"method":"sum","params":{"A":1,"B":2}}`))
+	h("devices/42/request", []byte(`{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}`))
+	h("devices/43", []byte(`{"jsonrpc":"2.0","id":8,"method":"sum","params":{"A":2,"B":2}}`))
+
+	want := []mqttMsg{
+		{"devices/42/response", 1, `{"jsonrpc":"2.0","id":7,"result":{"C":3}}`},
+		{"devices/43/response", 1, `{"jsonrpc":"2.0","id":8,"result":{"C":4}}`},
+	}
+	if len(pub.msgs) != len(want) {
+		t.Fatalf("invalid published responses:\ngot: %v\nwant: %v", pub.msgs, want)
+	}
+	for i := range want {
+		if pub.msgs[i] != want[i] {
+			t.Errorf("invalid published response:\ngot: %v\nwant: %v", pub.msgs[i], want[i])
+		}
+	}
+}
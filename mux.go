@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Middleware wraps an http.Handler, typically to authenticate or log the
+// requests it serves.
+type Middleware func(http.Handler) http.Handler
+
+// Mux routes HTTP requests to the Servers, or any http.Handler, mounted
+// under path prefixes, so that independent APIs, like a public and an
+// internal one, are served by one process:
+//
+//	mux := jsonrpc.NewMux(logRequests)
+//	mux.Handle("/rpc/v1", public)
+//	mux.Handle("/rpc/internal", internal, requireAdmin)
+//	http.ListenAndServe(":8080", mux)
+//
+// A request is routed to the longest prefix matching its path on segment
+// boundaries, requests matching none are answered with 404.
+type Mux struct {
+	mu         sync.RWMutex
+	entries    []muxEntry // sorted by decreasing prefix length
+	middleware []Middleware
+	handler    http.Handler
+}
+
+type muxEntry struct {
+	prefix  string
+	handler http.Handler
+}
+
+// NewMux returns a Mux applying middleware to all the requests it serves.
+func NewMux(middleware ...Middleware) *Mux {
+	m := &Mux{middleware: middleware}
+	m.handler = chainMiddleware(http.HandlerFunc(m.route), m.middleware)
+	return m
+}
+
+// Use adds middleware applied to all the requests served by m, after the
+// middleware already added.
+func (m *Mux) Use(middleware ...Middleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.middleware = append(m.middleware, middleware...)
+	m.handler = chainMiddleware(http.HandlerFunc(m.route), m.middleware)
+}
+
+// Handle mounts h under prefix, wrapped in middleware which only applies to
+// the requests it serves. The path of the requests isn't rewritten.
+func (m *Mux) Handle(prefix string, h http.Handler, middleware ...Middleware) error {
+	prefix = cleanPrefix(prefix)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.entries {
+		if e.prefix == prefix {
+			return fmt.Errorf("jsonrpc: %v already mounted", prefix)
+		}
+	}
+	m.entries = append(m.entries, muxEntry{prefix: prefix, handler: chainMiddleware(h, middleware)})
+	sort.SliceStable(m.entries, func(i, j int) bool { return len(m.entries[i].prefix) > len(m.entries[j].prefix) })
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (m *Mux) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	h := m.handler
+	m.mu.RUnlock()
+	h.ServeHTTP(rw, r)
+}
+
+func (m *Mux) route(rw http.ResponseWriter, r *http.Request) {
+	if h := m.lookup(r.URL.Path); h != nil {
+		h.ServeHTTP(rw, r)
+		return
+	}
+	rw.WriteHeader(http.StatusNotFound)
+	rw.Write([]byte("Not found"))
+}
+
+// lookup returns the handler mounted under the longest prefix of p.
+func (m *Mux) lookup(p string) http.Handler {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, e := range m.entries {
+		if e.prefix == "/" || p == e.prefix || strings.HasPrefix(p, e.prefix) && p[len(e.prefix)] == '/' {
+			return e.handler
+		}
+	}
+	return nil
+}
+
+func cleanPrefix(prefix string) string {
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return path.Clean(prefix)
+}
+
+// chainMiddleware wraps h in middleware, the first one being the outermost.
+func chainMiddleware(h http.Handler, middleware []Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
@@ -0,0 +1,67 @@
+package jsonrpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMux(t *testing.T) {
+	public, internal := NewServer(), NewServer()
+	public.HandleFunc("name", func(ctx context.Context) (string, error) { return "public", nil })
+	internal.HandleFunc("name", func(ctx context.Context) (string, error) { return "internal", nil })
+
+	var trace []string
+	tag := func(name string) Middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				trace = append(trace, name)
+				h.ServeHTTP(rw, r)
+			})
+		}
+	}
+	mux := NewMux(tag("shared"))
+	mux.Use(tag("used"))
+	if err := mux.Handle("/rpc/v1", public); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if err := mux.Handle("/rpc/v1/internal/", internal, tag("internal")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if err := mux.Handle("rpc/v1", public); err == nil {
+		t.Errorf("duplicate prefix mounted")
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		trace  string
+	}{
+		{"/rpc/v1", 200, `{"jsonrpc":"2.0","id":1,"result":"public"}`, "shared,used"},
+		{"/rpc/v1/internal", 200, `{"jsonrpc":"2.0","id":1,"result":"internal"}`, "shared,used,internal"},
+		{"/rpc/v1/internal/x", 200, `{"jsonrpc":"2.0","id":1,"result":"internal"}`, "shared,used,internal"},
+		{"/rpc/v10", 404, "Not found", "shared,used"},
+		{"/", 404, "Not found", "shared,used"},
+	}
+	for _, test := range tests {
+		trace = nil
+		resp, err := http.Post(ts.URL+test.path, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"name"}`))
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status || strings.TrimSpace(string(b)) != test.body {
+			t.Errorf("%v:\ngot: %v %s\nwant: %v %s", test.path, resp.StatusCode, b, test.status, test.body)
+		}
+		if got := strings.Join(trace, ","); got != test.trace {
+			t.Errorf("%v: invalid middleware:\ngot: %v\nwant: %v", test.path, got, test.trace)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// NATSPublisher is the subset of *nats.Conn used to publish responses and
+// notifications.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSHandler returns a function serving the JSON-RPC request in data and
+// publishing its response to the reply subject. It is meant to be called from
+// a NATS subscription:
+//
+//	h := server.NATSHandler(nc)
+//	nc.Subscribe("rpc", func(m *nats.Msg) { h(m.Reply, m.Data) })
+//
+// Responses to messages without a reply subject are discarded.
+func (s *Server) NATSHandler(pub NATSPublisher) func(reply string, data []byte) {
+	return func(reply string, data []byte) {
+		resp := s.ServeMessage(context.Background(), data)
+		if resp == nil || reply == "" {
+			return
+		}
+		if err := pub.Publish(reply, resp); err != nil {
+			log.Printf("jsonrpc: nats: publishing response: %v", err)
+		}
+	}
+}
+
+// PublishNATS publishes a JSON-RPC notification for method to subject.
+func PublishNATS(pub NATSPublisher, subject, method string, params interface{}) error {
+	b, err := encodeNotification(method, params)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: marshaling params: %w", err)
+	}
+	if err := pub.Publish(subject, b); err != nil {
+		return fmt.Errorf("jsonrpc: nats: %w", err)
+	}
+	return nil
+}
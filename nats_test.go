@@ -0,0 +1,49 @@
+package jsonrpc
+
+import "testing"
+
+type natsMsg struct {
+	subject string
+	data    string
+}
+
+type fakeNATS struct {
+	msgs []natsMsg
+}
+
+func (f *fakeNATS) Publish(subject string, data []byte) error {
+	f.msgs = append(f.msgs, natsMsg{subject, string(data)})
+	return nil
+}
+
+func TestNATSHandler(t *testing.T) {
+	counter := &state{}
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.HandleFunc("counter", counter.increaseCounter)
+
+	nc := &fakeNATS{}
+	h := server.NATSHandler(nc)
+	h("_INBOX.1", []byte(`{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}}`))
+	h("_INBOX.2", []byte(`{"jsonrpc":"2.0","method":"counter","params":3}`))
+	h("", []byte(`{"jsonrpc":"2.0","id":2,"method":"sum","params":{"A":1,"B":2}}`))
+
+	want := []natsMsg{{"_INBOX.1", `{"jsonrpc":"2.0","id":1,"result":{"C":3}}`}}
+	if len(nc.msgs) != len(want) || nc.msgs[0] != want[0] {
+		t.Errorf("invalid published messages:\ngot: %v\nwant: %v", nc.msgs, want)
+	}
+	if counter.N != 3 {
+		t.Errorf("notification not served:\ngot: %v\nwant: %v", counter.N, 3)
+	}
+}
+
+func TestPublishNATS(t *testing.T) {
+	nc := &fakeNATS{}
+	if err := PublishNATS(nc, "events", "updated", Args{1, 2}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	want := natsMsg{"events", `{"jsonrpc":"2.0","method":"updated","params":{"A":1,"B":2}}`}
+	if len(nc.msgs) != 1 || nc.msgs[0] != want {
+		t.Errorf("invalid published notification:\ngot: %v\nwant: %v", nc.msgs, want)
+	}
+}
@@ -0,0 +1,249 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NotificationStore persists the notifications queued for background
+// execution, see Server.NotificationWorkers, so that they survive restarts.
+// It also persists the offline queue of clients, see WithOfflineQueue.
+type NotificationStore interface {
+	// Add persists the notification msg under id.
+	Add(ctx context.Context, id string, msg []byte) error
+	// Remove forgets the notification id once it was executed.
+	Remove(ctx context.Context, id string) error
+	// Pending returns the notifications not removed, oldest first.
+	Pending(ctx context.Context) ([]StoredNotification, error)
+}
+
+// StoredNotification is a notification kept by a NotificationStore.
+type StoredNotification struct {
+	ID      string
+	Message []byte
+}
+
+// notificationQueue feeds the notifications executed in the background to
+// the workers.
+type notificationQueue struct {
+	once    sync.Once
+	c       chan queuedNotification
+	pending sync.WaitGroup
+}
+
+type queuedNotification struct {
+	ctx    context.Context
+	id     string // in s.NotificationStore, if set
+	method string
+	params json.RawMessage
+}
+
+var errNotificationQueueFull = errors.New("jsonrpc: notification queue full")
+
+// StartNotifications starts the workers executing notifications in the
+// background and queues the notifications left in s.NotificationStore by a
+// previous process. It is called with the first notification otherwise,
+// servers with a store should call it on startup.
+func (s *Server) StartNotifications(ctx context.Context) error {
+	var err error
+	s.notifications.once.Do(func() {
+		err = s.startNotifications(ctx)
+	})
+	return err
+}
+
+func (s *Server) startNotifications(ctx context.Context) error {
+	if s.NotificationWorkers <= 0 {
+		return errors.New("jsonrpc: no notification workers")
+	}
+	q := &s.notifications
+	q.c = make(chan queuedNotification, s.NotificationQueue)
+	for i := 0; i < s.NotificationWorkers; i++ {
+		go s.notificationWorker()
+	}
+	if s.NotificationStore == nil {
+		return nil
+	}
+	stored, err := s.NotificationStore.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: loading notifications: %w", err)
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+	// stored notifications don't count against the size of the queue
+	q.pending.Add(len(stored))
+	go func() {
+		for _, n := range stored {
+			var msg rawMessage
+			if err := json.Unmarshal(n.Message, &msg); err != nil {
+				log.Printf("jsonrpc: notification %v: %v", n.ID, err)
+				q.pending.Done()
+				continue
+			}
+			q.c <- queuedNotification{ctx: context.Background(), id: n.ID, method: msg.Method, params: msg.Params}
+		}
+	}()
+	return nil
+}
+
+// queueNotification queues the notification for the method name, already
+// authorized, for a worker. Its handler gets the values of ctx but not its
+// cancellation nor its HTTP response.
+func (s *Server) queueNotification(ctx context.Context, name string, params json.RawMessage) error {
+	if err := s.StartNotifications(context.Background()); err != nil {
+		log.Print(err)
+	}
+	q := &s.notifications
+	n := queuedNotification{ctx: detachedContext{ctx}, method: name, params: params}
+	if s.NotificationStore != nil {
+		req := &request{Method: name, Params: params}
+		msg, err := req.bytes()
+		if err != nil {
+			return err
+		}
+		n.id = fmt.Sprintf("%016x-%s", time.Now().UnixNano(), randomID(8))
+		if err := s.NotificationStore.Add(ctx, n.id, msg); err != nil {
+			return fmt.Errorf("jsonrpc: storing notification: %w", err)
+		}
+	}
+	q.pending.Add(1)
+	select {
+	case q.c <- n:
+		return nil
+	default:
+		q.pending.Done()
+		if n.id != "" {
+			s.NotificationStore.Remove(ctx, n.id)
+		}
+		return errNotificationQueueFull
+	}
+}
+
+func (s *Server) notificationWorker() {
+	q := &s.notifications
+	for n := range q.c {
+		s.runNotification(n)
+		q.pending.Done()
+	}
+}
+
+func (s *Server) runNotification(n queuedNotification) {
+	method, ok := s.handler.Load(n.method)
+	if !ok {
+		log.Printf("jsonrpc: notification: dropping %v: method not found", n.method)
+	} else {
+		req := &request{Method: n.method, Params: n.params, isNotification: true}
+		if _, err := s.callNotification(n.ctx, req, method.(handlerType), func() {}); err != nil {
+			log.Printf("jsonrpc: notification %v: %v", n.method, err)
+		}
+	}
+	if n.id != "" {
+		if err := s.NotificationStore.Remove(context.Background(), n.id); err != nil {
+			log.Printf("jsonrpc: removing notification %v: %v", n.id, err)
+		}
+	}
+}
+
+// waitNotifications waits for the queued notifications to be executed, or
+// for ctx to be done.
+func (s *Server) waitNotifications(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.notifications.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// detachedContext carries the values of a request context without its
+// cancellation, for work outliving the request. The HTTP response is hidden
+// since it is gone by then.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	if key == (httpContextKey{}) {
+		return nil
+	}
+	return c.Context.Value(key)
+}
+
+// DirNotificationStore is a NotificationStore keeping each notification in a
+// file of a directory.
+type DirNotificationStore struct {
+	dir string
+}
+
+// NewDirNotificationStore returns a DirNotificationStore keeping
+// notifications in dir, created if needed.
+func NewDirNotificationStore(dir string) (*DirNotificationStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DirNotificationStore{dir: dir}, nil
+}
+
+const notificationFileExt = ".json"
+
+// Add implements NotificationStore. The notification is written to a
+// temporary file first, so that a crash doesn't leave a partial one.
+func (d *DirNotificationStore) Add(ctx context.Context, id string, msg []byte) error {
+	path := filepath.Join(d.dir, id+notificationFileExt)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, msg, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Remove implements NotificationStore.
+func (d *DirNotificationStore) Remove(ctx context.Context, id string) error {
+	err := os.Remove(filepath.Join(d.dir, id+notificationFileExt))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Pending implements NotificationStore.
+func (d *DirNotificationStore) Pending(ctx context.Context) ([]StoredNotification, error) {
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var pending []StoredNotification
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasSuffix(name, notificationFileExt) {
+			continue
+		}
+		msg, err := ioutil.ReadFile(filepath.Join(d.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, StoredNotification{ID: strings.TrimSuffix(name, notificationFileExt), Message: msg})
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending, nil
+}
@@ -0,0 +1,148 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotificationWorkers(t *testing.T) {
+	var mu sync.Mutex
+	var got []int
+	started, release := make(chan struct{}, 3), make(chan struct{})
+	server := NewServer()
+	server.NotificationWorkers, server.NotificationQueue = 1, 1
+	server.HandleFunc("log", func(ctx context.Context, n int) (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		got = append(got, n)
+		mu.Unlock()
+		return nil, nil
+	})
+
+	notify := func(msg string) {
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, httptest.NewRequest("POST", "/", bytes.NewReader([]byte(msg))))
+		if rw.Code != 204 {
+			t.Errorf("invalid status:\ngot: %v\nwant: 204", rw.Code)
+		}
+	}
+	notify(`{"jsonrpc":"2.0","method":"log","params":1}`)
+	<-started
+	notify(`{"jsonrpc":"2.0","method":"log","params":2}`)
+	notify(`{"jsonrpc":"2.0","method":"log","params":3}`) // queue full
+	close(release)
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid notifications:\ngot: %v\nwant: %v", got, want)
+	}
+}
+
+func TestNotificationWorkersStream(t *testing.T) {
+	var live int32
+	closed := 0
+	server := NewServer()
+	server.NotificationWorkers, server.NotificationQueue = 1, 4
+	server.HandleFunc("subscribe", func(ctx context.Context) (<-chan int, error) {
+		atomic.AddInt32(&live, 1)
+		c := make(chan int)
+		go func() {
+			defer atomic.AddInt32(&live, -1)
+			defer close(c)
+			for i := 0; ; i++ {
+				select {
+				case c <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return c, nil
+	})
+	server.HandleFunc("raw", func(ctx context.Context) (RawResult, error) {
+		return RawResult{closeCounter{strings.NewReader(`"ok"`), &closed}}, nil
+	})
+
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","method":"subscribe"}`,
+		`{"jsonrpc":"2.0","method":"subscribe"}`,
+		`{"jsonrpc":"2.0","method":"subscribe"}`,
+		`{"jsonrpc":"2.0","method":"raw"}`,
+	} {
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, httptest.NewRequest("POST", "/", strings.NewReader(msg)))
+		if rw.Code != 204 {
+			t.Errorf("invalid status:\ngot: %v\nwant: 204", rw.Code)
+		}
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if closed != 1 {
+		t.Errorf("reader closed %v times, want 1", closed)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&live) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&live); n != 0 {
+		t.Errorf("%v streams left running", n)
+	}
+}
+
+func TestNotificationStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewDirNotificationStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first server stops before executing its notifications
+	stuck := make(chan struct{})
+	defer close(stuck)
+	old := NewServer()
+	old.NotificationWorkers, old.NotificationQueue, old.NotificationStore = 1, 2, store
+	old.HandleFunc("log", func(ctx context.Context, n int) (interface{}, error) {
+		<-stuck
+		return nil, nil
+	})
+	for _, msg := range []string{`{"jsonrpc":"2.0","method":"log","params":1}`, `{"jsonrpc":"2.0","method":"log","params":2}`} {
+		old.ServeMessage(context.Background(), []byte(msg))
+	}
+
+	got := make(chan int, 2)
+	server := NewServer()
+	server.NotificationWorkers, server.NotificationStore = 1, store
+	server.HandleFunc("log", func(ctx context.Context, n int) (interface{}, error) {
+		got <- n
+		return nil, nil
+	})
+	if err := server.StartNotifications(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if a, b := <-got, <-got; a != 1 || b != 2 {
+		t.Errorf("invalid notifications:\ngot: %v, %v\nwant: 1, 2", a, b)
+	}
+	if pending, err := store.Pending(context.Background()); err != nil || len(pending) != 0 {
+		t.Errorf("notifications left in the store: %v, %v", pending, err)
+	}
+}
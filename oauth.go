@@ -0,0 +1,221 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntrospectionConfig configures the TokenIntrospection middleware. One of
+// IntrospectionURL and UserInfoURL must be set.
+type IntrospectionConfig struct {
+	// IntrospectionURL is the RFC 7662 token introspection endpoint of the
+	// authorization server, authenticated with ClientID and ClientSecret.
+	IntrospectionURL string
+	ClientID         string
+	ClientSecret     string
+	// UserInfoURL is the OIDC userinfo endpoint, used when the provider
+	// has no introspection endpoint. Tokens it accepts are valid, their
+	// scopes are read from the "scope" claim if present.
+	UserInfoURL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// CacheTTL is how long the result of a validation is reused, bounded
+	// by the expiry of the token. Defaults to 1m.
+	CacheTTL time.Duration
+	// Optional lets requests without a token through, unauthenticated, for
+	// servers mixing public and protected methods.
+	Optional bool
+}
+
+// TokenIntrospection returns a middleware validating the opaque bearer
+// tokens of the requests it serves with the authorization server and setting
+// the client they were issued to on the context, see GetPrincipal. Requests
+// without a valid token are answered with 401.
+func TokenIntrospection(cfg IntrospectionConfig) Middleware {
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	cfg.CacheTTL = durationOr(cfg.CacheTTL, time.Minute)
+	ti := &tokenIntrospector{cfg: cfg, cache: make(map[[sha256.Size]byte]cachedToken)}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			if token == "" {
+				if cfg.Optional {
+					h.ServeHTTP(rw, r)
+					return
+				}
+				unauthorized(rw, `Bearer`)
+				return
+			}
+			p, err := ti.principal(r.Context(), token)
+			if err != nil {
+				log.Printf("jsonrpc: introspecting token: %v", err)
+				http.Error(rw, "Service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if p == nil {
+				unauthorized(rw, `Bearer error="invalid_token"`)
+				return
+			}
+			h.ServeHTTP(rw, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	const prefix = "bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+func unauthorized(rw http.ResponseWriter, challenge string) {
+	rw.Header().Set("WWW-Authenticate", challenge)
+	http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+}
+
+type tokenIntrospector struct {
+	cfg IntrospectionConfig
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedToken // by token hash, to not keep tokens
+}
+
+type cachedToken struct {
+	principal *Principal // nil for invalid tokens
+	expires   time.Time
+}
+
+// principal returns the client token was issued to, nil if token isn't
+// valid.
+func (ti *tokenIntrospector) principal(ctx context.Context, token string) (*Principal, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	ti.mu.Lock()
+	c, ok := ti.cache[key]
+	ti.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.principal, nil
+	}
+
+	var claims map[string]interface{}
+	var err error
+	if ti.cfg.IntrospectionURL != "" {
+		claims, err = ti.introspect(ctx, token)
+	} else if ti.cfg.UserInfoURL != "" {
+		claims, err = ti.userInfo(ctx, token)
+	} else {
+		err = errors.New("no introspection or userinfo endpoint")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c = cachedToken{expires: now.Add(ti.cfg.CacheTTL)}
+	if claims != nil {
+		c.principal = newPrincipal(claims)
+		if exp, ok := claims["exp"].(float64); ok {
+			if t := time.Unix(int64(exp), 0); t.Before(c.expires) {
+				c.expires = t
+			}
+		}
+	}
+	ti.mu.Lock()
+	// map iteration starts at a random entry, checking one per insertion
+	// drops expired entries over time without scanning the cache
+	for k, old := range ti.cache {
+		if now.After(old.expires) {
+			delete(ti.cache, k)
+		}
+		break
+	}
+	ti.cache[key] = c
+	ti.mu.Unlock()
+	return c.principal, nil
+}
+
+// introspect returns the claims of token, nil if it isn't active.
+func (ti *tokenIntrospector) introspect(ctx context.Context, token string) (map[string]interface{}, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ti.cfg.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if ti.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(ti.cfg.ClientID), url.QueryEscape(ti.cfg.ClientSecret))
+	}
+	claims, err := ti.do(req, false)
+	if err != nil || claims == nil {
+		return nil, err
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, nil
+	}
+	return claims, nil
+}
+
+// userInfo returns the claims of the owner of token, nil if token isn't
+// accepted.
+func (ti *tokenIntrospector) userInfo(ctx context.Context, token string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ti.cfg.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	return ti.do(req, true)
+}
+
+// do sends req and decodes the JSON object it is answered with. If req is
+// authenticated with the token, a 401 means it is invalid and yields nil.
+func (ti *tokenIntrospector) do(req *http.Request, tokenAuth bool) (map[string]interface{}, error) {
+	resp, err := ti.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if tokenAuth && resp.StatusCode == http.StatusUnauthorized {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, err
+	}
+	if claims == nil {
+		claims = make(map[string]interface{})
+	}
+	return claims, nil
+}
+
+func newPrincipal(claims map[string]interface{}) *Principal {
+	p := &Principal{Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	if p.Subject == "" {
+		p.Subject, _ = claims["username"].(string)
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	}
+	return p
+}
@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTokenIntrospection(t *testing.T) {
+	var introspections int32
+	idp := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&introspections, 1)
+		if id, secret, _ := r.BasicAuth(); id != "rpc" || secret != "s3cret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		if r.Form.Get("token") == "good" {
+			rw.Write([]byte(`{"active":true,"sub":"gopher","scope":"orders:read orders:write"}`))
+			return
+		}
+		rw.Write([]byte(`{"active":false}`))
+	}))
+	defer idp.Close()
+
+	var principal *Principal
+	server := NewServer()
+	server.HandleFunc("whoami", func(ctx context.Context) (string, error) {
+		principal = GetPrincipal(ctx)
+		return "", nil
+	})
+	h := TokenIntrospection(IntrospectionConfig{
+		IntrospectionURL: idp.URL,
+		ClientID:         "rpc",
+		ClientSecret:     "s3cret",
+	})(server)
+
+	call := func(auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"whoami"}`))
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		return rw
+	}
+
+	for i := 0; i < 2; i++ {
+		principal = nil
+		if rw := call("Bearer good"); rw.Code != 200 {
+			t.Fatalf("valid token: status %v", rw.Code)
+		}
+		if principal == nil || principal.Subject != "gopher" || !principal.HasScope("orders:write") {
+			t.Errorf("invalid principal: %+v", principal)
+		}
+	}
+	if GetPrincipal(context.Background()).HasScope("orders:write") {
+		t.Errorf("scope granted without a principal")
+	}
+	if n := atomic.LoadInt32(&introspections); n != 1 {
+		t.Errorf("introspection not cached: %v requests", n)
+	}
+	if rw := call("Bearer bad"); rw.Code != 401 || !strings.Contains(rw.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Errorf("invalid token: %v %q", rw.Code, rw.Header().Get("WWW-Authenticate"))
+	}
+	if rw := call(""); rw.Code != 401 || rw.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("missing token: %v %q", rw.Code, rw.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestTokenIntrospectionUserInfo(t *testing.T) {
+	idp := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Write([]byte(`{"sub":"gopher","email":"gopher@example.com"}`))
+	}))
+	defer idp.Close()
+
+	var principal *Principal
+	h := TokenIntrospection(IntrospectionConfig{UserInfoURL: idp.URL, Optional: true})(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		principal = GetPrincipal(r.Context())
+	}))
+	tests := []struct {
+		auth    string
+		status  int
+		subject string
+	}{
+		{"Bearer good", 200, "gopher"},
+		{"Bearer bad", 401, ""},
+		{"", 200, ""},
+	}
+	for _, test := range tests {
+		principal = nil
+		r := httptest.NewRequest("POST", "/", nil)
+		if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		subject := ""
+		if principal != nil {
+			subject = principal.Subject
+		}
+		if rw.Code != test.status || subject != test.subject {
+			t.Errorf("%q:\ngot: %v %q\nwant: %v %q", test.auth, rw.Code, subject, test.status, test.subject)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultOfflineRetry is the default interval at which a client tries to
+// send its offline queue.
+const DefaultOfflineRetry = 5 * time.Second
+
+// ErrQueued is returned by the calls of a client with an offline queue which
+// were queued instead of being sent, see WithOfflineQueue.
+var ErrQueued = errors.New("jsonrpc: request queued until the server is reachable")
+
+// QueuedError is the error of a queued call. It wraps ErrQueued and holds the
+// id the call is sent with, the id of the Response passed to
+// OfflineQueue.OnResponse.
+type QueuedError struct {
+	ID interface{}
+}
+
+func (e *QueuedError) Error() string {
+	return fmt.Sprintf("%v, id %v", ErrQueued, e.ID)
+}
+
+func (e *QueuedError) Unwrap() error {
+	return ErrQueued
+}
+
+// OfflineQueue configures the queue of requests a client can't send, see
+// WithOfflineQueue.
+type OfflineQueue struct {
+	// Size is the number of requests queued at most. Requests beyond it
+	// fail as if there was no queue.
+	Size int
+	// Store persists the queue, such as a DirNotificationStore, so that
+	// queued requests survive restarts. The queue is kept in memory only
+	// if not set.
+	Store NotificationStore
+	// RetryInterval is the interval at which the client tries to send the
+	// queued requests, DefaultOfflineRetry if not set.
+	RetryInterval time.Duration
+	// OnResponse is called with the responses of the queued calls, once
+	// they are sent. Responses are matched to calls by their id, see
+	// QueuedError.
+	OnResponse func(method string, resp *Response)
+}
+
+// WithOfflineQueue makes the client queue the calls and notifications it
+// can't send because the server is unreachable, and send them in order once
+// it is reachable again, for agents on flaky links. Calls which were queued
+// fail with a *QueuedError, their responses go to q.OnResponse. Notifications
+// which were queued succeed. While the queue isn't empty, new requests are
+// queued behind it. Batches are not queued.
+func WithOfflineQueue(q OfflineQueue) ClientOption {
+	return func(c *Client) {
+		q.RetryInterval = durationOr(q.RetryInterval, DefaultOfflineRetry)
+		c.offline = &offlineQueue{cfg: q, wake: make(chan struct{}, 1)}
+	}
+}
+
+type offlineQueue struct {
+	cfg  OfflineQueue
+	seq  uint64
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending []StoredNotification
+}
+
+// unreachableError is the error of requests which didn't reach the server.
+type unreachableError struct {
+	err error
+}
+
+func (e unreachableError) Error() string {
+	return e.err.Error()
+}
+
+func (e unreachableError) Unwrap() error {
+	return e.err
+}
+
+func isUnreachable(err error) bool {
+	var u unreachableError
+	return errors.As(err, &u)
+}
+
+// load queues the requests left in the store by a previous client.
+func (q *offlineQueue) load() {
+	if q.cfg.Store == nil {
+		return
+	}
+	stored, err := q.cfg.Store.Pending(context.Background())
+	if err != nil {
+		log.Printf("jsonrpc: loading offline queue: %v", err)
+		return
+	}
+	q.mu.Lock()
+	q.pending = stored
+	q.mu.Unlock()
+}
+
+func (q *offlineQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// queue queues req, it returns the error of the call or notification.
+func (c *Client) queue(ctx context.Context, req *ClientRequest) error {
+	q := c.offline
+	p, err := json.Marshal(req.Params)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: marshaling params: %w", err)
+	}
+	r := &request{Method: req.Method, Params: p}
+	if !req.Notification {
+		// queued calls aren't in flight
+		r.ID = c.nextID()
+		c.releaseID(r.ID)
+	}
+	msg, err := r.bytes()
+	if err != nil {
+		return fmt.Errorf("jsonrpc: sending request: %w", err)
+	}
+	// ids sort in queue order
+	n := StoredNotification{
+		ID:      fmt.Sprintf("%016x-%08x", time.Now().UnixNano(), uint32(atomic.AddUint64(&q.seq, 1))),
+		Message: msg,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= q.cfg.Size {
+		return fmt.Errorf("jsonrpc: offline queue full")
+	}
+	if q.cfg.Store != nil {
+		if err := q.cfg.Store.Add(ctx, n.ID, n.Message); err != nil {
+			return fmt.Errorf("jsonrpc: storing request: %w", err)
+		}
+	}
+	q.pending = append(q.pending, n)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	if req.Notification {
+		return nil
+	}
+	return &QueuedError{ID: r.ID}
+}
+
+// flushOffline sends the queued requests until ctx is done.
+func (c *Client) flushOffline(ctx context.Context) {
+	q := c.offline
+	t := time.NewTicker(q.cfg.RetryInterval)
+	defer t.Stop()
+	for {
+		c.sendQueued(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// sendQueued sends the queued requests in order, until one doesn't reach
+// the server.
+func (c *Client) sendQueued(ctx context.Context) {
+	q := c.offline
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		n := q.pending[0]
+		q.mu.Unlock()
+
+		if err := c.sendStored(ctx, n.Message); err != nil {
+			if isUnreachable(err) || ctx.Err() != nil {
+				return
+			}
+			log.Printf("jsonrpc: queued request %v: %v", n.ID, err)
+		}
+		if q.cfg.Store != nil {
+			if err := q.cfg.Store.Remove(ctx, n.ID); err != nil {
+				log.Printf("jsonrpc: removing queued request %v: %v", n.ID, err)
+			}
+		}
+		q.mu.Lock()
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+	}
+}
+
+// sendStored sends the queued request msg and passes its response to the
+// OnResponse hook.
+func (c *Client) sendStored(ctx context.Context, msg []byte) error {
+	var m rawMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return err
+	}
+	rc, _, err := c.send(ctx, msg)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if m.ID == nil {
+		io.Copy(ioutil.Discard, rc)
+		return nil
+	}
+	resp := &Response{}
+	if err := decodeResponseFromReader(rc, resp, c.strict, m.ID); err != nil {
+		return err
+	}
+	if c.offline.cfg.OnResponse != nil {
+		c.offline.cfg.OnResponse(m.Method, resp)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewDirNotificationStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an address nothing listens on yet
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx := context.Background()
+	client := NewClient("http://"+addr, WithOfflineQueue(OfflineQueue{Size: 10, Store: store, RetryInterval: time.Hour}))
+	if err := client.Notify(ctx, "log", "a"); err != nil {
+		t.Fatalf("notification not queued: %v", err)
+	}
+	_, err = client.Call(ctx, "log", "b")
+	var queued *QueuedError
+	if !errors.Is(err, ErrQueued) || !errors.As(err, &queued) {
+		t.Fatalf("got error %v, want ErrQueued", err)
+	}
+	if err := client.Notify(ctx, "log", "c"); err != nil {
+		t.Fatalf("notification not queued: %v", err)
+	}
+	client.Close()
+
+	var mu sync.Mutex
+	var logged []string
+	s := NewServer()
+	s.HandleFunc("log", func(ctx context.Context, msg string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, msg)
+		return "logged " + msg, nil
+	})
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Skipf("address taken again: %v", err)
+	}
+	hs := &http.Server{Handler: s}
+	go hs.Serve(l)
+	defer hs.Close()
+
+	// a new client sends the requests left by the first one
+	responses := make(chan string, 1)
+	client = NewClient("http://"+addr, WithOfflineQueue(OfflineQueue{
+		Size:          10,
+		Store:         store,
+		RetryInterval: 10 * time.Millisecond,
+		OnResponse: func(method string, resp *Response) {
+			var result string
+			resp.Decode(&result)
+			if !sameID(resp.ID(), queued.ID) {
+				result = fmt.Sprintf("response to %v instead of %v", resp.ID(), queued.ID)
+			}
+			responses <- result
+		},
+	}))
+	defer client.Close()
+	select {
+	case got := <-responses:
+		if got != "logged b" {
+			t.Errorf("got response %q, want %q", got, "logged b")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued call not sent")
+	}
+	for i := 0; i < 100 && client.offline.len() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(logged, want) {
+		t.Errorf("got requests %v, want %v", logged, want)
+	}
+	if pending, _ := store.Pending(ctx); len(pending) != 0 {
+		t.Errorf("%v requests left in the store", len(pending))
+	}
+}
+
+func TestOfflineQueueFirstCall(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	// the call finds the server unreachable, nothing is queued yet
+	client := NewClient("http://"+addr, WithOfflineQueue(OfflineQueue{Size: 10, RetryInterval: time.Hour}))
+	defer client.Close()
+	_, err = client.Call(context.Background(), "log", "a")
+	var queued *QueuedError
+	if !errors.Is(err, ErrQueued) || !errors.As(err, &queued) {
+		t.Fatalf("got error %v, want ErrQueued", err)
+	}
+	if n := client.offline.len(); n != 1 {
+		t.Errorf("%v requests queued, want 1", n)
+	}
+}
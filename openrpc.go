@@ -0,0 +1,241 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// OpenRPCVersion is the version of the OpenRPC specification of the
+// documents generated by Server.OpenRPC.
+const OpenRPCVersion = "1.2.6"
+
+// OpenRPCDocument is an OpenRPC document describing the methods of a server.
+type OpenRPCDocument struct {
+	OpenRPC string          `json:"openrpc"`
+	Info    OpenRPCInfo     `json:"info"`
+	Methods []OpenRPCMethod `json:"methods"`
+}
+
+// OpenRPCInfo is the metadata of the API.
+type OpenRPCInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenRPCMethod describes a method.
+type OpenRPCMethod struct {
+	Name           string           `json:"name"`
+	ParamStructure string           `json:"paramStructure,omitempty"`
+	Params         []OpenRPCContent `json:"params"`
+	Result         *OpenRPCContent  `json:"result,omitempty"`
+	Errors         []OpenRPCError   `json:"errors,omitempty"`
+	Deprecated     bool             `json:"deprecated,omitempty"`
+}
+
+// OpenRPCContent describes a param or a result.
+type OpenRPCContent struct {
+	Name     string                 `json:"name"`
+	Required bool                   `json:"required,omitempty"`
+	Schema   map[string]interface{} `json:"schema"`
+}
+
+// OpenRPCError describes an application error, see Server.RegisterError.
+type OpenRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// OpenRPC returns the OpenRPC document of the methods registered in s. The
+// params of methods taking a struct are described by name, those of methods
+// taking a scalar, such as an int, as a single positional param, see
+// isScalarParams. Other params, such as slices or maps, are described as a
+// single param holding the params themselves, with no param structure. The
+// registered error codes are listed on every method.
+func (s *Server) OpenRPC(info OpenRPCInfo) *OpenRPCDocument {
+	var errs []OpenRPCError
+	for _, e := range s.ErrorCodes() {
+		errs = append(errs, OpenRPCError{e.Code, e.Description})
+	}
+	doc := &OpenRPCDocument{OpenRPC: OpenRPCVersion, Info: info, Methods: []OpenRPCMethod{}}
+	for _, m := range s.Methods() {
+		v, _ := s.handler.Load(m.Name)
+		h := v.(handlerType)
+		method := OpenRPCMethod{Name: m.Name, Params: openRPCParams(h.ptype), Errors: errs, Deprecated: m.Deprecation != nil}
+		if p := h.ptype; p != nil {
+			switch {
+			case h.scalar:
+				method.ParamStructure = "by-position"
+			case indirect(p).Kind() == reflect.Struct && !isOpaqueStruct(indirect(p)):
+				method.ParamStructure = "by-name"
+			}
+		}
+		rtype := h.rtype
+		if h.stream {
+			rtype = reflect.SliceOf(rtype.Elem())
+		}
+		method.Result = &OpenRPCContent{Name: "result", Schema: jsonSchema(rtype, map[reflect.Type]bool{})}
+		doc.Methods = append(doc.Methods, method)
+	}
+	return doc
+}
+
+func openRPCParams(t reflect.Type) []OpenRPCContent {
+	params := []OpenRPCContent{}
+	if t == nil {
+		return params
+	}
+	st := indirect(t)
+	if st.Kind() != reflect.Struct || isOpaqueStruct(st) {
+		return append(params, OpenRPCContent{Name: "params", Required: true, Schema: jsonSchema(t, map[reflect.Type]bool{})})
+	}
+	for _, f := range jsonFields(st) {
+		params = append(params, OpenRPCContent{
+			Name:     f.wire,
+			Required: f.required,
+			Schema:   jsonSchema(f.typ, map[reflect.Type]bool{st: true}),
+		})
+	}
+	return params
+}
+
+var (
+	typeOfTime       = reflect.TypeOf(time.Time{})
+	typeOfRawMessage = reflect.TypeOf(json.RawMessage{})
+	typeOfMarshaler  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isOpaqueStruct reports whether t is a struct not encoded field by field.
+func isOpaqueStruct(t reflect.Type) bool {
+	return t == typeOfTime || t.Implements(typeOfMarshaler) || reflect.PtrTo(t).Implements(typeOfMarshaler)
+}
+
+// jsonSchema returns the JSON schema of the encoding of t, seen holding the
+// structs being described to stop on recursive types.
+func jsonSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	t = indirect(t)
+	switch {
+	case t == typeOfTime:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == typeOfRawMessage, t.Implements(typeOfMarshaler), reflect.PtrTo(t).Implements(typeOfMarshaler):
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		props := map[string]interface{}{}
+		var required []string
+		for _, f := range jsonFields(t) {
+			props[f.wire] = jsonSchema(f.typ, seen)
+			if f.required {
+				required = append(required, f.wire)
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	// interfaces and anything else can hold any value
+	return map[string]interface{}{}
+}
+
+// jsonField is a field of a struct as encoded by encoding/json.
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	required bool
+	// wire is the name of the field in params and results, the one of its
+	// jsonrpc tag if any.
+	wire string
+	// quoted reports whether the field is encoded as a string, with the
+	// json tag option "string".
+	quoted bool
+	// squashed reports whether the field is a squashed struct, left nested
+	// by nestedJSONFields.
+	squashed bool
+}
+
+// jsonFields returns the fields of the struct t as encoded by encoding/json,
+// the fields of embedded and squashed structs being promoted.
+func jsonFields(t reflect.Type) []jsonField {
+	return collectJSONFields(t, true)
+}
+
+// nestedJSONFields is jsonFields leaving squashed structs nested, as params
+// are once unsquashed, see unsquash.
+func nestedJSONFields(t reflect.Type) []jsonField {
+	return collectJSONFields(t, false)
+}
+
+func collectJSONFields(t reflect.Type, squash bool) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		if f.Anonymous && opts[0] == "" && indirect(f.Type).Kind() == reflect.Struct || squash && isSquashed(f) {
+			fields = append(fields, collectJSONFields(indirect(f.Type), squash)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if opts[0] != "" {
+			name = opts[0]
+		}
+		required := f.Type.Kind() != reflect.Ptr
+		quoted := false
+		for _, o := range opts[1:] {
+			switch o {
+			case "omitempty":
+				required = false
+			case "string":
+				quoted = true
+			}
+		}
+		wire := name
+		if n, _ := jsonrpcTag(f); n != "" {
+			wire = n
+		}
+		fields = append(fields, jsonField{name, f.Type, required, wire, quoted, isSquashed(f)})
+	}
+	return fields
+}
@@ -0,0 +1,85 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Order struct {
+	ID      int       `json:"id"`
+	Items   []string  `json:"items"`
+	Note    *string   `json:"note"`
+	Created time.Time `json:"created,omitempty"`
+	Parent  *Order    `json:"parent,omitempty"`
+}
+
+func TestOpenRPC(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("order", func(ctx context.Context, o Order) (bool, error) { return true, nil })
+	server.HandleFunc("double", func(ctx context.Context, n int) (int, error) { return 2 * n, nil })
+	server.HandleFunc("random", random)
+	server.HandleFunc("sum", func(ctx context.Context, n []int) (int, error) { return 0, nil })
+	server.RegisterError(42, "Not enough credit")
+
+	b, err := json.Marshal(server.OpenRPC(OpenRPCInfo{Title: "Shop", Version: "1.0.0"}))
+	if err != nil {
+		t.Fatalf("marshaling document: %v", err)
+	}
+	want := `{"openrpc":"1.2.6","info":{"title":"Shop","version":"1.0.0"},"methods":[` +
+		`{"name":"double","paramStructure":"by-position","params":[{"name":"params","required":true,"schema":{"type":"integer"}}],` +
+		`"result":{"name":"result","schema":{"type":"integer"}},"errors":[{"code":42,"message":"Not enough credit"}]},` +
+		`{"name":"order","paramStructure":"by-name","params":[` +
+		`{"name":"id","required":true,"schema":{"type":"integer"}},` +
+		`{"name":"items","required":true,"schema":{"items":{"type":"string"},"type":"array"}},` +
+		`{"name":"note","schema":{"type":"string"}},` +
+		`{"name":"created","schema":{"format":"date-time","type":"string"}},` +
+		`{"name":"parent","schema":{"type":"object"}}],` +
+		`"result":{"name":"result","schema":{"type":"boolean"}},"errors":[{"code":42,"message":"Not enough credit"}]},` +
+		`{"name":"random","params":[],"result":{"name":"result","schema":{"properties":{"C":{"type":"integer"}},"required":["C"],"type":"object"}},` +
+		`"errors":[{"code":42,"message":"Not enough credit"}]},` +
+		`{"name":"sum","params":[{"name":"params","required":true,"schema":{"items":{"type":"integer"},"type":"array"}}],` +
+		`"result":{"name":"result","schema":{"type":"integer"}},"errors":[{"code":42,"message":"Not enough credit"}]}]}`
+	if string(b) != want {
+		t.Errorf("invalid document:\ngot: %s\nwant: %s", b, want)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	docs := server.Docs(DocsConfig{
+		Info:      OpenRPCInfo{Title: "Calculator", Version: "2.1.0"},
+		Authorize: func(r *http.Request) bool { return r.Header.Get("Authorization") == "secret" },
+	})
+
+	get := func(url, auth string) (int, string) {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Authorization", auth)
+		rw := httptest.NewRecorder()
+		docs.ServeHTTP(rw, r)
+		b, _ := ioutil.ReadAll(rw.Body)
+		return rw.Code, string(b)
+	}
+
+	if code, _ := get("/docs", ""); code != http.StatusUnauthorized {
+		t.Errorf("unauthorized request:\ngot: %v\nwant: 401", code)
+	}
+	code, body := get("/docs", "secret")
+	if code != http.StatusOK {
+		t.Fatalf("invalid status: %v", code)
+	}
+	for _, want := range []string{"Calculator <small>2.1.0</small>", `<section id="sum">`, `{&#34;A&#34;:0,&#34;B&#34;:0}`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("docs don't contain %v:\n%s", want, body)
+		}
+	}
+	if _, body := get("/docs?format=json", "secret"); !strings.HasPrefix(body, `{"openrpc":"1.2.6"`) {
+		t.Errorf("invalid JSON document: %s", body)
+	}
+}
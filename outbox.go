@@ -0,0 +1,228 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultOutboxRetry is the default of the retry interval of an Outbox.
+const DefaultOutboxRetry = 30 * time.Second
+
+// OutboxStore persists the events of an Outbox until their consumer
+// acknowledges them.
+type OutboxStore interface {
+	// Add stores ev for consumer.
+	Add(ctx context.Context, consumer string, ev Event) error
+	// Ack removes the event id of consumer, unknown ids are ignored.
+	Ack(ctx context.Context, consumer string, id uint64) error
+	// Pending returns the events of consumer not acknowledged, by id.
+	Pending(ctx context.Context, consumer string) ([]Event, error)
+}
+
+// Outbox delivers events to named consumers at least once, for applications
+// where a dropped event is unacceptable, unlike Broker whose subscriptions
+// only get the events published while they are connected. Events are kept in
+// a store until the consumer acknowledges them, and sent again to the
+// consumer's streams every retry interval until then. Consumers must
+// tolerate duplicates, which the ids of events let them detect.
+//
+//	server.HandleFunc("events", func(ctx context.Context) (<-chan jsonrpc.Event, error) {
+//		return outbox.Stream(ctx, jsonrpc.GetPrincipal(ctx).Subject)
+//	})
+//	server.HandleFunc("ack", func(ctx context.Context, ids []uint64) (bool, error) {
+//		return true, outbox.Ack(ctx, jsonrpc.GetPrincipal(ctx).Subject, ids...)
+//	})
+type Outbox struct {
+	store OutboxStore
+	retry time.Duration
+
+	mu      sync.Mutex
+	lastID  uint64
+	streams map[string]map[chan struct{}]struct{} // wakeups by consumer
+}
+
+// NewOutbox returns an Outbox keeping events in store and sending them again
+// every retry interval until acknowledged, DefaultOutboxRetry if zero.
+func NewOutbox(store OutboxStore, retry time.Duration) *Outbox {
+	return &Outbox{
+		store:   store,
+		retry:   durationOr(retry, DefaultOutboxRetry),
+		streams: make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// Send stores an event for consumer with topic and data, and delivers it to
+// the streams of consumer. It returns the id of the event once it is stored,
+// which stays below 2^53.
+// Data must be marshalable to JSON, and unmarshalable if the store persists
+// events.
+func (o *Outbox) Send(ctx context.Context, consumer, topic string, data interface{}) (uint64, error) {
+	o.mu.Lock()
+	// ids derive from the clock so that they keep increasing across
+	// restarts, in microseconds so that they stay below 2^53, the largest
+	// integer JavaScript clients decode exactly
+	id := uint64(time.Now().UnixNano() / int64(time.Microsecond))
+	if id <= o.lastID {
+		id = o.lastID + 1
+	}
+	o.lastID = id
+	o.mu.Unlock()
+
+	if err := o.store.Add(ctx, consumer, Event{ID: id, Topic: topic, Data: data}); err != nil {
+		return 0, fmt.Errorf("jsonrpc: storing event: %w", err)
+	}
+	o.wake(consumer)
+	return id, nil
+}
+
+// Ack acknowledges the events ids of consumer, which are not sent anymore.
+func (o *Outbox) Ack(ctx context.Context, consumer string, ids ...uint64) error {
+	for _, id := range ids {
+		if err := o.store.Ack(ctx, consumer, id); err != nil {
+			return fmt.Errorf("jsonrpc: acknowledging event: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stream returns the events of consumer until ctx is done, to be returned by
+// a streaming handler: the events pending, including those sent to previous
+// streams and not acknowledged, then the events sent. Events not
+// acknowledged after the retry interval are sent again.
+func (o *Outbox) Stream(ctx context.Context, consumer string) (<-chan Event, error) {
+	if consumer == "" {
+		return nil, errors.New("jsonrpc: outbox stream without consumer")
+	}
+	wakeup := make(chan struct{}, 1)
+	wakeup <- struct{}{}
+	o.mu.Lock()
+	if o.streams[consumer] == nil {
+		o.streams[consumer] = make(map[chan struct{}]struct{})
+	}
+	o.streams[consumer][wakeup] = struct{}{}
+	o.mu.Unlock()
+
+	c := make(chan Event)
+	go func() {
+		defer close(c)
+		defer o.remove(consumer, wakeup)
+		ticker := time.NewTicker(o.retry)
+		defer ticker.Stop()
+		sent := make(map[uint64]time.Time)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wakeup:
+			case <-ticker.C:
+			}
+			if !o.deliver(ctx, consumer, c, sent) {
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+// deliver sends the pending events of consumer to c, those not sent yet or
+// sent more than a retry interval ago. It reports false if ctx is done.
+func (o *Outbox) deliver(ctx context.Context, consumer string, c chan<- Event, sent map[uint64]time.Time) bool {
+	pending, err := o.store.Pending(ctx, consumer)
+	if err != nil {
+		log.Printf("jsonrpc: loading events of %v: %v", consumer, err)
+		return ctx.Err() == nil
+	}
+	now := time.Now()
+	kept := make(map[uint64]bool, len(pending))
+	for _, ev := range pending {
+		kept[ev.ID] = true
+		if at, ok := sent[ev.ID]; ok && now.Sub(at) < o.retry {
+			continue
+		}
+		select {
+		case c <- ev:
+			sent[ev.ID] = now
+		case <-ctx.Done():
+			return false
+		}
+	}
+	// forget the events acknowledged
+	for id := range sent {
+		if !kept[id] {
+			delete(sent, id)
+		}
+	}
+	return true
+}
+
+func (o *Outbox) wake(consumer string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for wakeup := range o.streams[consumer] {
+		select {
+		case wakeup <- struct{}{}:
+		default: // a delivery is already due
+		}
+	}
+}
+
+func (o *Outbox) remove(consumer string, wakeup chan struct{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.streams[consumer], wakeup)
+	if len(o.streams[consumer]) == 0 {
+		delete(o.streams, consumer)
+	}
+}
+
+// MemoryOutboxStore is an OutboxStore keeping events in memory. They are
+// lost on restart, it is meant for tests and applications only guarding
+// against the disconnections of their consumers.
+type MemoryOutboxStore struct {
+	mu     sync.Mutex
+	events map[string]map[uint64]Event
+}
+
+// NewMemoryOutboxStore returns an empty MemoryOutboxStore.
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{events: make(map[string]map[uint64]Event)}
+}
+
+// Add implements OutboxStore.
+func (m *MemoryOutboxStore) Add(ctx context.Context, consumer string, ev Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.events[consumer] == nil {
+		m.events[consumer] = make(map[uint64]Event)
+	}
+	m.events[consumer][ev.ID] = ev
+	return nil
+}
+
+// Ack implements OutboxStore.
+func (m *MemoryOutboxStore) Ack(ctx context.Context, consumer string, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.events[consumer], id)
+	if len(m.events[consumer]) == 0 {
+		delete(m.events, consumer)
+	}
+	return nil
+}
+
+// Pending implements OutboxStore.
+func (m *MemoryOutboxStore) Pending(ctx context.Context, consumer string) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := make([]Event, 0, len(m.events[consumer]))
+	for _, ev := range m.events[consumer] {
+		pending = append(pending, ev)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending, nil
+}
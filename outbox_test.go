@@ -0,0 +1,64 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	outbox := NewOutbox(NewMemoryOutboxStore(), 50*time.Millisecond)
+	ctx := context.Background()
+	next := func(c <-chan Event) Event {
+		select {
+		case ev := <-c:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatalf("no event delivered")
+			return Event{}
+		}
+	}
+
+	id1, err := outbox.Send(ctx, "alice", "orders", "o1")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	sctx, cancel := context.WithCancel(ctx)
+	c, err := outbox.Stream(sctx, "alice")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if ev := next(c); ev.ID != id1 || ev.Data != "o1" {
+		t.Errorf("invalid pending event: %+v", ev)
+	}
+	id2, _ := outbox.Send(ctx, "alice", "orders", "o2")
+	outbox.Send(ctx, "bob", "orders", "b1")
+	if ev := next(c); ev.ID != id2 || id2 <= id1 || id2 >= 1<<53 {
+		t.Errorf("invalid event:\ngot: %+v\nwant id %v > %v, below 2^53", ev, id2, id1)
+	}
+
+	// the event not acknowledged is sent again
+	outbox.Ack(ctx, "alice", id2)
+	if ev := next(c); ev.ID != id1 {
+		t.Errorf("invalid retried event:\ngot: %v\nwant: %v", ev.ID, id1)
+	}
+	cancel()
+	for range c {
+	}
+
+	// and to the next stream of the consumer
+	c, _ = outbox.Stream(ctx, "alice")
+	if ev := next(c); ev.ID != id1 {
+		t.Errorf("invalid event after reconnecting:\ngot: %v\nwant: %v", ev.ID, id1)
+	}
+	outbox.Ack(ctx, "alice", id1)
+	select {
+	case ev := <-c:
+		t.Errorf("acknowledged event sent again: %+v", ev)
+	case <-time.After(120 * time.Millisecond):
+	}
+
+	if _, err := outbox.Stream(ctx, ""); err == nil {
+		t.Errorf("stream without consumer")
+	}
+}
@@ -0,0 +1,225 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamsErrorData is the data of the Invalid params errors answering params
+// which don't decode into the params of the method, locating the value at
+// fault.
+type ParamsErrorData struct {
+	// Pointer is the JSON pointer (RFC 6901) to the value within the
+	// params, such as "/items/0/price", empty for the params themselves.
+	Pointer string `json:"pointer"`
+	// Expected is the JSON schema type expected, such as "integer".
+	Expected string `json:"expected,omitempty"`
+	// Got is the value found.
+	Got json.RawMessage `json:"got,omitempty"`
+}
+
+// paramsError is an error decoding params into a value of type t.
+type paramsError struct {
+	err    error
+	params json.RawMessage
+	t      reflect.Type
+}
+
+func (e *paramsError) Error() string {
+	return errServerInvalidParams.Error() + ": " + e.err.Error()
+}
+
+func (e *paramsError) Unwrap() error {
+	return e.err
+}
+
+func (e *paramsError) Is(target error) bool {
+	return target == errServerInvalidParams
+}
+
+// decodeError returns the error of decoding params into a value of type t,
+// errServerInvalidParams if err isn't a decoding error.
+func decodeError(params json.RawMessage, t reflect.Type, err error) error {
+	if err == nil || err == errServerInvalidParams {
+		return errServerInvalidParams
+	}
+	return &paramsError{err: err, params: params, t: t}
+}
+
+// invalidParamsError returns the Invalid params error answering err, an
+// error wrapping errServerInvalidParams. Its data locates the value at fault
+// if the params don't decode because of the type of a value.
+func invalidParamsError(err error) *Error {
+	var pe *paramsError
+	var te *json.UnmarshalTypeError
+	if !errors.As(err, &pe) || !errors.As(pe.err, &te) {
+		return ErrInvalidParams
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(pe.params))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return ErrInvalidParams
+	}
+	data, ok := mismatch(v, pe.t, "")
+	if !ok {
+		// the type of the value is fine, its content isn't
+		data = &ParamsErrorData{Pointer: "/" + strings.Replace(te.Field, ".", "/", -1)}
+		if te.Field == "" {
+			data.Pointer = ""
+		}
+		data.Expected, _ = jsonSchema(te.Type, map[reflect.Type]bool{})["type"].(string)
+	}
+	data.Pointer = wirePointer(pe.t, data.Pointer)
+	return &Error{Code: CodeInvalidParams, Message: ErrInvalidParams.Message, Data: data}
+}
+
+// mismatch returns the location of the first value of v whose JSON type
+// doesn't match the Go type t, v being at pointer.
+func mismatch(v interface{}, t reflect.Type, pointer string) (*ParamsErrorData, bool) {
+	if v == nil {
+		return nil, false
+	}
+	t = indirect(t)
+	if reflect.PtrTo(t).Implements(typeOfUnmarshaler) {
+		return nil, false
+	}
+	ok := true
+	switch t.Kind() {
+	case reflect.Bool:
+		_, ok = v.(bool)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, isNumber := v.(json.Number)
+		_, err := strconv.ParseInt(string(n), 10, t.Bits())
+		ok = isNumber && err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, isNumber := v.(json.Number)
+		_, err := strconv.ParseUint(string(n), 10, t.Bits())
+		ok = isNumber && err == nil
+	case reflect.Float32, reflect.Float64:
+		_, ok = v.(json.Number)
+	case reflect.String:
+		_, ok = v.(string)
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			_, ok = v.(string)
+			break
+		}
+		var a []interface{}
+		if a, ok = v.([]interface{}); ok {
+			for i, e := range a {
+				if data, found := mismatch(e, t.Elem(), pointer+"/"+strconv.Itoa(i)); found {
+					return data, true
+				}
+			}
+		}
+	case reflect.Map:
+		var m map[string]interface{}
+		if m, ok = v.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if data, found := mismatch(m[k], t.Elem(), pointer+"/"+escapePointer(k)); found {
+					return data, true
+				}
+			}
+		}
+	case reflect.Struct:
+		var m map[string]interface{}
+		if m, ok = v.(map[string]interface{}); ok {
+			// params are unsquashed, see unsquash
+			for _, f := range nestedJSONFields(t) {
+				if f.quoted {
+					continue
+				}
+				for k, e := range m {
+					if !strings.EqualFold(k, f.name) {
+						continue
+					}
+					if data, found := mismatch(e, f.typ, pointer+"/"+escapePointer(k)); found {
+						return data, true
+					}
+				}
+			}
+		}
+	}
+	if ok {
+		return nil, false
+	}
+	data := &ParamsErrorData{Pointer: pointer}
+	data.Expected, _ = jsonSchema(t, map[reflect.Type]bool{})["type"].(string)
+	data.Got, _ = json.Marshal(v)
+	return data, true
+}
+
+var (
+	typeOfString = reflect.TypeOf("")
+	typeOfInt    = reflect.TypeOf(0)
+)
+
+// wirePointer maps pointer, into params of type t once renamed and
+// unsquashed, see rewriteParams, to the params as sent: the tokens of
+// squashed fields are dropped and fields get their wire names.
+func wirePointer(t reflect.Type, pointer string) string {
+	if pointer == "" {
+		return ""
+	}
+	var tokens []string
+	for _, tok := range strings.Split(pointer[1:], "/") {
+		if t == nil {
+			tokens = append(tokens, tok)
+			continue
+		}
+		t = indirect(t)
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			tokens = append(tokens, tok)
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+			if f, ok := nestedJSONField(t, unescapePointer(tok)); ok {
+				if !f.squashed {
+					tokens = append(tokens, escapePointer(f.wire))
+				}
+				t = f.typ
+				continue
+			}
+		}
+		// not a field of t, left as is
+		tokens = append(tokens, tok)
+		t = nil
+	}
+	if len(tokens) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(tokens, "/")
+}
+
+// nestedJSONField returns the field of the struct t named name, see
+// nestedJSONFields.
+func nestedJSONField(t reflect.Type, name string) (jsonField, bool) {
+	for _, f := range nestedJSONFields(t) {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// escapePointer escapes key as a reference token of a JSON pointer.
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// unescapePointer is the reverse of escapePointer.
+func unescapePointer(tok string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+}
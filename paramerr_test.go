@@ -0,0 +1,75 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+)
+
+type Shipment struct {
+	Lines []struct {
+		SKU      string `json:"sku"`
+		Quantity uint8  `json:"quantity"`
+	} `json:"lines"`
+	Labels map[string]int `json:"labels,omitempty"`
+}
+
+func TestParamsErrorData(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("ship", func(ctx context.Context, s Shipment) (int, error) {
+		return len(s.Lines), nil
+	})
+	server.HandleFunc("echo", func(ctx context.Context, s string) (string, error) {
+		return s, nil
+	})
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"ship","params":{"lines":[{"sku":"a","quantity":1},{"sku":"b","quantity":300}]}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"/lines/1/quantity","expected":"integer","got":300}}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"ship","params":{"lines":[{"sku":7}]}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"/lines/0/sku","expected":"string","got":7}}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"ship","params":{"lines":[],"labels":{"a/b":"x"}}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"/labels/a~1b","expected":"integer","got":"x"}}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"ship","params":{"lines":{}}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"/lines","expected":"array","got":{}}}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"a":1}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"","expected":"string","got":{"a":1}}}}`},
+		// null params carry no data
+		{`{"jsonrpc":"2.0","id":1,"method":"echo","params":null}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
+
+func TestParamsErrorDataWireNames(t *testing.T) {
+	type Window struct {
+		Limit int `json:"limit"`
+	}
+	type Lookup struct {
+		UserID int    `jsonrpc:"user_id"`
+		Page   Window `json:"page" jsonrpc:",squash"`
+	}
+	server := NewServer()
+	server.HandleFunc("lookup", func(ctx context.Context, l Lookup) (int, error) {
+		return l.UserID, nil
+	})
+
+	tests := []struct {
+		params, want string
+	}{
+		{`{"user_id":"x"}`, `{"pointer":"/user_id","expected":"integer","got":"x"}`},
+		{`{"user_id":1,"limit":"ten"}`, `{"pointer":"/limit","expected":"integer","got":"ten"}`},
+	}
+	for _, test := range tests {
+		req := `{"jsonrpc":"2.0","id":1,"method":"lookup","params":` + test.params + `}`
+		want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":` + test.want + `}}`
+		if got := string(server.ServeMessage(context.Background(), []byte(req))); got != want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.params, got, want)
+		}
+	}
+}
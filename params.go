@@ -0,0 +1,97 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// ParamsRewriter rewrites the raw params of a request for method before they
+// are decoded into the params of its handler, to inject a tenant id taken
+// from ctx, map legacy field names or strip fields for instance. It returns
+// params unchanged for the methods it doesn't rewrite. An error which isn't
+// an *Error answers the request with ErrInvalidParams.
+type ParamsRewriter func(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
+
+// rewriteParams passes the params of req through s.ParamsRewriters, in order,
+// unwraps the single positional param of handlers taking a scalar, see
+// isScalarParams, then converts them to the encoding of encoding/json:
+// fields are renamed, squashed fields are nested, times are decoded, and
+// mismatching values are coerced, see Server.FieldMatching, Server.TimeFormat
+// and Server.LenientParams. The values of types with a codec are decoded by
+// the handler, see Server.RegisterCodec.
+func (s *Server) rewriteParams(ctx context.Context, req *request, h *handlerType) *Error {
+	for _, rewrite := range s.ParamsRewriters {
+		params, err := rewrite(ctx, req.Method, req.Params)
+		if err != nil {
+			if rpcErr, ok := AsError(err); ok {
+				return rpcErr
+			}
+			return ErrInvalidParamsf("%v", err)
+		}
+		req.Params = params
+	}
+	if h.scalar {
+		req.Params = unwrapPositional(req.Params)
+	}
+	if s.convertsFieldNames(h.paramNames) {
+		params, err := s.decodeFieldNames(req.Params, h.paramNames)
+		if err != nil {
+			return ErrInvalidParamsf("%v", err)
+		}
+		req.Params = params
+	}
+	if len(h.paramSquash) > 0 {
+		params, err := unsquash(req.Params, h.paramSquash)
+		if err != nil {
+			return ErrInvalidParamsf("%v", err)
+		}
+		req.Params = params
+	}
+	if s.convertsTimes(h.paramTimes) {
+		params, err := s.decodeTimes(req.Params, h.paramTimes)
+		if err != nil {
+			return ErrInvalidParamsf("%v", err)
+		}
+		req.Params = params
+	}
+	if s.LenientParams && h.ptype != nil {
+		params, err := coerceParams(req.Params, h.ptype, h.paramCodecs)
+		if err != nil {
+			return ErrInvalidParamsf("%v", err)
+		}
+		req.Params = params
+	}
+	return nil
+}
+
+// isScalarParams reports whether params of type t are a single value, such as
+// a string or a time.Time, rather than an object or an array. Such params may
+// also be sent as an array holding the value, the by-position form of
+// JSON-RPC.
+func isScalarParams(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return t == typeOfTime
+}
+
+// unwrapPositional returns the value of params if they are an array holding
+// a single value, params otherwise.
+func unwrapPositional(params json.RawMessage) json.RawMessage {
+	if firstByte(params) != '[' {
+		return params
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(params, &values); err != nil || len(values) != 1 {
+		return params
+	}
+	return values[0]
+}
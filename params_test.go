@@ -0,0 +1,84 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestParamsRewriters(t *testing.T) {
+	type Query struct {
+		Tenant string `json:"tenant"`
+		Name   string `json:"name"`
+	}
+	server := NewServer()
+	server.HandleFunc("find", func(ctx context.Context, q Query) (Query, error) {
+		return q, nil
+	})
+	injectTenant := func(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		if !ok {
+			return nil, errors.New("no tenant")
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(params, &m); err != nil {
+			return nil, err
+		}
+		m["tenant"] = tenant
+		return json.Marshal(m)
+	}
+	renameLegacy := func(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+		var m map[string]interface{}
+		if err := json.Unmarshal(params, &m); err != nil {
+			return nil, err
+		}
+		if v, ok := m["username"]; ok {
+			m["name"] = v
+			delete(m, "username")
+		}
+		return json.Marshal(m)
+	}
+	server.ParamsRewriters = []ParamsRewriter{renameLegacy, injectTenant}
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	tests := []struct {
+		ctx       context.Context
+		req, want string
+	}{
+		{ctx, `{"jsonrpc":"2.0","id":1,"method":"find","params":{"name":"gopher","tenant":"evil"}}`, `{"jsonrpc":"2.0","id":1,"result":{"tenant":"acme","name":"gopher"}}`},
+		{ctx, `{"jsonrpc":"2.0","id":2,"method":"find","params":{"username":"gopher"}}`, `{"jsonrpc":"2.0","id":2,"result":{"tenant":"acme","name":"gopher"}}`},
+		{context.Background(), `{"jsonrpc":"2.0","id":3,"method":"find","params":{"name":"gopher"}}`, `{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"Invalid params","data":"no tenant"}}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(test.ctx, []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
+
+func TestScalarParamsByPosition(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("double", func(ctx context.Context, n int) (int, error) { return 2 * n, nil })
+	server.HandleFunc("upper", func(ctx context.Context, s *string) (string, error) { return strings.ToUpper(*s), nil })
+	server.HandleFunc("len", func(ctx context.Context, s []int) (int, error) { return len(s), nil })
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"double","params":5}`, `{"jsonrpc":"2.0","id":1,"result":10}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"double","params":[5]}`, `{"jsonrpc":"2.0","id":1,"result":10}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"double","params":[5,6]}`, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"","expected":"integer","got":[5,6]}}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"upper","params":["go"]}`, `{"jsonrpc":"2.0","id":1,"result":"GO"}`},
+		// slices are the positional params themselves
+		{`{"jsonrpc":"2.0","id":1,"method":"len","params":[5]}`, `{"jsonrpc":"2.0","id":1,"result":1}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package jsonrpc
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// Playground returns a handler serving an HTML page to try the methods of s
+// from a browser: it lists the methods, lets developers edit the params and
+// shows the raw requests and responses. Requests are posted to endpoint, the
+// path s is served on. It is meant for development, it exposes the methods
+// and their types.
+func (s *Server) Playground(endpoint string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := struct {
+			Endpoint string
+			Methods  []MethodInfo
+		}{endpoint, s.Methods()}
+		if err := playgroundTemplate.Execute(rw, data); err != nil {
+			log.Printf("jsonrpc: rendering playground: %v", err)
+		}
+	})
+}
+
+var playgroundTemplate = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>JSON-RPC playground</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+textarea, pre { width: 100%; box-sizing: border-box; font-family: monospace; }
+textarea { height: 10em; }
+pre { background: #f4f4f4; padding: 1em; min-height: 3em; white-space: pre-wrap; }
+.types { color: #666; }
+</style>
+</head>
+<body>
+<h1>JSON-RPC playground</h1>
+<p>
+<select id="method">
+{{range .Methods}}<option value="{{.Name}}">{{.Name}}</option>
+{{end}}</select>
+<label><input type="checkbox" id="notification"> notification</label>
+<span class="types" id="types"></span>
+</p>
+<textarea id="params"></textarea>
+<p><button id="send">Send</button></p>
+<h2>Request</h2>
+<pre id="request"></pre>
+<h2>Response</h2>
+<pre id="response"></pre>
+<script>
+const endpoint = {{.Endpoint}};
+const methods = {{.Methods}} || [];
+let nextID = 1;
+const $ = id => document.getElementById(id);
+
+function selectMethod() {
+	const m = methods.find(m => m.name === $("method").value);
+	if (!m) return;
+	$("types").textContent = "(" + (m.params || "") + ") " + m.result + (m.stream ? " stream" : "");
+	$("params").value = m.example ? JSON.stringify(m.example, null, 2) : "";
+}
+
+async function send() {
+	const req = {jsonrpc: "2.0", method: $("method").value};
+	if (!$("notification").checked) req.id = nextID++;
+	const params = $("params").value.trim();
+	if (params) {
+		try {
+			req.params = JSON.parse(params);
+		} catch (e) {
+			$("response").textContent = "invalid params: " + e.message;
+			return;
+		}
+	}
+	const body = JSON.stringify(req, null, 2);
+	$("request").textContent = body;
+	$("response").textContent = "...";
+	try {
+		const resp = await fetch(endpoint, {method: "POST", headers: {"Content-Type": "application/json"}, body: body});
+		const text = await resp.text();
+		try {
+			$("response").textContent = JSON.stringify(JSON.parse(text), null, 2);
+		} catch (e) {
+			$("response").textContent = resp.status + " " + (text || resp.statusText);
+		}
+	} catch (e) {
+		$("response").textContent = e.message;
+	}
+}
+
+$("method").addEventListener("change", selectMethod);
+$("send").addEventListener("click", send);
+selectMethod();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,44 @@
+package jsonrpc
+
+import "context"
+
+// Principal is the authenticated client of a request, set on the context by
+// an authentication middleware such as TokenIntrospection.
+type Principal struct {
+	// Subject identifies the client, such as a user id or a username.
+	Subject string
+	// Scopes are the permissions granted to the client.
+	Scopes []string
+	// Claims holds everything the identity provider returned about the
+	// client, if anything.
+	Claims map[string]interface{}
+}
+
+// HasScope reports whether p was granted scope. A nil Principal, that of
+// unauthenticated clients, has no scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p, for custom authentication
+// middleware and transports other than HTTP.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// GetPrincipal returns the authenticated client of the request of ctx, nil
+// if it isn't authenticated.
+func GetPrincipal(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
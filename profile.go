@@ -0,0 +1,78 @@
+package jsonrpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MethodProfile is the profile of the calls made to a method while
+// Server.Profiling was enabled.
+type MethodProfile struct {
+	Calls uint64
+	// Duration is the total wall time spent in the handler.
+	Duration time.Duration
+	// Allocs and AllocBytes are the heap objects and bytes allocated while
+	// the handler ran. They are sampled process wide, so allocations made
+	// concurrently by other goroutines are counted too: compare methods
+	// under similar load.
+	Allocs     uint64
+	AllocBytes uint64
+}
+
+// Profile returns the profile of every method called while s.Profiling was
+// enabled.
+func (s *Server) Profile() map[string]MethodProfile {
+	out := make(map[string]MethodProfile)
+	s.profiles.m.Range(func(k, v interface{}) bool {
+		p := v.(*methodProfile)
+		out[k.(string)] = MethodProfile{
+			Calls:      atomic.LoadUint64(&p.calls),
+			Duration:   time.Duration(atomic.LoadInt64(&p.duration)),
+			Allocs:     atomic.LoadUint64(&p.allocs),
+			AllocBytes: atomic.LoadUint64(&p.allocBytes),
+		}
+		return true
+	})
+	return out
+}
+
+type profiles struct {
+	m sync.Map
+}
+
+type methodProfile struct {
+	calls      uint64
+	duration   int64
+	allocs     uint64
+	allocBytes uint64
+}
+
+// profileSample is a snapshot of the clock and the allocation counters.
+type profileSample struct {
+	start              time.Time
+	allocs, allocBytes uint64
+}
+
+func startProfile() *profileSample {
+	p := &profileSample{}
+	p.allocs, p.allocBytes = readAllocs()
+	p.start = time.Now()
+	return p
+}
+
+// record adds the call started at p to the profile of method.
+func (ps *profiles) record(method string, p *profileSample) {
+	d := time.Since(p.start)
+	allocs, allocBytes := readAllocs()
+
+	v, ok := ps.m.Load(method)
+	if !ok {
+		v, _ = ps.m.LoadOrStore(method, &methodProfile{})
+	}
+	mp := v.(*methodProfile)
+	atomic.AddUint64(&mp.calls, 1)
+	atomic.AddInt64(&mp.duration, int64(d))
+	atomic.AddUint64(&mp.allocs, allocs-p.allocs)
+	atomic.AddUint64(&mp.allocBytes, allocBytes-p.allocBytes)
+}
@@ -0,0 +1,15 @@
+//go:build !go1.16
+// +build !go1.16
+
+package jsonrpc
+
+import "runtime"
+
+// readAllocs returns the number of heap objects and bytes allocated by the
+// process so far. Before go1.16 they are read from runtime.MemStats, which
+// stops the world: profiling is slower there.
+func readAllocs() (objects, bytes uint64) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Mallocs, ms.TotalAlloc
+}
@@ -0,0 +1,19 @@
+//go:build go1.16
+// +build go1.16
+
+package jsonrpc
+
+import "runtime/metrics"
+
+var profileMetrics = [2]string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+// readAllocs returns the number of heap objects and bytes allocated by the
+// process so far.
+func readAllocs() (objects, bytes uint64) {
+	samples := [2]metrics.Sample{{Name: profileMetrics[0]}, {Name: profileMetrics[1]}}
+	metrics.Read(samples[:])
+	if samples[0].Value.Kind() != metrics.KindUint64 || samples[1].Value.Kind() != metrics.KindUint64 {
+		return 0, 0
+	}
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
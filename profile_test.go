@@ -0,0 +1,45 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var sink []byte
+
+func TestProfile(t *testing.T) {
+	server := NewServer()
+	server.Profiling = true
+	server.HandleFunc("alloc", func(ctx context.Context) (int, error) {
+		for i := 0; i < 100; i++ {
+			sink = make([]byte, 1024)
+		}
+		time.Sleep(time.Millisecond)
+		return len(sink), nil
+	})
+	server.HandleFunc("sum", sum)
+
+	for i := 0; i < 3; i++ {
+		server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"alloc"}`))
+	}
+
+	profiles := server.Profile()
+	p, ok := profiles["alloc"]
+	if !ok {
+		t.Fatalf("alloc not profiled: %v", profiles)
+	}
+	if p.Calls != 3 {
+		t.Errorf("invalid number of calls: got %v, want 3", p.Calls)
+	}
+	if p.Duration < 3*time.Millisecond {
+		t.Errorf("invalid duration: got %v, want at least 3ms", p.Duration)
+	}
+	// the runtime counters are only approximately up to date
+	if p.Allocs < 200 || p.AllocBytes < 200*1024 {
+		t.Errorf("invalid allocations: got %v objects and %v bytes", p.Allocs, p.AllocBytes)
+	}
+	if _, ok := profiles["sum"]; ok {
+		t.Errorf("sum profiled without being called")
+	}
+}
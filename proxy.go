@@ -0,0 +1,89 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Bind turns the func fields of the struct pointed to by v into calls to the
+// methods of the same name, or the name in the `jsonrpc:"name"` tag of the
+// field. Fields must be funcs of the form
+//
+//	func(ctx context.Context[, params P]) (R, error)
+//
+// to call a method, or
+//
+//	func(ctx context.Context[, params P]) error
+//
+// to send a notification. Go can't implement interfaces at runtime, but a
+// bound struct gives type-safe calls without code generation:
+//
+//	var api struct {
+//		Sum func(ctx context.Context, args Args) (int, error) `jsonrpc:"sum"`
+//	}
+//	err := client.Bind(&api)
+//	n, err := api.Sum(ctx, Args{1, 2})
+func (c *Client) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("jsonrpc: bind: expected a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.PkgPath != "" || f.Type.Kind() != reflect.Func {
+			continue
+		}
+		method := f.Name
+		if tag := f.Tag.Get("jsonrpc"); tag != "" {
+			method = tag
+		}
+		fn, err := c.proxyFunc(method, f.Type)
+		if err != nil {
+			return fmt.Errorf("jsonrpc: bind %v: %v", f.Name, err)
+		}
+		rv.Field(i).Set(fn)
+	}
+	return nil
+}
+
+// proxyFunc returns a func of type ft calling method.
+func (c *Client) proxyFunc(method string, ft reflect.Type) (reflect.Value, error) {
+	if ft.NumIn() < 1 || ft.NumIn() > 2 || ft.In(0) != typeOfContext {
+		return reflect.Value{}, fmt.Errorf("expected func(context.Context[, params]), got %v", ft)
+	}
+	if ft.IsVariadic() {
+		return reflect.Value{}, fmt.Errorf("variadic funcs aren't supported")
+	}
+	notify := ft.NumOut() == 1
+	if ft.NumOut() < 1 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != typeOfError {
+		return reflect.Value{}, fmt.Errorf("expected (result, error) or error returns, got %v", ft)
+	}
+
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		ctx := args[0].Interface().(context.Context)
+		var params interface{}
+		if len(args) == 2 {
+			params = args[1].Interface()
+		}
+		if notify {
+			return []reflect.Value{errorValue(c.Notify(ctx, method, params))}
+		}
+
+		result := reflect.New(ft.Out(0))
+		resp, err := c.Call(ctx, method, params)
+		if err == nil {
+			err = resp.Decode(result.Interface())
+		}
+		return []reflect.Value{result.Elem(), errorValue(err)}
+	}), nil
+}
+
+// errorValue returns err as a reflect.Value of type error.
+func errorValue(err error) reflect.Value {
+	if err == nil {
+		return reflect.Zero(typeOfError)
+	}
+	return reflect.ValueOf(&err).Elem()
+}
@@ -0,0 +1,54 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	counter := &state{}
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+	server.HandleFunc("random", random)
+	server.HandleFunc("counter", counter.increaseCounter)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	var api struct {
+		Sum     func(ctx context.Context, args Args) (Reply, error) `jsonrpc:"sum"`
+		Random  func(ctx context.Context) (*Reply, error)           `jsonrpc:"random"`
+		Counter func(ctx context.Context, add int) error            `jsonrpc:"counter"`
+		Unknown func(ctx context.Context) (int, error)
+		name    string
+	}
+	client := NewClient(ts.URL)
+	if err := client.Bind(&api); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+
+	ctx := context.Background()
+	if r, err := api.Sum(ctx, Args{1, 2}); err != nil || r.C != 3 {
+		t.Errorf("Sum:\ngot: %v, %v\nwant: {3}", r, err)
+	}
+	if r, err := api.Random(ctx); err != nil || r.C != 33 {
+		t.Errorf("Random:\ngot: %v, %v\nwant: &{33}", r, err)
+	}
+	if err := api.Counter(ctx, 4); err != nil || counter.N != 4 {
+		t.Errorf("Counter:\ngot: %v, %v\nwant: 4", counter.N, err)
+	}
+	if _, err := api.Unknown(ctx); !errors.Is(err, ErrMethodNotFound) {
+		t.Errorf("Unknown:\ngot: %v\nwant: %v", err, ErrMethodNotFound)
+	}
+
+	var bad struct {
+		F func(n int) (int, error)
+	}
+	if err := client.Bind(&bad); err == nil {
+		t.Errorf("Bind: expected an error for a func without context")
+	}
+	if err := client.Bind(api); err == nil {
+		t.Errorf("Bind: expected an error for a struct value")
+	}
+}
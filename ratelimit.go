@@ -0,0 +1,237 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClientQueueFull is returned by clients with a rate limit when a request
+// can't be sent right away and the queue of waiting requests is full.
+var ErrClientQueueFull = errors.New("jsonrpc: client request queue full")
+
+// RateLimit bounds the requests sent by a client, see WithRateLimit. A zero
+// field means no limit.
+type RateLimit struct {
+	// PerSecond is the number of requests sent per second.
+	PerSecond float64
+	// Burst is the number of requests which may be sent at once after a
+	// pause, 1 if not set.
+	Burst int
+	// MaxConcurrent is the number of requests in flight, a request being
+	// in flight until its response has been read.
+	MaxConcurrent int
+	// Queue is the number of requests waiting for their turn. Requests
+	// beyond it fail with ErrClientQueueFull. With no queue, requests fail
+	// as soon as they can't be sent right away.
+	Queue int
+}
+
+// WithRateLimit bounds the requests sent by the client to the quotas of
+// the server, so that an application can't overwhelm a third-party provider.
+// Every HTTP request counts: batches count once, retries and hedged requests
+// count for each attempt. Requests wait in the queue until they can be sent
+// or their context is done.
+func WithRateLimit(l RateLimit) ClientOption {
+	return func(c *Client) {
+		if l.Burst <= 0 {
+			l.Burst = 1
+		}
+		c.limiter = &limiter{cfg: l, tokens: float64(l.Burst)}
+		if l.MaxConcurrent > 0 {
+			c.limiter.slots = make(chan struct{}, l.MaxConcurrent)
+		}
+	}
+}
+
+// limiter is a token bucket combined with a bound on concurrent requests.
+type limiter struct {
+	cfg    RateLimit
+	slots  chan struct{}
+	queued int64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// acquire waits for a request to be allowed to be sent, and returns the
+// function to call once its response has been read.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	if l.trySlot() {
+		if l.take() {
+			return l.release, nil
+		}
+		l.release()
+	}
+
+	if atomic.AddInt64(&l.queued, 1) > int64(l.cfg.Queue) {
+		atomic.AddInt64(&l.queued, -1)
+		return nil, ErrClientQueueFull
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if wait := l.reserve(); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.tokens++
+			l.mu.Unlock()
+			l.release()
+			return nil, ctx.Err()
+		}
+	}
+	return l.release, nil
+}
+
+// trySlot takes a concurrency slot if one is free.
+func (l *limiter) trySlot() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// take takes a token if one is available.
+func (l *limiter) take() bool {
+	if l.cfg.PerSecond <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// reserve takes a token, borrowed from the future if the bucket is empty,
+// and returns how long to wait before using it.
+func (l *limiter) reserve() time.Duration {
+	if l.cfg.PerSecond <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.cfg.PerSecond * float64(time.Second))
+}
+
+// refill adds the tokens earned since the last call, l.mu held.
+func (l *limiter) refill() {
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.cfg.PerSecond
+		if max := float64(l.cfg.Burst); l.tokens > max {
+			l.tokens = max
+		}
+	}
+	l.last = now
+}
+
+// releaseCloser calls release once the response body it wraps is closed.
+type releaseCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (rc *releaseCloser) Close() error {
+	err := rc.ReadCloser.Close()
+	rc.once.Do(rc.release)
+	return err
+}
+
+// ClientRateLimit bounds the requests each client sends to a server, see
+// Server.RateLimit. Clients are told apart by their IP address, see
+// IPFilter.ClientIP. A zero PerSecond means no limit.
+type ClientRateLimit struct {
+	// PerSecond is the number of requests per second of each client.
+	PerSecond float64 `json:"per_second"`
+	// Burst is the number of requests a client may send at once after a
+	// pause, 1 if not set.
+	Burst int `json:"burst,omitempty"`
+}
+
+// clientLimiters are the limiters of the clients of a server.
+type clientLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*limiter
+	pruned   time.Time
+}
+
+// allow reports whether client may send a request according to cfg.
+func (c *clientLimiters) allow(cfg ClientRateLimit, client string) bool {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	c.mu.Lock()
+	now := time.Now()
+	if now.Sub(c.pruned) > time.Minute {
+		c.prune(now, time.Duration(float64(burst)/cfg.PerSecond*float64(time.Second)))
+	}
+	l := c.limiters[client]
+	if l == nil {
+		if c.limiters == nil {
+			c.limiters = make(map[string]*limiter)
+		}
+		l = &limiter{cfg: RateLimit{PerSecond: cfg.PerSecond, Burst: burst}, tokens: float64(burst)}
+		c.limiters[client] = l
+	}
+	c.mu.Unlock()
+	return l.take()
+}
+
+// prune drops the limiters idle for refill, which are full again, c.mu held.
+func (c *clientLimiters) prune(now time.Time, refill time.Duration) {
+	for client, l := range c.limiters {
+		l.mu.Lock()
+		idle := now.Sub(l.last) > refill
+		l.mu.Unlock()
+		if idle {
+			delete(c.limiters, client)
+		}
+	}
+	c.pruned = now
+}
+
+// tooManyRequests answers a request beyond the rate limit of its client.
+func tooManyRequests(rw http.ResponseWriter, l ClientRateLimit) {
+	rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(1/l.PerSecond))))
+	rw.WriteHeader(http.StatusTooManyRequests)
+	rw.Write([]byte("Too Many Requests"))
+}
@@ -0,0 +1,92 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	var n int
+	ts := newCountingServer(&n)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithRateLimit(RateLimit{PerSecond: 20, Queue: 10}))
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.Call(context.Background(), "count", nil); err != nil {
+			t.Fatalf("count: error not expected: %v", err)
+		}
+	}
+	if d := time.Since(start); d < 180*time.Millisecond {
+		t.Errorf("5 calls at 20/s took %v, want at least 200ms", d)
+	}
+}
+
+func TestRateLimitQueue(t *testing.T) {
+	release, entered := make(chan struct{}), make(chan struct{})
+	s := NewServer()
+	s.HandleFunc("block", func(ctx context.Context) (bool, error) {
+		entered <- struct{}{}
+		<-release
+		return true, nil
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithRateLimit(RateLimit{MaxConcurrent: 1, Queue: 1}))
+	errs := make(chan error, 2)
+	go func() {
+		_, err := client.Call(context.Background(), "block", nil)
+		errs <- err
+	}()
+	<-entered
+	go func() {
+		_, err := client.Call(context.Background(), "block", nil)
+		errs <- err
+	}()
+	// wait for the second call to be queued
+	for i := 0; i < 100 && atomic.LoadInt64(&client.limiter.queued) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := client.Call(context.Background(), "block", nil); !errors.Is(err, ErrClientQueueFull) {
+		t.Errorf("got error %v, want ErrClientQueueFull", err)
+	}
+	close(release)
+	<-entered
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("block: error not expected: %v", err)
+		}
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	server := NewServer()
+	server.RateLimit = ClientRateLimit{PerSecond: 1, Burst: 2}
+	server.HandleFunc("ping", func(ctx context.Context) (string, error) { return "pong", nil })
+
+	post := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		r.RemoteAddr = remoteAddr
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, r)
+		return rw
+	}
+	for i := 0; i < 2; i++ {
+		if rw := post("192.0.2.1:1234"); rw.Code != 200 {
+			t.Fatalf("request %v within the burst: status %v", i, rw.Code)
+		}
+	}
+	rw := post("192.0.2.1:5678")
+	if rw.Code != 429 || rw.Header().Get("Retry-After") != "1" {
+		t.Errorf("request beyond the burst: status %v, Retry-After %q", rw.Code, rw.Header().Get("Retry-After"))
+	}
+	if rw := post("192.0.2.2:1234"); rw.Code != 200 {
+		t.Errorf("request of another client: status %v", rw.Code)
+	}
+}
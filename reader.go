@@ -0,0 +1,62 @@
+package jsonrpc
+
+import (
+	"encoding/base64"
+	"io"
+)
+
+// RawResult is a result whose JSON encoding is read from the embedded Reader
+// and copied as is into the response. It lets handlers forward large,
+// already encoded documents without unmarshaling and marshaling them. The
+// content is not validated.
+//
+// The reader is closed once the response is sent, or dropped, if it
+// implements io.Closer.
+type RawResult struct {
+	io.Reader
+}
+
+// Base64Result is a result whose content is read from the embedded Reader
+// and sent as a base64 encoded string, for binary results too large to be
+// held in memory. Like the one of RawResult, the reader is closed once the
+// response is sent, or dropped, if it implements io.Closer.
+type Base64Result struct {
+	io.Reader
+}
+
+// readerResult returns the reader to stream into the response when ret is
+// a RawResult or a Base64Result.
+func readerResult(ret interface{}) (rd io.Reader, raw bool, ok bool) {
+	switch v := ret.(type) {
+	case RawResult:
+		return v.Reader, true, v.Reader != nil
+	case Base64Result:
+		return v.Reader, false, v.Reader != nil
+	}
+	return nil, false, false
+}
+
+// closeReader closes rd if it implements io.Closer.
+func closeReader(rd io.Reader) {
+	if c, ok := rd.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// copyReaderResult copies rd to w as a JSON value.
+func copyReaderResult(w encodeWriter, rd io.Reader, raw bool) error {
+	if raw {
+		_, err := io.Copy(w, rd)
+		return err
+	}
+
+	w.WriteByte('"')
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, rd); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return w.WriteByte('"')
+}
@@ -0,0 +1,84 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeReaderResult(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("raw", func(ctx context.Context) (RawResult, error) {
+		return RawResult{strings.NewReader(`{"large":[1,2,3]}`)}, nil
+	})
+	server.HandleFunc("binary", func(ctx context.Context) (Base64Result, error) {
+		return Base64Result{bytes.NewReader([]byte{0, 1, 2, 3})}, nil
+	})
+
+	tests := []struct {
+		req  string
+		resp string
+	}{
+		{
+			req:  `{"jsonrpc":"2.0","id":1,"method":"raw"}`,
+			resp: `{"jsonrpc":"2.0","id":1,"result":{"large":[1,2,3]}}`,
+		},
+		{
+			req:  `{"jsonrpc":"2.0","id":2,"method":"binary"}`,
+			resp: `{"jsonrpc":"2.0","id":2,"result":"AAECAw=="}`,
+		},
+		{
+			req:  `[{"jsonrpc":"2.0","id":3,"method":"raw"},{"jsonrpc":"2.0","id":4,"method":"binary"}]`,
+			resp: `[{"jsonrpc":"2.0","id":3,"result":{"large":[1,2,3]}},{"jsonrpc":"2.0","id":4,"result":"AAECAw=="}]`,
+		},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("POST", "localhost:8080", strings.NewReader(tc.req))
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, req)
+		if got := rw.Body.String(); got != tc.resp {
+			t.Errorf("invalid jsonrpc response: \ngot: %v\nwant: %v\n", got, tc.resp)
+		}
+	}
+}
+
+type closeCounter struct {
+	io.Reader
+	closed *int
+}
+
+func (c closeCounter) Close() error {
+	*c.closed++
+	return nil
+}
+
+func TestReaderResultClosed(t *testing.T) {
+	closed := 0
+	server := NewServer()
+	reader := func() RawResult {
+		return RawResult{closeCounter{strings.NewReader(`"ok"`), &closed}}
+	}
+	server.HandleFunc("raw", func(ctx context.Context) (RawResult, error) {
+		return reader(), nil
+	})
+	server.HandleFunc("fail", func(ctx context.Context) (RawResult, error) {
+		return reader(), errors.New("failed")
+	})
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"raw"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"fail"}`,
+		`{"jsonrpc":"2.0","method":"raw"}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"raw"},{"jsonrpc":"2.0","method":"raw"}]`,
+	} {
+		closed = 0
+		server.ServeMessage(context.Background(), []byte(req))
+		if want := strings.Count(req, `"method"`); closed != want {
+			t.Errorf("%v: %d readers closed, want %d", req, closed, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// DefaultRedactionMask replaces redacted values when Redactor.Mask is empty.
+const DefaultRedactionMask = "[REDACTED]"
+
+// Redactor masks sensitive values in params before they are handed to hooks,
+// audit records or logs. Fields of handler params tagged `jsonrpc:"redact"`
+// are always masked, Paths adds fields by JSON path.
+type Redactor struct {
+	// Paths are dot separated paths to the values to mask, relative to the
+	// params, such as "password", "$.user.token" or "items.*.secret". "*"
+	// matches any key or array index. Keys match case insensitively, as
+	// encoding/json does when decoding params.
+	Paths []string
+	// Mask replaces the masked values, defaults to DefaultRedactionMask.
+	Mask string
+}
+
+// Redact returns params with the sensitive values of the params of method
+// masked. Params are returned unchanged if nothing is masked and fully masked
+// if they aren't valid JSON.
+func (s *Server) Redact(method string, params json.RawMessage) json.RawMessage {
+	var tagged [][]string
+	if v, ok := s.handler.Load(method); ok {
+		tagged = v.(handlerType).redact
+	}
+	if len(params) == 0 || len(tagged) == 0 && len(s.Redaction.Paths) == 0 {
+		return params
+	}
+	mask := s.Redaction.Mask
+	if mask == "" {
+		mask = DefaultRedactionMask
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		b, _ := json.Marshal(mask)
+		return b
+	}
+	masked := false
+	for _, p := range s.Redaction.Paths {
+		masked = redactPath(v, splitRedactPath(p), mask) || masked
+	}
+	for _, p := range tagged {
+		masked = redactPath(v, p, mask) || masked
+	}
+	if !masked {
+		return params
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(mask)
+	}
+	return b
+}
+
+func splitRedactPath(p string) []string {
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	p = strings.Replace(p, "[*]", ".*", -1)
+	return strings.Split(p, ".")
+}
+
+// redactPath replaces the values of v at path with mask and reports whether
+// any was.
+func redactPath(v interface{}, path []string, mask string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	last := len(path) == 1
+	masked := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if path[0] != "*" && !strings.EqualFold(k, path[0]) {
+				continue
+			}
+			if last {
+				v[k] = mask
+				masked = true
+			} else {
+				masked = redactPath(e, path[1:], mask) || masked
+			}
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return false
+		}
+		for i, e := range v {
+			if last {
+				v[i] = mask
+				masked = true
+			} else {
+				masked = redactPath(e, path[1:], mask) || masked
+			}
+		}
+	}
+	return masked
+}
+
+// redactedFields returns the paths of the fields of t tagged
+// `jsonrpc:"redact"`.
+func redactedFields(t reflect.Type) [][]string {
+	var paths [][]string
+	collectRedactedFields(t, nil, &paths, map[reflect.Type]bool{})
+	return paths
+}
+
+func collectRedactedFields(t reflect.Type, prefix []string, paths *[][]string, seen map[reflect.Type]bool) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		collectRedactedFields(t.Elem(), append(prefix, "*"), paths, seen)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		tagName, opts := jsonrpcTag(f)
+		if tagName != "" {
+			name = tagName
+		}
+		path := append(append([]string(nil), prefix...), name)
+		if hasTagOption(opts, "redact") {
+			*paths = append(*paths, path)
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" || isSquashed(f) {
+			// fields of embedded and squashed structs are promoted
+			collectRedactedFields(f.Type, prefix, paths, seen)
+			continue
+		}
+		collectRedactedFields(f.Type, path, paths, seen)
+	}
+}
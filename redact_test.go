@@ -0,0 +1,63 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type Credentials struct {
+	User     string
+	Password string `json:"password" jsonrpc:"redact"`
+}
+
+type LoginArgs struct {
+	Credentials
+	Tokens  []Token `json:"tokens"`
+	Comment string
+}
+
+type Token struct {
+	Name  string
+	Value string `jsonrpc:"redact"`
+}
+
+func TestRedact(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("login", func(ctx context.Context, args LoginArgs) (int, error) { return 0, nil })
+	server.HandleFunc("sum", sum)
+
+	tests := []struct {
+		name   string
+		paths  []string
+		method string
+		params string
+		want   string
+	}{
+		{"tags", nil, "login",
+			`{"User":"bob","PASSWORD":"x","tokens":[{"Name":"a","Value":"y"}],"Comment":"c"}`,
+			`{"Comment":"c","PASSWORD":"[REDACTED]","User":"bob","tokens":[{"Name":"a","Value":"[REDACTED]"}]}`},
+		{"paths", []string{"$.comment", "tokens[*].name"}, "login",
+			`{"Comment":"c","tokens":[{"Name":"a"}]}`,
+			`{"Comment":"[REDACTED]","tokens":[{"Name":"[REDACTED]"}]}`},
+		{"unchanged", nil, "sum", `{"A": 1, "B": 2}`, `{"A": 1, "B": 2}`},
+		{"unknown method", []string{"A"}, "unknown", `{"A":1}`, `{"A":"[REDACTED]"}`},
+		{"invalid json", nil, "login", `{"password":`, `"[REDACTED]"`},
+		{"no params", nil, "login", ``, ``},
+	}
+	for _, tt := range tests {
+		server.Redaction.Paths = tt.paths
+		got := server.Redact(tt.method, json.RawMessage(tt.params))
+		if string(got) != tt.want {
+			t.Errorf("%v:\ngot: %s\nwant: %s", tt.name, got, tt.want)
+		}
+	}
+
+	var params json.RawMessage
+	server.Redaction = Redactor{Mask: "***"}
+	server.Hooks.OnRequest = func(ctx context.Context, req *RequestInfo) { params = req.Params }
+	server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"login","params":{"password":"x"}}`))
+	if want := `{"password":"***"}`; string(params) != want {
+		t.Errorf("hook params not redacted:\ngot: %s\nwant: %s", params, want)
+	}
+}
@@ -0,0 +1,98 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// RedisMessage is a message received on a Redis pub/sub channel.
+type RedisMessage struct {
+	Channel string
+	Payload []byte
+}
+
+// RedisPubSub is the subset of a Redis client used by RedisBridge, it maps
+// to the Publish and Subscribe commands of go-redis.
+type RedisPubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe returns the messages received on channels, the channel
+	// being closed once ctx is done.
+	Subscribe(ctx context.Context, channels ...string) (<-chan RedisMessage, error)
+}
+
+// RedisBridge shares the events of a Broker between the instances of a
+// server through Redis pub/sub. Each bridged topic is a Redis channel of
+// the same name: the events published on it through any instance are
+// delivered to the subscriptions of every instance.
+//
+//	bridge := jsonrpc.NewRedisBridge(broker, redisPubSub, "orders.created", "chat.lobby")
+//	go bridge.Run(ctx)
+//	bridge.Publish(ctx, "orders.created", order)
+type RedisBridge struct {
+	broker   *Broker
+	ps       RedisPubSub
+	channels map[string]bool
+	list     []string
+}
+
+// NewRedisBridge returns a bridge relaying the topics channels between b
+// and ps.
+func NewRedisBridge(b *Broker, ps RedisPubSub, channels ...string) *RedisBridge {
+	rb := &RedisBridge{broker: b, ps: ps, channels: make(map[string]bool), list: channels}
+	for _, c := range channels {
+		rb.channels[c] = true
+	}
+	return rb
+}
+
+var errRedisSubscriptionClosed = errors.New("jsonrpc: redis: subscription closed")
+
+// Run publishes the messages received on the bridged channels to the broker
+// until ctx is done. Payloads are the JSON encoded data of the events. If
+// the subscription ends before, such as when the connection to Redis is
+// lost, Run returns an error so that it can be run again.
+func (rb *RedisBridge) Run(ctx context.Context) error {
+	msgs, err := rb.ps.Subscribe(ctx, rb.list...)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: redis: subscribing: %w", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-msgs:
+			if !ok {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return errRedisSubscriptionClosed
+			}
+			if !json.Valid(m.Payload) {
+				log.Printf("jsonrpc: redis: dropping invalid event on %v", m.Channel)
+				continue
+			}
+			rb.broker.Publish(m.Channel, json.RawMessage(m.Payload))
+		}
+	}
+}
+
+// Publish publishes data on topic: through Redis if the topic is bridged, so
+// that every instance delivers it, this one included once received by Run,
+// or to the broker directly otherwise.
+func (rb *RedisBridge) Publish(ctx context.Context, topic string, data interface{}) error {
+	if !rb.channels[topic] {
+		rb.broker.Publish(topic, data)
+		return nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: marshaling event: %w", err)
+	}
+	if err := rb.ps.Publish(ctx, topic, b); err != nil {
+		return fmt.Errorf("jsonrpc: redis: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-memory pub/sub shared by several bridges.
+type fakeRedis struct {
+	mu   sync.Mutex
+	subs map[string][]chan RedisMessage
+}
+
+func (r *fakeRedis) Publish(ctx context.Context, channel string, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.subs[channel] {
+		c <- RedisMessage{channel, payload}
+	}
+	return nil
+}
+
+func (r *fakeRedis) Subscribe(ctx context.Context, channels ...string) (<-chan RedisMessage, error) {
+	c := make(chan RedisMessage, 16)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subs == nil {
+		r.subs = make(map[string][]chan RedisMessage)
+	}
+	for _, ch := range channels {
+		r.subs[ch] = append(r.subs[ch], c)
+	}
+	return c, nil
+}
+
+func TestRedisBridge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	redis := &fakeRedis{}
+	var brokers []*Broker
+	var bridges []*RedisBridge
+	var events []<-chan Event
+	for i := 0; i < 2; i++ {
+		b := NewBroker()
+		c, err := b.Subscribe(ctx, "orders.#")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rb := NewRedisBridge(b, redis, "orders.created")
+		go rb.Run(ctx)
+		brokers, bridges, events = append(brokers, b), append(bridges, rb), append(events, c)
+	}
+	// wait for the bridges to subscribe
+	for {
+		redis.mu.Lock()
+		n := len(redis.subs["orders.created"])
+		redis.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := bridges[0].Publish(ctx, "orders.created", map[string]int{"id": 7}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	for i, c := range events {
+		select {
+		case ev := <-c:
+			b, _ := json.Marshal(ev.Data)
+			if ev.Topic != "orders.created" || string(b) != `{"id":7}` {
+				t.Errorf("instance %v: got %v %s", i, ev.Topic, b)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("instance %v: event not delivered", i)
+		}
+	}
+	// not bridged, delivered locally only
+	bridges[0].Publish(ctx, "orders.deleted", 8)
+	select {
+	case ev := <-events[0]:
+		if ev.Topic != "orders.deleted" {
+			t.Errorf("instance 0: got %v", ev.Topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("local event not delivered")
+	}
+	select {
+	case ev := <-events[1]:
+		t.Errorf("instance 1 got a local event of instance 0: %v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// lostRedis is a pub/sub whose subscriptions end at once, like on a dropped
+// connection.
+type lostRedis struct{ fakeRedis }
+
+func (r *lostRedis) Subscribe(ctx context.Context, channels ...string) (<-chan RedisMessage, error) {
+	c := make(chan RedisMessage)
+	close(c)
+	return c, nil
+}
+
+func TestRedisBridgeSubscriptionLost(t *testing.T) {
+	rb := NewRedisBridge(NewBroker(), &lostRedis{}, "orders.created")
+	if err := rb.Run(context.Background()); err != errRedisSubscriptionClosed {
+		t.Errorf("run:\ngot: %v\nwant: %v", err, errRedisSubscriptionClosed)
+	}
+}
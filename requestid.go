@@ -0,0 +1,68 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the HTTP header carrying the correlation id of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the correlation ids accepted from clients.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the correlation id id, for
+// transports other than HTTP.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation id carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID returns the correlation id sent by the client in r or a new one
+// if it didn't send a valid one.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	return randomID(16)
+}
+
+// randomID returns n random bytes hex encoded.
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id is short and printable, so that it can
+// safely end up in logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// logID formats the correlation id of ctx for log lines.
+func logID(ctx context.Context) string {
+	if id := RequestID(ctx); id != "" {
+		return " [" + id + "]"
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	server := NewServer()
+	server.RequestIDs = true
+	server.HandleFunc("id", func(ctx context.Context) (string, error) {
+		got = RequestID(ctx)
+		return got, nil
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"id"}`
+	post := func(id string) *http.Response {
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(body))
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := post("abc-123")
+	if h := resp.Header.Get(RequestIDHeader); h != "abc-123" || got != "abc-123" {
+		t.Errorf("client id not propagated:\ngot: %q, header %q\nwant: abc-123", got, h)
+	}
+
+	for _, id := range []string{"", "bad id", strings.Repeat("a", 200)} {
+		resp = post(id)
+		h := resp.Header.Get(RequestIDHeader)
+		if len(h) != 32 || h != got {
+			t.Errorf("%q: invalid generated id:\ngot: %q, context %q", id, h, got)
+		}
+	}
+}
+
+func TestRequestIDLogs(t *testing.T) {
+	var buf bytes.Buffer
+	server := NewServer()
+	server.SlowCallThreshold = 1
+	server.HandleFunc("random", random)
+	defer captureLog(&buf)()
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	server.ServeMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"random"}`))
+	if !strings.Contains(buf.String(), "slow call [req-1]") {
+		t.Errorf("correlation id not logged:\n%s", buf.String())
+	}
+}
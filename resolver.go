@@ -0,0 +1,120 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync/atomic"
+)
+
+var errNoEndpoints = errors.New("no endpoints available")
+
+// Resolver returns the endpoints of a JSON-RPC service, e.g. from Consul or
+// etcd.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// Watcher is implemented by resolvers able to push endpoint updates. Watch
+// calls update with the full list of endpoints on every change and blocks
+// until ctx is done.
+type Watcher interface {
+	Watch(ctx context.Context, update func(endpoints []string)) error
+}
+
+// StaticResolver always resolves to the same endpoints.
+type StaticResolver []string
+
+// Resolve returns r.
+func (r StaticResolver) Resolve(ctx context.Context) ([]string, error) {
+	return r, nil
+}
+
+// WithResolver makes the client spread its requests over the endpoints
+// returned by r in a round robin fashion. If r is a Watcher, the endpoints
+// are kept up to date until the client is closed. The url given to NewClient
+// is used while r has no endpoints. See WithStickyKey to pin related calls
+// to one endpoint.
+func WithResolver(r Resolver) ClientOption {
+	return func(c *Client) {
+		c.resolver = r
+	}
+}
+
+// watchEndpoints keeps the client endpoints up to date until ctx is done.
+func (c *Client) watchEndpoints(ctx context.Context, w Watcher) {
+	err := w.Watch(ctx, c.setEndpoints)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("jsonrpc: watching endpoints: %v", err)
+	}
+}
+
+func (c *Client) setEndpoints(endpoints []string) {
+	eps := make([]string, len(endpoints))
+	copy(eps, endpoints)
+	c.mu.Lock()
+	c.endpoints = eps
+	c.mu.Unlock()
+}
+
+// endpoint returns the url the next request is sent to.
+func (c *Client) endpoint(ctx context.Context) (string, error) {
+	if c.failover != nil {
+		return c.failover.endpoint()
+	}
+	if c.resolver == nil {
+		return c.url, nil
+	}
+	c.mu.Lock()
+	eps := c.endpoints
+	c.mu.Unlock()
+	if len(eps) == 0 {
+		resolved, err := c.resolver.Resolve(ctx)
+		if err != nil {
+			return "", fmt.Errorf("resolving endpoints: %w", err)
+		}
+		c.setEndpoints(resolved)
+		eps = resolved
+	}
+	if len(eps) == 0 {
+		if c.url == "" {
+			return "", errNoEndpoints
+		}
+		return c.url, nil
+	}
+	if key, ok := ctx.Value(stickyKey{}).(string); ok {
+		return stickyEndpoint(eps, key), nil
+	}
+	n := atomic.AddUint64(&c.rr, 1)
+	return eps[(n-1)%uint64(len(eps))], nil
+}
+
+type stickyKey struct{}
+
+// WithStickyKey returns a copy of ctx whose calls, made by a client with a
+// resolver, all go to the same endpoint among those of the resolver, so that
+// related calls relying on server side state, such as the steps of a
+// workflow, land on the same backend. When the endpoints change, only the
+// keys of the endpoints removed move.
+func WithStickyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, stickyKey{}, key)
+}
+
+// stickyEndpoint returns the endpoint of eps key is bound to, the one with
+// the highest hash of key and endpoint (rendezvous hashing).
+func stickyEndpoint(eps []string, key string) string {
+	var best string
+	var max uint64
+	for _, ep := range eps {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(ep))
+		if sum := h.Sum64(); best == "" || sum > max {
+			best, max = ep, sum
+		}
+	}
+	return best
+}
@@ -0,0 +1,117 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCountingServer(n *int) *httptest.Server {
+	s := NewServer()
+	s.HandleFunc("count", func(ctx context.Context) (int, error) {
+		*n++
+		return *n, nil
+	})
+	return httptest.NewServer(s)
+}
+
+func TestStaticResolver(t *testing.T) {
+	var n1, n2 int
+	ts1, ts2 := newCountingServer(&n1), newCountingServer(&n2)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	client := NewClient("", WithResolver(StaticResolver{ts1.URL, ts2.URL}))
+	defer client.Close()
+	for i := 0; i < 4; i++ {
+		if _, err := client.Call(context.Background(), "count", nil); err != nil {
+			t.Fatalf("count: error not expected: %v", err)
+		}
+	}
+	if n1 != 2 || n2 != 2 {
+		t.Errorf("calls not balanced: got %v and %v, want 2 and 2", n1, n2)
+	}
+}
+
+func TestStickyKey(t *testing.T) {
+	var n1, n2 int
+	ts1, ts2 := newCountingServer(&n1), newCountingServer(&n2)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	client := NewClient("", WithResolver(StaticResolver{ts1.URL, ts2.URL}))
+	defer client.Close()
+	ctx := WithStickyKey(context.Background(), "workflow-42")
+	for i := 0; i < 4; i++ {
+		if _, err := client.Call(ctx, "count", nil); err != nil {
+			t.Fatalf("count: error not expected: %v", err)
+		}
+	}
+	if n1+n2 != 4 || (n1 != 0 && n2 != 0) {
+		t.Errorf("calls not sticky: got %v and %v", n1, n2)
+	}
+
+	eps := []string{"a", "b", "c", "d"}
+	keys := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		keys[key] = stickyEndpoint(eps, key)
+	}
+	for key, ep := range keys {
+		if got := stickyEndpoint(eps[:3], key); ep != "d" && got != ep {
+			t.Errorf("key %v moved from %v to %v when d was removed", key, ep, got)
+		}
+	}
+}
+
+type chanWatcher struct {
+	StaticResolver
+	updates chan []string
+}
+
+func (w *chanWatcher) Watch(ctx context.Context, update func([]string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case eps := <-w.updates:
+			update(eps)
+		}
+	}
+}
+
+func TestWatchResolver(t *testing.T) {
+	var n1, n2 int
+	ts1, ts2 := newCountingServer(&n1), newCountingServer(&n2)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	w := &chanWatcher{StaticResolver: StaticResolver{ts1.URL}, updates: make(chan []string)}
+	client := NewClient("", WithResolver(w))
+	defer client.Close()
+
+	if _, err := client.Call(context.Background(), "count", nil); err != nil {
+		t.Fatalf("count: error not expected: %v", err)
+	}
+	w.updates <- []string{ts2.URL}
+	// the update is applied asynchronously
+	for i := 0; i < 100 && n2 == 0; i++ {
+		if _, err := client.Call(context.Background(), "count", nil); err != nil {
+			t.Fatalf("count: error not expected: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n1 == 0 || n2 == 0 {
+		t.Errorf("endpoint update not applied: got %v and %v calls", n1, n2)
+	}
+}
+
+func TestResolverNoEndpoints(t *testing.T) {
+	client := NewClient("", WithResolver(StaticResolver{}))
+	_, err := client.Call(context.Background(), "count", nil)
+	if err == nil {
+		t.Errorf("expected an error without endpoints")
+	}
+}
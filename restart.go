@@ -0,0 +1,196 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Environment variables through which Restart hands the listener of a
+// server over to the new process.
+const (
+	// ListenFDsEnv lists the inherited listeners as comma separated
+	// "fd:addr" pairs, addr being the address given to ListenAndServe.
+	ListenFDsEnv = "JSONRPC_LISTEN_FDS"
+	// ReadyFDEnv is the file descriptor the new process writes to once it
+	// serves the inherited listeners.
+	ReadyFDEnv = "JSONRPC_READY_FD"
+)
+
+var errNotListening = errors.New("jsonrpc: server not listening")
+
+// inherited holds the listeners passed by the parent process, claimed by
+// address as servers start.
+var inherited struct {
+	sync.Mutex
+	loaded bool
+	files  map[string]*os.File
+	ready  *os.File
+}
+
+// inheritedListener returns the listener inherited for addr, or nil.
+func inheritedListener(addr string) (net.Listener, error) {
+	inherited.Lock()
+	defer inherited.Unlock()
+	if !inherited.loaded {
+		inherited.loaded = true
+		if err := loadInherited(); err != nil {
+			return nil, err
+		}
+	}
+	f := inherited.files[addr]
+	if f == nil {
+		return nil, nil
+	}
+	delete(inherited.files, addr)
+	l, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: inheriting listener of %v: %w", addr, err)
+	}
+	if len(inherited.files) == 0 && inherited.ready != nil {
+		// the parent may stop serving now
+		inherited.ready.Write([]byte{1})
+		inherited.ready.Close()
+		inherited.ready = nil
+	}
+	return l, nil
+}
+
+func loadInherited() error {
+	fds, ready := os.Getenv(ListenFDsEnv), os.Getenv(ReadyFDEnv)
+	os.Unsetenv(ListenFDsEnv)
+	os.Unsetenv(ReadyFDEnv)
+	inherited.files = make(map[string]*os.File)
+	if fds != "" {
+		for _, pair := range strings.Split(fds, ",") {
+			i := strings.IndexByte(pair, ':')
+			if i < 0 {
+				return fmt.Errorf("jsonrpc: invalid %v %q", ListenFDsEnv, fds)
+			}
+			fd, err := strconv.Atoi(pair[:i])
+			if err != nil {
+				return fmt.Errorf("jsonrpc: invalid %v %q", ListenFDsEnv, fds)
+			}
+			inherited.files[pair[i+1:]] = os.NewFile(uintptr(fd), "listener")
+		}
+	}
+	if ready != "" {
+		fd, err := strconv.Atoi(ready)
+		if err != nil {
+			return fmt.Errorf("jsonrpc: invalid %v %q", ReadyFDEnv, ready)
+		}
+		inherited.ready = os.NewFile(uintptr(fd), "ready")
+	}
+	return nil
+}
+
+// Restart starts a new instance of the running executable, with the same
+// arguments, and hands it the listener of s, so that the address is never
+// closed. Once the new process serves the listener, by calling
+// ListenAndServe or ListenAndServeTLS with the same address, s is shut down
+// gracefully: in-flight calls complete until ctx is done while new
+// connections go to the new process. If the new process fails to start
+// serving before ctx is done, it is killed, s keeps serving and an error is
+// returned.
+//
+// Restart is typically called on SIGHUP. Listener inheritance is only
+// supported on Unix systems.
+func (s *Server) Restart(ctx context.Context) error {
+	s.mu.Lock()
+	l, srv := s.listener, s.httpServer
+	s.mu.Unlock()
+	if l == nil || srv == nil {
+		return errNotListening
+	}
+	fl, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("jsonrpc: listener %T can't be inherited", l)
+	}
+	lf, err := fl.File()
+	if err != nil {
+		return fmt.Errorf("jsonrpc: restarting: %w", err)
+	}
+	defer lf.Close()
+	ready, rw, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("jsonrpc: restarting: %w", err)
+	}
+	defer ready.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		rw.Close()
+		return fmt.Errorf("jsonrpc: restarting: %w", err)
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// ExtraFiles start at fd 3
+	cmd.ExtraFiles = []*os.File{lf, rw}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, ListenFDsEnv+"=") && !strings.HasPrefix(kv, ReadyFDEnv+"=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, ListenFDsEnv+"=3:"+srv.Addr, ReadyFDEnv+"=4")
+	err = cmd.Start()
+	rw.Close()
+	restoreNonblock(l)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: restarting: %w", err)
+	}
+
+	// a byte is written to the pipe once the listener is served, it is
+	// closed without one if the process exits first
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	readyc := make(chan bool, 1)
+	go func() {
+		var b [1]byte
+		n, _ := ready.Read(b[:])
+		readyc <- n == 1
+	}()
+	select {
+	case ok := <-readyc:
+		if !ok {
+			return errors.New("jsonrpc: restarting: new process exited before serving")
+		}
+	case <-ctx.Done():
+		// s keeps serving, the new process must not serve alongside it
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("jsonrpc: restarting: %w", ctx.Err())
+	}
+	return s.Shutdown(ctx)
+}
+
+// restoreNonblock puts l back in non-blocking mode once its file has been
+// passed to a new process, which makes the socket blocking. Accept couldn't
+// be interrupted by Close otherwise.
+func restoreNonblock(l net.Listener) {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return
+	}
+	rc, err := sc.SyscallConn()
+	if err == nil {
+		cerr := rc.Control(func(fd uintptr) { err = setNonblock(fd) })
+		if cerr != nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		log.Printf("jsonrpc: restarting: restoring non-blocking listener: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Restart runs the test binary again, restartEnv tells it to act as the new
+// process of TestRestart instead of running the tests: "serve" serves the
+// inherited listener, any other value is a file the process writes its pid
+// to before hanging without serving.
+const restartEnv = "JSONRPC_TEST_RESTART"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(restartEnv); mode != "" {
+		// don't outlive the test if something goes wrong
+		time.AfterFunc(time.Minute, func() { os.Exit(2) })
+		if mode != "serve" {
+			ioutil.WriteFile(mode, []byte(strconv.Itoa(os.Getpid())), 0600)
+			select {}
+		}
+		addr := os.Getenv(ListenFDsEnv)
+		addr = addr[strings.IndexByte(addr, ':')+1:]
+		server := NewServer()
+		server.HandleFunc("pid", pid)
+		server.HandleFunc("shutdown", func(ctx context.Context) (bool, error) {
+			go server.Shutdown(context.Background())
+			return true, nil
+		})
+		if err := server.ListenAndServe(addr); err != http.ErrServerClosed {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func pid(ctx context.Context) (int, error) {
+	return os.Getpid(), nil
+}
+
+// inherit passes a duplicate of l to the listeners inherited for addr.
+func inherit(t *testing.T, l net.Listener, addr string) *os.File {
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("listener file: %v", err)
+	}
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatalf("dup: %v", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	wfd, err := syscall.Dup(int(w.Fd()))
+	w.Close()
+	if err != nil {
+		t.Fatalf("dup: %v", err)
+	}
+	os.Setenv(ListenFDsEnv, strconv.Itoa(fd)+":"+addr)
+	os.Setenv(ReadyFDEnv, strconv.Itoa(wfd))
+	inherited.Lock()
+	inherited.loaded = false
+	inherited.Unlock()
+	return r
+}
+
+func TestInheritedListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	ready := inherit(t, l, ":4547")
+	defer ready.Close()
+
+	server := NewServer()
+	server.HandleFunc("random", random)
+	il, err := server.listen(":4547")
+	if err != nil {
+		t.Fatalf("inheriting listener: %v", err)
+	}
+	defer il.Close()
+	if il.Addr().String() != l.Addr().String() {
+		t.Errorf("invalid inherited listener:\ngot: %v\nwant: %v", il.Addr(), l.Addr())
+	}
+	var b [1]byte
+	if n, _ := ready.Read(b[:]); n != 1 {
+		t.Errorf("parent not told the listener is served")
+	}
+	if os.Getenv(ListenFDsEnv) != "" {
+		t.Errorf("%v left in the environment", ListenFDsEnv)
+	}
+
+	go http.Serve(il, server)
+	client := NewClient("http://" + l.Addr().String())
+	if _, err := client.Call(context.Background(), "random", nil); err != nil {
+		t.Errorf("calling through the inherited listener: %v", err)
+	}
+
+	// listeners are inherited once
+	l2, err := server.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	l2.Close()
+}
+
+func TestReusePort(t *testing.T) {
+	server := NewServer()
+	server.ReusePort = true
+	l1, err := server.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l1.Close()
+	l2, err := server.listen(l1.Addr().String())
+	if err != nil {
+		t.Fatalf("listening twice on %v: %v", l1.Addr(), err)
+	}
+	l2.Close()
+}
+
+func TestRestartNotListening(t *testing.T) {
+	if err := NewServer().Restart(context.Background()); !errors.Is(err, errNotListening) {
+		t.Errorf("restart:\ngot: %v\nwant: %v", err, errNotListening)
+	}
+}
+
+// serveRestartable starts server on a free address and returns the address
+// along with the error of ListenAndServe.
+func serveRestartable(t *testing.T, server *Server) (string, chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	done := make(chan error, 1)
+	go func() { done <- server.ListenAndServe(addr) }()
+	for listening := false; !listening; time.Sleep(5 * time.Millisecond) {
+		server.mu.Lock()
+		listening = server.listener != nil
+		server.mu.Unlock()
+	}
+	return addr, done
+}
+
+func TestRestart(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("pid", pid)
+	addr, done := serveRestartable(t, server)
+	client := NewClient("http://" + addr)
+
+	os.Setenv(restartEnv, "serve")
+	defer os.Unsetenv(restartEnv)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Restart(ctx); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("ListenAndServe after restart:\ngot: %v\nwant: %v", err, http.ErrServerClosed)
+	}
+	var child int
+	resp, err := client.Call(context.Background(), "pid", nil)
+	if err == nil {
+		err = resp.Decode(&child)
+	}
+	if err != nil {
+		t.Fatalf("calling the new process: %v", err)
+	}
+	if child == os.Getpid() {
+		t.Errorf("call served by the old process")
+	}
+	if _, err := client.Call(context.Background(), "shutdown", nil); err != nil {
+		t.Errorf("shutting the new process down: %v", err)
+	}
+}
+
+func TestRestartCanceled(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("pid", pid)
+	addr, done := serveRestartable(t, server)
+	defer func() {
+		server.Shutdown(context.Background())
+		<-done
+	}()
+
+	dir, err := ioutil.TempDir("", "jsonrpc")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+	os.Setenv(restartEnv, pidFile)
+	defer os.Unsetenv(restartEnv)
+
+	// cancel once the new process is started, it never serves
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		for i := 0; i < 3000; i++ {
+			if b, _ := ioutil.ReadFile(pidFile); len(b) > 0 {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	if err := server.Restart(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("restart:\ngot: %v\nwant: %v", err, context.Canceled)
+	}
+	b, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("reading pid: %v", err)
+	}
+	child, _ := strconv.Atoi(string(b))
+	if err := syscall.Kill(child, 0); err != syscall.ESRCH {
+		t.Errorf("new process left running: %v", err)
+	}
+
+	var got int
+	resp, err := NewClient("http://"+addr).Call(context.Background(), "pid", nil)
+	if err == nil {
+		err = resp.Decode(&got)
+	}
+	if err != nil || got != os.Getpid() {
+		t.Errorf("old process not serving after a failed restart: %v %v", got, err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package jsonrpc
+
+import "syscall"
+
+// setNonblock puts fd back in non-blocking mode, see Restart.
+func setNonblock(fd uintptr) error {
+	return syscall.SetNonblock(int(fd), true)
+}
@@ -0,0 +1,24 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ResultRewriter rewrites the encoded result of a call to method before the
+// response is written, to wrap it in an envelope with pagination metadata or
+// filter fields by the scopes of the caller for instance. It returns result
+// unchanged for the methods it doesn't rewrite. Errors are answered as if
+// the handler returned them.
+type ResultRewriter func(ctx context.Context, method string, result json.RawMessage) (json.RawMessage, error)
+
+// rewriteResult passes result through s.ResultRewriters, in order.
+func (s *Server) rewriteResult(ctx context.Context, method string, result json.RawMessage) (json.RawMessage, error) {
+	for _, rewrite := range s.ResultRewriters {
+		var err error
+		if result, err = rewrite(ctx, method, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
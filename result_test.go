@@ -0,0 +1,47 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestResultRewriters(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("list", func(ctx context.Context) ([]int, error) {
+		return []int{1, 2, 3}, nil
+	})
+	server.HandleFunc("get", func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	envelope := func(ctx context.Context, method string, result json.RawMessage) (json.RawMessage, error) {
+		if method != "list" {
+			return result, nil
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(result, &items); err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf(`{"items":%s,"count":%d}`, result, len(items))), nil
+	}
+	forbid := func(ctx context.Context, method string, result json.RawMessage) (json.RawMessage, error) {
+		if method == "get" {
+			return nil, ErrForbidden
+		}
+		return result, nil
+	}
+	server.ResultRewriters = []ResultRewriter{envelope, forbid}
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"list"}`, `{"jsonrpc":"2.0","id":1,"result":{"items":[1,2,3],"count":3}}`},
+		{`{"jsonrpc":"2.0","id":2,"method":"get"}`, `{"jsonrpc":"2.0","id":2,"error":{"code":-32003,"message":"Forbidden"}}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// retriableError marks an error returned by a handler as retriable.
+type retriableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retriableError) Error() string { return e.err.Error() }
+func (e *retriableError) Unwrap() error { return e.err }
+
+// ErrorData implements ErrorDataProvider.
+func (e *retriableError) ErrorData() interface{} {
+	return retryData{Retriable: true, RetryAfter: e.after.Seconds()}
+}
+
+// retryData is the error data of retriable errors.
+type retryData struct {
+	Retriable bool `json:"retriable"`
+	// RetryAfter is the suggested delay in seconds.
+	RetryAfter float64 `json:"retry_after,omitempty"`
+}
+
+// Retriable marks err, returned by a handler, as retriable after the
+// suggested delay, zero meaning none. The retry hint is sent in the error
+// data, unless err already has data, and in the Retry-After header over HTTP.
+func Retriable(err error, after time.Duration) error {
+	return &retriableError{err, after}
+}
+
+// RetryAfter reports whether err is retriable and the suggested delay, for
+// errors marked by Retriable and errors received by a client alike.
+func RetryAfter(err error) (time.Duration, bool) {
+	var re *retriableError
+	if errors.As(err, &re) {
+		return re.after, true
+	}
+	e, ok := AsError(err)
+	if !ok {
+		return 0, false
+	}
+	data, ok := e.Data.(map[string]interface{})
+	if !ok || data["retriable"] != true {
+		return 0, false
+	}
+	secs, _ := data["retry_after"].(float64)
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// setRetryAfter sets the Retry-After header of the HTTP response if err is
+// retriable.
+func setRetryAfter(ctx context.Context, err error) {
+	var re *retriableError
+	if errors.As(err, &re) && re.after > 0 {
+		SetResponseHeader(ctx, "Retry-After", strconv.Itoa(int(math.Ceil(re.after.Seconds()))))
+	}
+}
+
+// WithRetry makes Client.Call retry calls failing with a retriable error, see
+// RetryAfter, making at most attempts attempts. Retries wait for the delay
+// suggested by the server, or backoff if it suggested none.
+func WithRetry(attempts int, backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retryAttempts, c.retryBackoff = attempts, backoff
+	}
+}
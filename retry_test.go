@@ -0,0 +1,73 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetriable(t *testing.T) {
+	errBusy := NewServerError(-32001, "Busy", nil)
+	failures := 2
+	server := NewServer()
+	server.HandleFunc("flaky", func(ctx context.Context) (int, error) {
+		if failures > 0 {
+			failures--
+			return 0, Retriable(errBusy, 1500*time.Millisecond)
+		}
+		return 7, nil
+	})
+	server.HandleFunc("busy", func(ctx context.Context) (int, error) {
+		return 0, Retriable(errBusy, 0)
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	hres, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"flaky"}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	body, _ := ioutil.ReadAll(hres.Body)
+	hres.Body.Close()
+	if want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"Busy","data":{"retriable":true,"retry_after":1.5}}}`; string(body) != want {
+		t.Errorf("invalid response:\ngot: %s\nwant: %v", body, want)
+	}
+	if h := hres.Header.Get("Retry-After"); h != "2" {
+		t.Errorf("invalid Retry-After:\ngot: %q\nwant: 2", h)
+	}
+
+	// the client waits for the suggested delay before retrying
+	failures = 1
+	server.HandleFunc("flaky", func(ctx context.Context) (int, error) {
+		if failures > 0 {
+			failures--
+			return 0, Retriable(errBusy, 10*time.Millisecond)
+		}
+		return 7, nil
+	})
+	client := NewClient(ts.URL, WithRetry(3, time.Millisecond))
+	start := time.Now()
+	resp, err := client.Call(context.Background(), "flaky", nil)
+	if err != nil || resp.Err() != nil {
+		t.Fatalf("flaky: unexpected error: %v, %v", err, resp.Err())
+	}
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("retried before the suggested delay: %v", d)
+	}
+
+	resp, err = client.Call(context.Background(), "busy", nil)
+	if err != nil {
+		t.Fatalf("busy: unexpected error: %v", err)
+	}
+	if after, ok := RetryAfter(resp.Err()); !ok || after != 0 {
+		t.Errorf("busy: invalid retry hint: %v, %v", after, ok)
+	}
+	if !errors.Is(Retriable(errBusy, 0), errBusy) {
+		t.Errorf("Retriable doesn't wrap its error")
+	}
+}
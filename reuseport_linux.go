@@ -0,0 +1,8 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package jsonrpc
+
+// soReusePort is SO_REUSEPORT, which the syscall package lacks on some
+// architectures.
+const soReusePort = 0xf
@@ -1,15 +1,21 @@
 package jsonrpc
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/token"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"reflect"
+	"strconv"
 	"sync"
+	"time"
 )
 
 var (
@@ -21,33 +27,262 @@ var (
 	errServerInvalidReturn = errors.New("invalid return type format")
 )
 
-// Server represents a JSON-RPC server.
+// Server represents a JSON-RPC server. It is configured by setting its
+// exported fields before it serves requests, the ServerOptions passed to
+// NewServer being shorthands setting some of them.
 type Server struct {
 	handler sync.Map
-	// cors map
-	Cors map[string]string
+
+	// RequireAuth restricts the methods registered without an access
+	// option to authenticated clients, see Authenticated and Public.
+	RequireAuth bool
+
+	// IPFilter, if set, rejects clients by address before reading their
+	// requests.
+	IPFilter *IPFilter
+
+	// RateLimit bounds the requests of each client over HTTP, answering
+	// those beyond it with 429 Too Many Requests.
+	RateLimit  ClientRateLimit
+	rateLimits clientLimiters
+
+	// CORS configures the cross-origin requests allowed, see CORSConfig.
+	CORS CORSConfig
+
+	// CSRF configures defenses against cross-site request forgery, see
+	// CSRFConfig.
+	CSRF CSRFConfig
+
+	// H2C enables HTTP/2 cleartext connections in ListenAndServe.
+	H2C bool
+
+	// Timeouts used by ListenAndServe and ListenAndServeTLS, a zero value
+	// selects the matching Default*Timeout.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// StreamFlushInterval controls when the results of streaming handlers
+	// are flushed to the client. Zero flushes whenever the handler has no
+	// value ready, which coalesces bursts, a negative value flushes after
+	// every value and a positive value flushes periodically.
+	StreamFlushInterval time.Duration
+
+	// RequestIDs enables correlation ids: every HTTP request is given the id
+	// sent by the client in the X-Request-ID header, or a generated one,
+	// which is returned in the response header, carried by the handler
+	// context, see RequestID, and attached to log lines and audit records.
+	RequestIDs bool
+
+	// Compression accepts gzip encoded requests, and compresses the
+	// responses of more than 1KiB for clients accepting gzip. Streamed
+	// responses are not compressed.
+	Compression bool
+
+	// Tracing exposes the span of the caller, read from the W3C
+	// traceparent header or the B3 headers, to handlers, whose calls made
+	// by clients with tracing continue the trace. See GetSpanContext.
+	Tracing bool
+
+	// ContextHeaders lists the incoming HTTP headers exposed to handlers,
+	// see Header.
+	ContextHeaders []string
+
+	// Hooks observe the requests served, see Hooks.
+	Hooks Hooks
+
+	// Redaction masks sensitive params passed to hooks and audit records,
+	// see Redact.
+	Redaction Redactor
+
+	// Audit, if set, records every request served, see Auditor.
+	Audit *Auditor
+
+	// Profiling enables the collection of per-method profiles, see Profile.
+	Profiling bool
+	profiles  profiles
+
+	// SlowCallThreshold, if positive, logs and counts the handlers running
+	// longer than it, see SlowCalls.
+	SlowCallThreshold time.Duration
+	slowCalls         sync.Map
+
+	// StatsWindow, if positive, keeps rolling statistics of the calls made
+	// to every method over the last StatsWindow, see Stats and HandleStats.
+	StatsWindow time.Duration
+	stats       sync.Map
+
+	// VersionPolicy selects the version of methods registered with
+	// HandleVersion called without a version.
+	VersionPolicy VersionPolicy
+	versions      sync.Map
+
+	// Meta configures an extension member added to responses, see
+	// ResponseMeta.
+	Meta ResponseMeta
+
+	// Messages translates error messages to the language of the request,
+	// selected with WithLanguage or the Accept-Language header.
+	Messages MessageCatalog
+
+	// ErrorMapper, if set, translates the errors returned by handlers which
+	// aren't an *Error. A nil result falls back to a server error carrying
+	// the error message.
+	ErrorMapper func(error) *Error
+
+	// ExposeErrors sends the message of unexpected handler errors, those
+	// which aren't an *Error, to clients. By default they get a generic
+	// message and a reference to the error logged by the server, so that
+	// internal details don't leak. Meant for development.
+	ExposeErrors bool
+
+	errorCodes    sync.Map
+	hasErrorCodes int32
+
+	codecs sync.Map // reflect.Type to TypeCodec
+
+	// MaxConcurrentRequests limits the number of requests executed at the
+	// same time, zero means no limit. Up to AdmissionQueue requests wait
+	// for at most AdmissionTimeout, zero meaning as long as their context,
+	// before being answered with ErrServerBusy. See AdmissionStats.
+	MaxConcurrentRequests int
+	AdmissionQueue        int
+	AdmissionTimeout      time.Duration
+	admission             admission
+
+	// Limits bounds the size of requests and their params, see
+	// DecodeLimits.
+	Limits DecodeLimits
+
+	// IDPolicy constrains the ids of requests.
+	IDPolicy IDPolicy
+
+	// ParamsRewriters rewrite the params of requests, in order, once they
+	// are within Limits and before they are decoded.
+	ParamsRewriters []ParamsRewriter
+
+	// ResultRewriters rewrite the encoded results of calls, in order, before
+	// the responses are written. Streamed results and readers are not
+	// rewritten.
+	ResultRewriters []ResultRewriter
+
+	// TimeFormat and DurationFormat are the encodings of the time.Time and
+	// time.Duration values in params and results, those of encoding/json
+	// by default. Values are found from the types of handlers: those held
+	// by interfaces, streamed or implementing json.Marshaler aren't
+	// converted. A codec registered for either type replaces its format,
+	// see RegisterCodec.
+	TimeFormat     TimeFormat
+	DurationFormat DurationFormat
+
+	// LenientParams coerces the values of params whose type obviously
+	// mismatches the one of their field, such as "42" for an int or 1 for
+	// a bool, instead of rejecting them, for sloppy clients. Numbers and
+	// booleans are read from strings, booleans from 0 and 1, and strings
+	// from numbers and booleans. Other mismatches are still rejected.
+	LenientParams bool
+
+	// FieldMatching is how the keys of params objects are matched to the
+	// fields of params structs, case insensitively by default. Fields are
+	// named after their jsonrpc tag, such as `jsonrpc:"user_id"`, falling
+	// back to their json tag.
+	FieldMatching FieldMatching
+
+	// MaxConnections limits the number of simultaneous connections accepted
+	// by ListenAndServe and ListenAndServeTLS, zero means no limit.
+	MaxConnections int
+
+	// ReusePort sets SO_REUSEPORT on the sockets of ListenAndServe and
+	// ListenAndServeTLS, so that a new process can bind the address while
+	// the old one drains, see also Restart. Only supported on Unix systems.
+	ReusePort bool
+
+	// NotificationWorkers, if set, executes notifications in the
+	// background on that many goroutines, the response being sent as soon
+	// as they are queued. Up to NotificationQueue notifications wait for a
+	// worker, the others are dropped. With NotificationStore, queued
+	// notifications are executed after a restart, see StartNotifications.
+	NotificationWorkers int
+	NotificationQueue   int
+	NotificationStore   NotificationStore
+	notifications       notificationQueue
+
+	mu         sync.Mutex
+	httpServer *http.Server
+	listener   net.Listener
 }
 
 type handlerType struct {
-	f       reflect.Value
+	call    handlerFunc
 	ptype   reflect.Type
 	rtype   reflect.Type
 	numArgs int
+	stream  bool
+	scalar  bool
+	redact  [][]string
+	access  accessLevel
+	scopes  []string
+
+	middleware  []MethodMiddleware
+	useNumber   bool
+	deprecation *Deprecation
+
+	paramTimes, resultTimes   timePaths
+	paramCodecs, resultCodecs []typeCodec
+	paramSquash, resultSquash []squashField
+	paramNames, resultNames   fieldNames
 }
 
-// NewServer returns a new Server.
-func NewServer() *Server {
-	return &Server{}
+// ServerOption sets a field of a Server, such as WithStatsWindow setting
+// Server.StatsWindow. The fields remain the primary way of configuring a
+// Server, options only spare setting them after NewServer or
+// NewServerFromConfig.
+type ServerOption func(*Server)
+
+// NewServer returns a new Server, opts being applied in order.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // HandleFunc registers the handle function for the given JSON-RPC method.
-func (s *Server) HandleFunc(method string, handler interface{}) error {
+// Params of dynamic methods may be taken as a map[string]interface{} or an
+// interface{}, see UseNumber. Options restrict who may call it, see
+// Authenticated, or wrap it, see WithMiddleware.
+func (s *Server) HandleFunc(method string, handler interface{}, opts ...MethodOption) error {
 	h := reflect.ValueOf(handler)
 	numArgs, ptype, rtype, err := inspectHandler(h)
 	if err != nil {
 		return fmt.Errorf("jsonrpc: %v", err)
 	}
-	s.handler.Store(method, handlerType{f: h, ptype: ptype, rtype: rtype, numArgs: numArgs})
+	paramCodecs := s.typeCodecs(ptype)
+	ht := handlerType{
+		ptype:   ptype,
+		rtype:   rtype,
+		numArgs: numArgs,
+		stream:  isStreamType(rtype),
+		scalar:  isScalarParams(ptype),
+		redact:  redactedFields(ptype),
+
+		paramTimes:   s.timePaths(ptype),
+		resultTimes:  s.timePaths(rtype),
+		paramCodecs:  paramCodecs,
+		resultCodecs: s.typeCodecs(rtype),
+		paramSquash:  squashedFields(ptype),
+		resultSquash: squashedFields(rtype),
+		paramNames:   fieldNamesOf(ptype),
+		resultNames:  fieldNamesOf(rtype),
+	}
+	for _, opt := range opts {
+		opt(&ht)
+	}
+	ht.call = compileHandler(h, ptype, paramCodecs, ht.useNumber)
+	ht.wrapMiddleware()
+	s.handler.Store(method, ht)
 	return nil
 }
 
@@ -97,10 +332,15 @@ func inspectHandler(h reflect.Value) (numArgs int, ptype, rtype reflect.Type, er
 
 // ServeHTTP responds to an JSON-RPC request and executes the requested method.
 func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	if len(s.Cors) >0{
-		for k1, v1 := range s.Cors {
-			rw.Header().Add(k1,v1)
-		}
+	if s.IPFilter != nil && !s.IPFilter.check(rw, r) {
+		return
+	}
+	if s.RateLimit.PerSecond > 0 && !s.rateLimits.allow(s.RateLimit, s.clientIP(r)) {
+		tooManyRequests(rw, s.RateLimit)
+		return
+	}
+	if s.CORS.enabled() && s.CORS.handle(rw, r) {
+		return
 	}
 	// Only POST methods are jsonrpc valid calls
 	if r.Method != "POST" {
@@ -108,118 +348,540 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		rw.Write([]byte("Not found"))
 		return
 	}
+	if s.CSRF.enabled() && !s.CSRF.check(r, &s.CORS) {
+		rw.WriteHeader(http.StatusForbidden)
+		rw.Write([]byte("Forbidden"))
+		return
+	}
+	var limited *limitedBody
+	if s.Limits.MaxBytes > 0 {
+		if r.ContentLength > s.Limits.MaxBytes {
+			requestTooLarge(rw)
+			return
+		}
+		limited = newLimitedBody(r.Body, s.Limits.MaxBytes)
+		r.Body = limited
+	}
+	if s.Compression {
+		if !decodeRequestBody(rw, r) {
+			return
+		}
+		if limited != nil && r.Body != io.ReadCloser(limited) {
+			// bound the decompressed body too, against gzip bombs
+			limited = newLimitedBody(r.Body, s.Limits.MaxBytes)
+			r.Body = limited
+		}
+	}
+
+	var ctx context.Context = &httpContext{Context: r.Context(), r: r, rw: rw}
+	if s.RequestIDs {
+		id := requestID(r)
+		rw.Header().Set(RequestIDHeader, id)
+		ctx = WithRequestID(ctx, id)
+	}
+	if len(s.ContextHeaders) > 0 {
+		ctx = s.withHeaders(ctx, r)
+	}
+	if s.Tracing {
+		ctx = withSpanContext(ctx, r)
+	}
 
-	ctx := r.Context()
-	req, err := decodeRequestFromReader(r.Body)
 	defer r.Body.Close()
-	if errors.Is(err, errInvalidEncodedJSON) {
-		sendResponse(rw, errResponse(null, ErrorParseError))
+	var resps []*Response
+	var batch bool
+	if r.ContentLength > 0 && r.ContentLength <= maxFastPathSize {
+		// small bodies are read at once, which enables the fast path
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if _, err := buf.ReadFrom(r.Body); err != nil {
+			if limited != nil && limited.exceeded {
+				requestTooLarge(rw)
+				return
+			}
+			sendResponse(ctx, rw, []*Response{s.decodeError(ctx, nil, ErrorParseError)}, false)
+			return
+		}
+		resps, batch = s.handleBytes(ctx, buf.Bytes())
+	} else {
+		resps, batch = s.handle(ctx, r.Body)
+	}
+	defer releaseResponses(resps)
+	if limited != nil && limited.exceeded {
+		requestTooLarge(rw)
 		return
 	}
-	if errors.Is(err, errInvalidDecodedMessage) {
-		sendResponse(rw, errResponse(req.ID, ErrInvalidRequest))
+	if len(resps) == 0 {
+		// notifications, alone or in a batch, are not answered
+		rw.WriteHeader(http.StatusNoContent)
 		return
 	}
-
-	method, ok := s.handler.Load(req.Method)
-	if !ok {
-		sendResponse(rw, errResponse(req.ID, ErrMethodNotFound))
+	if !batch && resps[0].stream.IsValid() {
+		sendStream(ctx, rw, resps[0], s.StreamFlushInterval)
+		return
+	}
+	if !batch && resps[0].reader != nil {
+		// write directly to the connection instead of buffering the result
+		bw := bufio.NewWriter(rw)
+		if err := resps[0].encode(bw); err != nil {
+			log.Printf("jsonrpc: sending response%s: %v", logID(ctx), err)
+		}
+		if err := bw.Flush(); err != nil {
+			log.Printf("jsonrpc: sending response%s: %v", logID(ctx), err)
+		}
 		return
 	}
+	if s.Compression && acceptsGzip(r) {
+		sendCompressedResponse(ctx, rw, resps, batch)
+		return
+	}
+	sendResponse(ctx, rw, resps, batch)
+}
+
+// ServeMessage executes the JSON-RPC request, or batch of requests, encoded
+// in msg and returns the encoded response. It returns nil when there is
+// nothing to answer, i.e. for notifications. ServeMessage lets non-HTTP
+// transports reuse the handlers registered in s.
+func (s *Server) ServeMessage(ctx context.Context, msg []byte) []byte {
+	var resps []*Response
+	var batch bool
+	if s.Limits.MaxBytes > 0 && int64(len(msg)) > s.Limits.MaxBytes {
+		resps = []*Response{s.decodeError(ctx, nil, limitError("bytes", int(s.Limits.MaxBytes)))}
+	} else {
+		resps, batch = s.handleBytes(ctx, msg)
+	}
+	defer releaseResponses(resps)
+	if len(resps) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := encodeResponses(&buf, resps, batch); err != nil {
+		log.Printf("jsonrpc: encoding response%s: %v", logID(ctx), err)
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// Invoke executes method with the JSON encoded params and returns its JSON
+// encoded result. Errors are always of type *Error. Invoke bypasses the
+// JSON-RPC envelope, which makes it a good fit for bridges whose protocol
+// already carries a method name, like a generic gRPC service.
+func (s *Server) Invoke(ctx context.Context, method string, params []byte) ([]byte, error) {
+	req := getRequest()
+	req.ID, req.Method, req.Params = 0, method, params
+	resp := s.dispatch(ctx, req)
+	releaseRequest(req)
+	defer releaseResponses([]*Response{resp})
+	if resp.error != nil {
+		return nil, resp.error
+	}
+	if resp.stream.IsValid() {
+		return collectStream(resp.stream)
+	}
+	if resp.reader != nil {
+		var buf bytes.Buffer
+		if err := copyReaderResult(&buf, resp.reader, resp.rawReader); err != nil {
+			return nil, ErrInternalError
+		}
+		return buf.Bytes(), nil
+	}
+	return resp.result, nil
+}
+
+// maxFastPathSize is the largest HTTP body read at once to be served by
+// handleBytes.
+const maxFastPathSize = 64 << 10
+
+// handleBytes is like handle for a message held in memory. Single requests,
+// by far the most common case, are unmarshaled directly instead of going
+// through the streaming decoder.
+func (s *Server) handleBytes(ctx context.Context, msg []byte) ([]*Response, bool) {
+	if firstByte(msg) != '{' {
+		return s.handle(ctx, bytes.NewReader(msg))
+	}
+	req, err := unmarshalRequest(msg)
+	if errors.Is(err, errInvalidEncodedJSON) {
+		return []*Response{s.decodeError(ctx, nil, ErrorParseError)}, false
+	}
+	defer releaseRequest(req)
+	if errors.Is(err, errInvalidDecodedMessage) {
+		return []*Response{s.decodeError(ctx, req, ErrInvalidRequest)}, false
+	}
+	if err := s.IDPolicy.check(req); err != nil {
+		return []*Response{s.decodeError(ctx, req, err)}, false
+	}
+	if resp := s.dispatch(ctx, req); resp != nil {
+		return []*Response{resp}, false
+	}
+	return nil, false
+}
 
+// handle decodes a request or a batch of requests from body and executes
+// them. A batch is decoded and validated as a whole before its entries are
+// executed, so that a malformed batch has no effect. It returns the
+// responses to send, there are none for notifications, and whether body
+// contained a batch.
+func (s *Server) handle(ctx context.Context, body io.Reader) ([]*Response, bool) {
+	first, body, err := peekReader(body)
+	if err != nil {
+		return []*Response{s.decodeError(ctx, nil, ErrorParseError)}, false
+	}
+	dec := json.NewDecoder(body)
+	if first != '[' {
+		resp := s.handleRequest(ctx, dec)
+		if resp == nil {
+			return nil, false
+		}
+		return []*Response{resp}, false
+	}
+
+	// consume the opening bracket
+	if _, err := dec.Token(); err != nil {
+		return []*Response{s.decodeError(ctx, nil, ErrorParseError)}, false
+	}
+	type entry struct {
+		req *request
+		err error
+	}
+	var entries []entry
+	defer func() {
+		for _, e := range entries {
+			releaseRequest(e.req)
+		}
+	}()
+	for dec.More() {
+		req, err := decodeRequest(dec)
+		if errors.Is(err, errInvalidEncodedJSON) {
+			// the rest of the batch can't be decoded
+			return []*Response{s.decodeError(ctx, nil, ErrorParseError)}, false
+		}
+		entries = append(entries, entry{req, err})
+	}
+	if _, err := dec.Token(); err != nil || trailingData(dec) {
+		return []*Response{s.decodeError(ctx, nil, ErrorParseError)}, false
+	}
+	if len(entries) == 0 {
+		resp := s.decodeError(ctx, nil, ErrInvalidRequest)
+		s.Hooks.onBatch(ctx, 0, 0)
+		return []*Response{resp}, false
+	}
+	var resps []*Response
+	for _, e := range entries {
+		var resp *Response
+		if errors.Is(e.err, errInvalidDecodedMessage) {
+			resp = s.decodeError(ctx, e.req, ErrInvalidRequest)
+		} else if err := s.IDPolicy.check(e.req); err != nil {
+			resp = s.decodeError(ctx, e.req, err)
+		} else {
+			resp = s.dispatch(ctx, e.req)
+		}
+		if resp != nil && e.err == nil && e.req.isNotification && !e.req.nullID {
+			// notifications aren't answered in batches, so that a
+			// batch of notifications gets no content
+			log.Printf("jsonrpc: notification: dropping %v: %v", e.req.Method, resp.Err())
+			releaseResponses([]*Response{resp})
+			resp = nil
+		}
+		if resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	s.Hooks.onBatch(ctx, len(entries), len(resps))
+	return resps, true
+}
+
+// handleRequest decodes the request held by dec and executes it. Like
+// handleBytes, it rejects anything following the request.
+func (s *Server) handleRequest(ctx context.Context, dec *json.Decoder) *Response {
+	req, err := decodeRequest(dec)
+	if errors.Is(err, errInvalidEncodedJSON) {
+		return s.decodeError(ctx, nil, ErrorParseError)
+	}
+	defer releaseRequest(req)
+	if trailingData(dec) {
+		return s.decodeError(ctx, nil, ErrorParseError)
+	}
+	if errors.Is(err, errInvalidDecodedMessage) {
+		return s.decodeError(ctx, req, ErrInvalidRequest)
+	}
+	if err := s.IDPolicy.check(req); err != nil {
+		return s.decodeError(ctx, req, err)
+	}
+	return s.dispatch(ctx, req)
+}
+
+// trailingData reports whether dec holds anything but whitespace after the
+// value decoded last.
+func trailingData(dec *json.Decoder) bool {
+	_, err := dec.Token()
+	return err != io.EOF
+}
+
+// call executes the handler of method.
+// Panics are recovered and returned as a *panicError.
+func (s *Server) call(ctx context.Context, req *request, htype handlerType) (ret interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			ret, err = nil, s.recoverPanic(ctx, req, v)
+		}
+	}()
+	if s.SlowCallThreshold > 0 {
+		defer s.checkSlowCall(ctx, req, time.Now())
+	}
+	if !s.Profiling {
+		return htype.call(ctx, req.Params)
+	}
+	p := startProfile()
+	defer s.profiles.record(req.Method, p)
+	return htype.call(ctx, req.Params)
+}
+
+// dispatch executes the method requested by req and fires the lifecycle
+// hooks. The returned Response is nil for notifications.
+func (s *Server) dispatch(ctx context.Context, req *request) *Response {
+	hooks := s.Hooks.enabled()
+	if !hooks && s.Audit == nil && s.Meta.Member == "" && s.StatsWindow <= 0 {
+		resp, _ := s.execute(ctx, req)
+		s.localizeError(ctx, resp)
+		return resp
+	}
+	var info *RequestInfo
+	if hooks {
+		info = s.newRequestInfo(req)
+		s.Hooks.onRequest(ctx, info)
+	}
+	var meta *metaValues
+	if s.Meta.Member != "" && !req.isNotification {
+		meta = &metaValues{}
+		ctx = context.WithValue(ctx, metaKey{}, meta)
+	}
+	start := time.Now()
+	resp, err := s.execute(ctx, req)
+	s.localizeError(ctx, resp)
+	if meta != nil && resp != nil {
+		s.setMeta(resp, meta, time.Since(start))
+	}
+	if s.StatsWindow > 0 {
+		s.recordStats(req, time.Since(start), err)
+	}
+	if s.Audit != nil {
+		s.Audit.record(ctx, req, s.Redact, resp, start, err)
+	}
+	if hooks {
+		s.Hooks.onDone(ctx, info, resp, err)
+	}
+	return resp
+}
+
+// execute executes the method requested by req. The returned Response is nil
+// for notifications, the returned error is the cause of the failure if any,
+// as returned by the handler.
+func (s *Server) execute(ctx context.Context, req *request) (*Response, error) {
+	name := req.Method
+	method, ok := s.handler.Load(name)
+	if !ok {
+		if name, ok = s.resolveVersion(ctx, req); ok {
+			method, ok = s.handler.Load(name)
+		}
+	}
+	if !ok {
+		return errResponse(req.ID, ErrMethodNotFound), ErrMethodNotFound
+	}
 	htype, _ := method.(handlerType)
+	warnDeprecated(ctx, name, &htype)
+
+	if err := s.authorize(ctx, &htype); err != nil {
+		if req.isNotification {
+			log.Printf("jsonrpc: notification: dropping %v: %v", req.Method, err.Message)
+			return nil, err
+		}
+		return errResponse(req.ID, err), err
+	}
+
+	if err := s.Limits.check(req.Params); err != nil {
+		if req.isNotification {
+			log.Print("jsonrpc: notification: ", err)
+			return nil, err
+		}
+		return errResponse(req.ID, err), err
+	}
+
+	if err := s.rewriteParams(ctx, req, &htype); err != nil {
+		if req.isNotification {
+			log.Printf("jsonrpc: notification: dropping %v: %v", req.Method, err)
+			return nil, err
+		}
+		return errResponse(req.ID, err), err
+	}
+
+	if req.isNotification && s.NotificationWorkers > 0 {
+		if err := s.queueNotification(ctx, name, req.Params); err != nil {
+			log.Printf("jsonrpc: notification: dropping %v: %v", req.Method, err)
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	release, ok := s.admit(ctx)
+	if !ok {
+		if req.isNotification {
+			log.Printf("jsonrpc: notification: dropping %v: server busy", req.Method)
+			return nil, ErrServerBusy
+		}
+		return errResponse(req.ID, ErrServerBusy), ErrServerBusy
+	}
+	// streamed results hold their slot until written, see Response.done
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			release()
+		}
+	}()
+
 	if req.isNotification {
-		_, err := callMethod(ctx, req, htype)
+		var err error
+		handedOff, err = s.callNotification(ctx, req, htype, release)
 		if errors.Is(err, errServerInvalidParams) {
 			log.Print("jsonrpc: notification: ", err)
-			return
+			err = WithCause(invalidParamsError(err), err)
 		}
-		rw.WriteHeader(http.StatusOK)
-		rw.Write([]byte(""))
-		return
+		return nil, err
 	}
 
-	ret, err := callMethod(ctx, req, htype)
+	ret, err := s.call(ctx, req, htype)
 	if errors.Is(err, errServerInvalidParams) {
-		sendResponse(rw, errResponse(req.ID, ErrInvalidParams))
-		return
+		rpcErr := invalidParamsError(err)
+		return errResponse(req.ID, rpcErr), WithCause(rpcErr, err)
+	}
+	if _, ok := err.(*panicError); ok {
+		return errResponse(req.ID, ErrInternalError), err
+	}
+	if c := reflect.ValueOf(ret); htype.stream && err == nil && (!c.IsValid() || c.IsNil()) {
+		// answered as collectStream does
+		resp := getResponse()
+		resp.id, resp.result = req.ID, emptyArray
+		return resp, nil
+	}
+	if htype.stream && err == nil {
+		resp := getResponse()
+		resp.id, resp.stream, resp.done = req.ID, reflect.ValueOf(ret), release
+		handedOff = true
+		return resp, nil
+	}
+	if rd, raw, ok := readerResult(ret); ok {
+		if err != nil {
+			closeReader(rd)
+		} else {
+			resp := getResponse()
+			resp.id, resp.reader, resp.rawReader, resp.done = req.ID, rd, raw, release
+			handedOff = true
+			return resp, nil
+		}
 	}
 
-	result, err := encodeMethodReturn(ret)
-	if errors.Is(err, errServerInvalidReturn) {
-		sendResponse(rw, errResponse(req.ID, ErrInternalError))
-		return
+	result, encErr := s.encodeMethodReturn(ctx, req, ret, err)
+	if encErr == nil && s.convertsTimes(htype.resultTimes) {
+		if result, encErr = s.encodeTimes(result, htype.resultTimes); encErr != nil {
+			log.Printf("jsonrpc: encoding times of %v: %v", req.Method, encErr)
+			encErr = errServerInvalidReturn
+		}
 	}
-	if err, ok := err.(*Error); ok {
-		sendResponse(rw, errResponse(req.ID, err))
-		return
+	if encErr == nil && len(htype.resultCodecs) > 0 {
+		if result, encErr = encodeCodecs(result, ret, htype.resultCodecs); encErr != nil {
+			log.Printf("jsonrpc: encoding result of %v: %v", req.Method, encErr)
+			encErr = errServerInvalidReturn
+		}
+	}
+	if encErr == nil && len(htype.resultSquash) > 0 {
+		if result, encErr = squash(result, htype.resultSquash); encErr != nil {
+			log.Printf("jsonrpc: encoding result of %v: %v", req.Method, encErr)
+			encErr = errServerInvalidReturn
+		}
+	}
+	if encErr == nil && htype.resultNames.renamed {
+		if result, encErr = encodeFieldNames(result, htype.resultNames); encErr != nil {
+			log.Printf("jsonrpc: encoding result of %v: %v", req.Method, encErr)
+			encErr = errServerInvalidReturn
+		}
+	}
+	if encErr == nil && len(s.ResultRewriters) > 0 {
+		if result, err = s.rewriteResult(ctx, req.Method, result); err != nil {
+			result, encErr = s.encodeMethodReturn(ctx, req, nil, err)
+		}
+	}
+	if errors.Is(encErr, errServerInvalidReturn) {
+		return errResponse(req.ID, ErrInternalError), encErr
+	}
+	if rpcErr, ok := encErr.(*Error); ok {
+		setRetryAfter(ctx, err)
+		if !s.checkErrorCode(req.Method, rpcErr) {
+			return errResponse(req.ID, ErrInternalError), err
+		}
+		return errResponse(req.ID, rpcErr), err
 	}
 
-	sendResponse(rw, &Response{
-		id:     req.ID,
-		error:  nil,
-		result: (json.RawMessage)(result),
-	})
+	resp := getResponse()
+	resp.id, resp.result = req.ID, result
+	return resp, nil
 }
 
-func sendResponse(rw http.ResponseWriter, resp *Response) {
-	b, err := resp.bytes()
-	if err != nil {
-		log.Printf("jsonrpc: sending response: %v", err)
+func sendResponse(ctx context.Context, rw http.ResponseWriter, resps []*Response, batch bool) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeResponses(buf, resps, batch); err != nil {
+		log.Printf("jsonrpc: sending response%s: %v", logID(ctx), err)
 		return
 	}
-	_, err = rw.Write(b)
-	if err != nil {
-		log.Printf("jsonrpc: sending response: %v", err)
-	}
+	writeResponse(ctx, rw, buf.Bytes())
 }
 
-func callMethod(ctx context.Context, req *request, htype handlerType) ([]reflect.Value, error) {
-	var retv []reflect.Value
-	if htype.numArgs == 1 {
-		retv = htype.f.Call([]reflect.Value{reflect.ValueOf(ctx)})
-		return retv, nil
+func writeResponse(ctx context.Context, rw http.ResponseWriter, b []byte) {
+	rw.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	if _, err := rw.Write(b); err != nil {
+		log.Printf("jsonrpc: sending response%s: %v", logID(ctx), err)
 	}
+}
 
-	var pvalue, pzero reflect.Value
-	pIsValue := false
-	if htype.ptype.Kind() == reflect.Ptr {
-		pvalue = reflect.New(htype.ptype.Elem())
-		pzero = reflect.New(htype.ptype.Elem())
-	} else {
-		pvalue = reflect.New(htype.ptype)
-		pzero = reflect.New(htype.ptype)
-		pIsValue = true
+// callNotification calls the handler of the notification req, whose result
+// nobody reads. Readers are closed, and streams are cancelled once the
+// handler returns, what they still send being drained before release is
+// called. It reports whether release was handed off that way.
+func (s *Server) callNotification(ctx context.Context, req *request, htype handlerType, release func()) (bool, error) {
+	var cancel context.CancelFunc
+	if htype.stream {
+		ctx, cancel = context.WithCancel(ctx)
 	}
-
-	// here pvalue is guaranteed to be a ptr
-	// QUESTION: if pvalue doesnt change params should be invalid?
-	if req.Params == nil || string(req.Params) == string(null) {
-		return nil, errServerInvalidParams
+	ret, err := s.call(ctx, req, htype)
+	if rd, _, ok := readerResult(ret); ok {
+		closeReader(rd)
 	}
-	if err := json.Unmarshal(req.Params, pvalue.Interface()); err != nil || pvalue.Elem().Interface() == pzero.Elem().Interface() {
-		return nil, errServerInvalidParams
+	if cancel == nil {
+		return false, err
 	}
-
-	if pIsValue {
-		retv = htype.f.Call([]reflect.Value{reflect.ValueOf(ctx), pvalue.Elem()})
-	} else {
-		retv = htype.f.Call([]reflect.Value{reflect.ValueOf(ctx), pvalue})
+	cancel()
+	c := reflect.ValueOf(ret)
+	if !c.IsValid() || c.IsNil() {
+		return false, err
 	}
-	return retv, nil
+	go func() {
+		drainStream(c)
+		release()
+	}()
+	return true, err
 }
 
-func encodeMethodReturn(ret []reflect.Value) (json.RawMessage, error) {
-	outErr := ret[1].Interface()
-	switch err := outErr.(type) {
-	case *Error:
-		return nil, err
-	case error:
-		return nil, &Error{Code: -32000, Message: err.Error()}
-	default:
+func (s *Server) encodeMethodReturn(ctx context.Context, req *request, ret interface{}, outErr error) (json.RawMessage, error) {
+	if outErr != nil {
+		err, ok := AsError(outErr)
+		if !ok && s.ErrorMapper != nil {
+			err = s.ErrorMapper(outErr)
+		}
+		if err == nil {
+			err = s.serverError(ctx, req, outErr)
+		}
+		return nil, withErrorData(err, outErr)
 	}
 
-	result, err := json.Marshal(ret[0].Interface())
+	result, err := json.Marshal(ret)
 	if err != nil {
 		// this should not happen if the output is well defined
 		return nil, errServerInvalidReturn
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -161,6 +164,56 @@ var serveTestcases = []testcase{
 			return *s, nil
 		},
 	},
+	{
+		id:      34,
+		numArgs: 2,
+		name:    "rawparams_interface",
+		params:  []int{1, 2, 3},
+		resp:    `{"jsonrpc":"2.0","id":34,"result":[1,2,3]}`,
+		f: func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return params, nil
+		},
+	},
+	{
+		id:      35,
+		numArgs: 2,
+		name:    "slice_int",
+		params:  []int{1, 2, 3},
+		resp:    `{"jsonrpc":"2.0","id":35,"result":3}`,
+		f: func(ctx context.Context, s []int) (int, error) {
+			return len(s), nil
+		},
+	},
+	{
+		id:      36,
+		numArgs: 2,
+		name:    "map_interface",
+		params:  json.RawMessage(`{"id":42,"name":"x"}`),
+		resp:    `{"jsonrpc":"2.0","id":36,"result":{"id":42}}`,
+		f: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return map[string]interface{}{"id": params["id"]}, nil
+		},
+	},
+	{
+		id:      37,
+		numArgs: 2,
+		name:    "interface_interface",
+		params:  []interface{}{1, "a", nil},
+		resp:    `{"jsonrpc":"2.0","id":37,"result":[1,"a",null]}`,
+		f: func(ctx context.Context, params interface{}) (interface{}, error) {
+			return params, nil
+		},
+	},
+	{
+		id:      38,
+		numArgs: 2,
+		name:    "ptrmap_int",
+		params:  map[string]int{"a": 1, "b": 2},
+		resp:    `{"jsonrpc":"2.0","id":38,"result":2}`,
+		f: func(ctx context.Context, params *map[string]interface{}) (int, error) {
+			return len(*params), nil
+		},
+	},
 	{
 		id:      nil,
 		numArgs: 2,
@@ -214,7 +267,7 @@ var serveErrTestcases = []testcase{
 		numArgs: 2,
 		name:    "invalid_params",
 		req:     `{"jsonrpc":"2.0","id":1,"method":"invalid_params","params":[1,2]}`,
-		resp:    `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`,
+		resp:    `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"pointer":"","expected":"string","got":[1,2]}}}`,
 		f: func(ctx context.Context, s string) (string, error) {
 			return "string", nil
 		},
@@ -340,6 +393,9 @@ func TestServeErr(t *testing.T) {
 			if got := rw.Body.String(); got != want {
 				t.Errorf("invalid jsonrpc response: \ngot: %v\nwant: %v\n", got, want)
 			}
+			if got, want := rw.Header().Get("Content-Length"), strconv.Itoa(len(want)); got != want {
+				t.Errorf("invalid content length: got %v, want %v", got, want)
+			}
 		})
 	}
 }
@@ -353,6 +409,7 @@ func TestServeSync(t *testing.T) {
 	}
 
 	server := NewServer()
+	server.ExposeErrors = true
 	for _, h := range serveTestcases {
 		server.HandleFunc(h.name, h.f)
 	}
@@ -389,6 +446,7 @@ func TestServeAsync(t *testing.T) {
 	}
 
 	server := NewServer()
+	server.ExposeErrors = true
 	for _, h := range serveTestcases {
 		server.HandleFunc(h.name, h.f)
 	}
@@ -421,3 +479,170 @@ func TestServeAsync(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestInvoke(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	result, err := server.Invoke(context.Background(), "sum", []byte(`{"A":1,"B":2}`))
+	if err != nil {
+		t.Fatalf("sum: error not expected: %v", err)
+	}
+	if want := `{"C":3}`; string(result) != want {
+		t.Errorf("invalid result:\ngot: %s\nwant: %v", result, want)
+	}
+
+	_, err = server.Invoke(context.Background(), "unknown", nil)
+	if e, ok := err.(*Error); !ok || *e != *ErrMethodNotFound {
+		t.Errorf("unknown method:\ngot: %v\nwant: ErrMethodNotFound", err)
+	}
+}
+
+func TestServeBatch(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("sum", sum)
+
+	tests := []struct {
+		name   string
+		req    string
+		status int
+		resp   string
+	}{
+		{
+			name:   "mixed",
+			status: 200,
+			req:    `[{"jsonrpc":"2.0","id":1,"method":"sum","params":{"A":1,"B":2}},{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}},1,{"jsonrpc":"2.0","id":2,"method":"unknown"}]`,
+			resp:   `[{"jsonrpc":"2.0","id":1,"result":{"C":3}},{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request"}},{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found"}}]`,
+		},
+		{
+			name:   "whitespace",
+			status: 200,
+			req:    " \n\t[{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"sum\",\"params\":{\"A\":2,\"B\":2}}]",
+			resp:   `[{"jsonrpc":"2.0","id":1,"result":{"C":4}}]`,
+		},
+		{
+			name:   "notifications",
+			status: 204,
+			req:    `[{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}},{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}]`,
+			resp:   ``,
+		},
+		{
+			name:   "unknown_notifications",
+			status: 204,
+			req:    `[{"jsonrpc":"2.0","method":"unknown"},{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}}]`,
+			resp:   ``,
+		},
+		{
+			name:   "empty",
+			status: 200,
+			req:    `[]`,
+			resp:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request"}}`,
+		},
+		{
+			name:   "invalid_json",
+			status: 200,
+			req:    `[{"jsonrpc":"2.0","method":"sum","params":{"A":1,"B":2}},{"jsonrpc"`,
+			resp:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "locahost:8080", bytes.NewReader([]byte(tc.req)))
+			rw := httptest.NewRecorder()
+			server.ServeHTTP(rw, req)
+
+			if rw.Code != tc.status {
+				t.Errorf("invalid status:\ngot: %v\nwant: %v", rw.Code, tc.status)
+			}
+			if got := rw.Body.String(); got != tc.resp {
+				t.Errorf("invalid jsonrpc response: \ngot: %v\nwant: %v\n", got, tc.resp)
+			}
+		})
+	}
+}
+
+func TestServeBatchMalformed(t *testing.T) {
+	server := NewServer()
+	var calls int32
+	server.HandleFunc("incr", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	for _, req := range []string{
+		`[{"jsonrpc":"2.0","id":1,"method":"incr"},{"jsonrpc":"2.0","method":"incr"},{"jsonrpc"`,
+		`[{"jsonrpc":"2.0","id":1,"method":"incr"},{"jsonrpc":"2.0","method":"incr"}`,
+	} {
+		resp := server.ServeMessage(context.Background(), []byte(req))
+		if want := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`; string(resp) != want {
+			t.Errorf("%v:\ngot: %s\nwant: %v", req, resp, want)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("malformed batches ran %v calls, want none", n)
+	}
+}
+
+func TestServeTrailingData(t *testing.T) {
+	server := NewServer()
+	var calls int32
+	server.HandleFunc("incr", func(ctx context.Context) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		return true, nil
+	})
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"incr"} garbage`,
+		`{"jsonrpc":"2.0","id":1,"method":"incr"}{"jsonrpc":"2.0","id":2,"method":"incr"}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"incr"}] garbage`,
+	} {
+		// with a known length, bodies are served by handleBytes, by handle
+		// otherwise
+		for _, length := range []int64{int64(len(req)), -1} {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(req))
+			r.ContentLength = length
+			rw := httptest.NewRecorder()
+			server.ServeHTTP(rw, r)
+			if want := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`; rw.Body.String() != want {
+				t.Errorf("%v with length %v:\ngot: %s\nwant: %v", req, length, rw.Body, want)
+			}
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("requests with trailing data ran %v calls, want none", n)
+	}
+
+	// trailing whitespace is fine
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"incr"}`+"\n"))
+	r.ContentLength = -1
+	rw := httptest.NewRecorder()
+	server.ServeHTTP(rw, r)
+	if want := `{"jsonrpc":"2.0","id":1,"result":true}`; rw.Body.String() != want {
+		t.Errorf("trailing newline:\ngot: %s\nwant: %v", rw.Body, want)
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	server := NewServer()
+	echoID := func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["id"], nil
+	}
+	server.HandleFunc("float", echoID)
+	server.HandleFunc("number", echoID, UseNumber())
+	server.HandleFunc("ptr", func(ctx context.Context, params *[]interface{}) (interface{}, error) {
+		return (*params)[0], nil
+	}, UseNumber())
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"float","params":{"id":12345678901234567890}}`, `{"jsonrpc":"2.0","id":1,"result":12345678901234567000}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"number","params":{"id":12345678901234567890}}`, `{"jsonrpc":"2.0","id":1,"result":12345678901234567890}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"ptr","params":[12345678901234567890]}`, `{"jsonrpc":"2.0","id":1,"result":12345678901234567890}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
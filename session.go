@@ -0,0 +1,302 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load for unknown or expired
+// sessions.
+var ErrSessionNotFound = errors.New("jsonrpc: session not found")
+
+// SessionStore stores the values of sessions by id. Implementations backed
+// by an external database, such as Redis, typically encode the values as
+// JSON.
+type SessionStore interface {
+	Load(ctx context.Context, id string) (map[string]interface{}, error)
+	// Save stores values, replacing the previous ones, for ttl.
+	Save(ctx context.Context, id string, values map[string]interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionConfig configures the Sessions middleware.
+type SessionConfig struct {
+	Store SessionStore
+	// Cookie is the name of the cookie carrying the session id, defaults
+	// to "session" unless Header is set.
+	Cookie string
+	// Header, if set, is the header carrying the session id, for clients
+	// which don't keep cookies. The id of new sessions is returned in the
+	// same header.
+	Header string
+	// TTL is how long sessions live after their last change, defaults to
+	// 24h.
+	TTL time.Duration
+}
+
+// Session holds values kept between the requests of a client, see Sessions.
+// It is safe for concurrent use by the handlers of a batch.
+type Session struct {
+	mu        sync.Mutex
+	id        string
+	oldID     string
+	values    map[string]interface{}
+	changed   bool
+	destroyed bool
+}
+
+type sessionKey struct{}
+
+// GetSession returns the session of the client making the request of ctx,
+// nil if the Sessions middleware isn't used.
+func GetSession(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// ID returns the id of s, empty for a new session until the response is
+// written.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get returns the value of key, nil if unset.
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set sets the value of key.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed, s.destroyed = true, false
+}
+
+// Delete removes the value of key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.changed = true
+}
+
+// Renew gives s a new id, keeping its values. Call it when the privileges of
+// the client change, like on login, to defeat session fixation.
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = ""
+	s.changed = true
+}
+
+// Destroy removes s and its values, like on logout.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]interface{})
+	s.destroyed, s.changed = true, false
+}
+
+// Sessions returns a middleware giving the handlers of the requests it
+// serves a session, see GetSession. Sessions are identified by a cookie or
+// a header and saved in cfg.Store before the response is written, when they
+// changed.
+//
+//	http.Handle("/rpc", jsonrpc.Sessions(cfg)(server))
+func Sessions(cfg SessionConfig) Middleware {
+	if cfg.Cookie == "" && cfg.Header == "" {
+		cfg.Cookie = "session"
+	}
+	cfg.TTL = durationOr(cfg.TTL, 24*time.Hour)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			sess, err := cfg.load(r)
+			if err != nil {
+				log.Printf("jsonrpc: loading session: %v", err)
+				http.Error(rw, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			sw := &sessionWriter{ResponseWriter: rw, r: r, cfg: &cfg, sess: sess}
+			h.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), sessionKey{}, sess)))
+			sw.commit()
+		})
+	}
+}
+
+func (cfg *SessionConfig) load(r *http.Request) (*Session, error) {
+	var id string
+	if cfg.Header != "" {
+		id = r.Header.Get(cfg.Header)
+	}
+	if cookie, err := r.Cookie(cfg.Cookie); id == "" && cfg.Cookie != "" && err == nil {
+		id = cookie.Value
+	}
+	sess := &Session{values: make(map[string]interface{})}
+	if id == "" {
+		return sess, nil
+	}
+	values, err := cfg.Store.Load(r.Context(), id)
+	if errors.Is(err, ErrSessionNotFound) {
+		return sess, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if values != nil {
+		sess.values = values
+	}
+	sess.id = id
+	return sess, nil
+}
+
+// sessionWriter saves the session before the response is written, so that
+// the cookie or header of new sessions can still be set.
+type sessionWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	cfg       *SessionConfig
+	sess      *Session
+	committed bool
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	w.commit()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *sessionWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	s, cfg, ctx := w.sess, w.cfg, w.r.Context()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID != "" {
+		if err := cfg.Store.Delete(ctx, s.oldID); err != nil {
+			log.Printf("jsonrpc: deleting session: %v", err)
+		}
+	}
+	switch {
+	case s.destroyed:
+		if s.id != "" {
+			if err := cfg.Store.Delete(ctx, s.id); err != nil {
+				log.Printf("jsonrpc: deleting session: %v", err)
+			}
+		}
+		w.setID("", -1)
+	case s.changed:
+		if s.id == "" {
+			s.id = randomID(32)
+		}
+		if err := cfg.Store.Save(ctx, s.id, s.values, cfg.TTL); err != nil {
+			log.Printf("jsonrpc: saving session: %v", err)
+			return
+		}
+		w.setID(s.id, int(cfg.TTL/time.Second))
+	}
+}
+
+func (w *sessionWriter) setID(id string, maxAge int) {
+	if w.cfg.Header != "" {
+		w.Header().Set(w.cfg.Header, id)
+	}
+	if w.cfg.Cookie != "" {
+		http.SetCookie(w.ResponseWriter, &http.Cookie{
+			Name:     w.cfg.Cookie,
+			Value:    id,
+			Path:     "/",
+			MaxAge:   maxAge,
+			HttpOnly: true,
+			Secure:   w.r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// MemorySessionStore is a SessionStore keeping sessions in memory, for tests
+// and single instance deployments. Expired sessions are removed as new ones
+// are saved.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	saves    int
+}
+
+type memorySession struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Load implements SessionStore.
+func (m *MemorySessionStore) Load(ctx context.Context, id string) (map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || time.Now().After(s.expires) {
+		return nil, ErrSessionNotFound
+	}
+	return copyValues(s.values), nil
+}
+
+// Save implements SessionStore.
+func (m *MemorySessionStore) Save(ctx context.Context, id string, values map[string]interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if m.saves++; m.saves%1000 == 0 {
+		for id, s := range m.sessions {
+			if now.After(s.expires) {
+				delete(m.sessions, id)
+			}
+		}
+	}
+	m.sessions[id] = memorySession{values: copyValues(values), expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements SessionStore.
+func (m *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,98 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessions(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("login", func(ctx context.Context, user string) (bool, error) {
+		s := GetSession(ctx)
+		s.Renew()
+		s.Set("user", user)
+		return true, nil
+	})
+	server.HandleFunc("whoami", func(ctx context.Context) (interface{}, error) {
+		return GetSession(ctx).Get("user"), nil
+	})
+	server.HandleFunc("logout", func(ctx context.Context) (bool, error) {
+		GetSession(ctx).Destroy()
+		return true, nil
+	})
+	store := NewMemorySessionStore()
+	ts := httptest.NewServer(Sessions(SessionConfig{Store: store})(server))
+	defer ts.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := NewClient(ts.URL, WithHTTPClient(&http.Client{Jar: jar}))
+	whoami := func() interface{} {
+		var user interface{}
+		resp, err := client.Call(context.Background(), "whoami", nil)
+		if err != nil {
+			t.Fatalf("whoami: %v", err)
+		}
+		resp.Decode(&user)
+		return user
+	}
+
+	if user := whoami(); user != nil {
+		t.Errorf("user before login: %v", user)
+	}
+	if len(store.sessions) != 0 {
+		t.Errorf("unchanged session saved")
+	}
+	if _, err := client.Call(context.Background(), "login", "gopher"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if user := whoami(); user != "gopher" {
+		t.Errorf("invalid user after login:\ngot: %v\nwant: gopher", user)
+	}
+	if _, err := client.Call(context.Background(), "login", "gopher"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if len(store.sessions) != 1 {
+		t.Errorf("renewed session kept: %v sessions", len(store.sessions))
+	}
+	if _, err := client.Call(context.Background(), "logout", nil); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if user := whoami(); user != nil {
+		t.Errorf("user after logout: %v", user)
+	}
+	if len(store.sessions) != 0 {
+		t.Errorf("destroyed session kept")
+	}
+}
+
+func TestSessionHeader(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("count", func(ctx context.Context) (int, error) {
+		s := GetSession(ctx)
+		n, _ := s.Get("n").(int)
+		s.Set("n", n+1)
+		return n + 1, nil
+	})
+	h := Sessions(SessionConfig{Store: NewMemorySessionStore(), Header: "X-Session"})(server)
+
+	var id string
+	for i := 1; i <= 3; i++ {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"count"}`))
+		r.Header.Set("X-Session", id)
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		if want := `{"jsonrpc":"2.0","id":1,"result":` + string(rune('0'+i)) + `}`; strings.TrimSpace(rw.Body.String()) != want {
+			t.Errorf("call %v:\ngot: %v\nwant: %v", i, rw.Body, want)
+		}
+		if id = rw.Header().Get("X-Session"); id == "" {
+			t.Fatalf("call %v: no session id", i)
+		}
+		if len(rw.Result().Cookies()) != 0 {
+			t.Errorf("cookie set in header mode")
+		}
+	}
+}
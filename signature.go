@@ -0,0 +1,173 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of signed requests, see WithSigning and VerifySignatures.
+const (
+	SignatureKeyHeader       = "X-Signature-Key"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	SignatureHeader          = "X-Signature"
+)
+
+// DefaultSignatureSkew is the default of SignatureConfig.MaxSkew.
+const DefaultSignatureSkew = 5 * time.Minute
+
+// DefaultSignatureMaxBody is the default of SignatureConfig.MaxBody.
+const DefaultSignatureMaxBody = 1 << 20
+
+// sign returns the hex encoded HMAC-SHA256 of a request body signed at
+// timestamp with nonce.
+func sign(secret []byte, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(nonce))
+	mac.Write([]byte{'\n'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WithSigning signs the requests of the client with secret, shared with the
+// server under keyID, see VerifySignatures.
+func WithSigning(keyID string, secret []byte) ClientOption {
+	return func(c *Client) {
+		c.signKeyID, c.signSecret = keyID, secret
+	}
+}
+
+// signRequest adds the signature headers of body to hreq.
+func (c *Client) signRequest(hreq *http.Request, body []byte) {
+	ts, nonce := strconv.FormatInt(time.Now().Unix(), 10), randomID(16)
+	hreq.Header.Set(SignatureKeyHeader, c.signKeyID)
+	hreq.Header.Set(SignatureTimestampHeader, ts)
+	hreq.Header.Set(SignatureNonceHeader, nonce)
+	hreq.Header.Set(SignatureHeader, sign(c.signSecret, ts, nonce, body))
+}
+
+// NonceStore remembers the nonces of the signed requests served, so that
+// they can't be replayed.
+type NonceStore interface {
+	// Claim records nonce until expires and reports whether it was unseen.
+	Claim(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// SignatureConfig configures the VerifySignatures middleware.
+type SignatureConfig struct {
+	// Secret returns the secret of keyID, nil if it is unknown.
+	Secret func(keyID string) []byte
+	// MaxSkew bounds the difference between the timestamp of requests and
+	// the clock of the server, defaults to DefaultSignatureSkew.
+	MaxSkew time.Duration
+	// Nonces defaults to a MemoryNonceStore, which doesn't protect servers
+	// running several instances.
+	Nonces NonceStore
+	// MaxBody bounds the size in bytes of the bodies read to be verified,
+	// defaults to DefaultSignatureMaxBody. Larger requests are rejected
+	// with 413.
+	MaxBody int64
+}
+
+// VerifySignatures returns a middleware rejecting with 401 the requests not
+// signed with the secret of their key, see WithSigning, signed too long ago
+// or already served. The key id is set on the context as the subject of the
+// Principal, see GetPrincipal.
+func VerifySignatures(cfg SignatureConfig) Middleware {
+	cfg.MaxSkew = durationOr(cfg.MaxSkew, DefaultSignatureSkew)
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = DefaultSignatureMaxBody
+	}
+	if cfg.Nonces == nil {
+		cfg.Nonces = NewMemoryNonceStore()
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			keyID := r.Header.Get(SignatureKeyHeader)
+			ts, nonce := r.Header.Get(SignatureTimestampHeader), r.Header.Get(SignatureNonceHeader)
+			secret := cfg.Secret(keyID)
+			if secret == nil || nonce == "" || !validRequestID(nonce) {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			sec, err := strconv.ParseInt(ts, 10, 64)
+			if skew := time.Since(time.Unix(sec, 0)); err != nil || skew > cfg.MaxSkew || skew < -cfg.MaxSkew {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, cfg.MaxBody))
+			r.Body.Close()
+			if err != nil && int64(len(body)) == cfg.MaxBody {
+				http.Error(rw, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(rw, "Bad request", http.StatusBadRequest)
+				return
+			}
+			if !hmac.Equal([]byte(sign(secret, ts, nonce, body)), []byte(r.Header.Get(SignatureHeader))) {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			// the nonce is only claimed once the request is authentic, and
+			// kept as long as its timestamp is acceptable
+			fresh, err := cfg.Nonces.Claim(r.Context(), keyID+":"+nonce, time.Unix(sec, 0).Add(cfg.MaxSkew))
+			if err != nil {
+				log.Printf("jsonrpc: claiming nonce: %v", err)
+				http.Error(rw, "Service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if !fresh {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			h.ServeHTTP(rw, r.WithContext(WithPrincipal(r.Context(), &Principal{Subject: keyID})))
+		})
+	}
+}
+
+// MemoryNonceStore is a NonceStore keeping nonces in memory. Expired nonces
+// are removed as new ones are claimed.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	purged time.Time
+}
+
+// NewMemoryNonceStore returns an empty MemoryNonceStore.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+// Claim implements NonceStore.
+func (m *MemoryNonceStore) Claim(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.purged) > time.Minute {
+		for n, exp := range m.nonces {
+			if now.After(exp) {
+				delete(m.nonces, n)
+			}
+		}
+		m.purged = now
+	}
+	if exp, ok := m.nonces[nonce]; ok && now.Before(exp) {
+		return false, nil
+	}
+	m.nonces[nonce] = expires
+	return true, nil
+}
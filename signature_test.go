@@ -0,0 +1,110 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignatures(t *testing.T) {
+	var signer string
+	server := NewServer()
+	server.HandleFunc("whoami", func(ctx context.Context) (string, error) {
+		signer = GetPrincipal(ctx).Subject
+		return signer, nil
+	})
+	secrets := map[string][]byte{"billing": []byte("s3cret")}
+	var recorded *http.Request
+	var body []byte
+	h := VerifySignatures(SignatureConfig{Secret: func(id string) []byte { return secrets[id] }, MaxSkew: time.Minute})(server)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		recorded = r.Clone(context.Background())
+		body, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		h.ServeHTTP(rw, r)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithSigning("billing", []byte("s3cret")))
+	resp, err := client.Call(context.Background(), "whoami", nil)
+	if err != nil || resp.Err() != nil || signer != "billing" {
+		t.Fatalf("signed call: %v %v %q", err, resp.Err(), signer)
+	}
+
+	replay := func(mutate func(r *http.Request)) int {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		r.Header = recorded.Header.Clone()
+		if mutate != nil {
+			mutate(r)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		return rw.Code
+	}
+	if code := replay(nil); code != 401 {
+		t.Errorf("replayed request: status %v", code)
+	}
+	resign := func(key string, secret []byte, at time.Time) func(r *http.Request) {
+		return func(r *http.Request) {
+			ts, nonce := strconv.FormatInt(at.Unix(), 10), randomID(16)
+			r.Header.Set(SignatureKeyHeader, key)
+			r.Header.Set(SignatureTimestampHeader, ts)
+			r.Header.Set(SignatureNonceHeader, nonce)
+			r.Header.Set(SignatureHeader, sign(secret, ts, nonce, body))
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(r *http.Request)
+		status int
+	}{
+		{"fresh nonce", resign("billing", []byte("s3cret"), time.Now()), 200},
+		{"stale", resign("billing", []byte("s3cret"), time.Now().Add(-2*time.Minute)), 401},
+		{"future", resign("billing", []byte("s3cret"), time.Now().Add(2*time.Minute)), 401},
+		{"wrong secret", resign("billing", []byte("guess"), time.Now()), 401},
+		{"unknown key", resign("shipping", []byte("s3cret"), time.Now()), 401},
+		{"tampered", func(r *http.Request) {
+			resign("billing", []byte("s3cret"), time.Now())(r)
+			r.Body = ioutil.NopCloser(bytes.NewReader(append(body, ' ')))
+		}, 401},
+	}
+	for _, test := range tests {
+		if code := replay(test.mutate); code != test.status {
+			t.Errorf("%v: invalid status:\ngot: %v\nwant: %v", test.name, code, test.status)
+		}
+	}
+
+	h = VerifySignatures(SignatureConfig{Secret: func(id string) []byte { return secrets[id] }, MaxBody: 16})(server)
+	if code := replay(resign("billing", []byte("s3cret"), time.Now())); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: invalid status:\ngot: %v\nwant: 413", code)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	ctx := context.Background()
+	if ok, _ := store.Claim(ctx, "a", time.Now().Add(time.Minute)); !ok {
+		t.Errorf("new nonce rejected")
+	}
+	if ok, _ := store.Claim(ctx, "a", time.Now().Add(time.Minute)); ok {
+		t.Errorf("nonce claimed twice")
+	}
+	store.Claim(ctx, "b", time.Now().Add(-time.Second))
+	if ok, _ := store.Claim(ctx, "b", time.Now().Add(time.Minute)); !ok {
+		t.Errorf("expired nonce rejected")
+	}
+	store.Claim(ctx, "c", time.Now().Add(-time.Second))
+	store.purged = time.Time{}
+	store.Claim(ctx, "d", time.Now().Add(time.Minute))
+	if _, ok := store.nonces["c"]; ok {
+		t.Errorf("expired nonce kept")
+	}
+	if _, ok := store.nonces["a"]; !ok {
+		t.Errorf("nonce purged before it expired")
+	}
+}
@@ -0,0 +1,41 @@
+package jsonrpc
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// WithSlowCallThreshold sets Server.SlowCallThreshold.
+func WithSlowCallThreshold(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.SlowCallThreshold = d
+	}
+}
+
+// SlowCalls returns the number of calls per method which exceeded
+// s.SlowCallThreshold.
+func (s *Server) SlowCalls() map[string]uint64 {
+	out := make(map[string]uint64)
+	s.slowCalls.Range(func(k, v interface{}) bool {
+		out[k.(string)] = atomic.LoadUint64(v.(*uint64))
+		return true
+	})
+	return out
+}
+
+// checkSlowCall logs and counts the call of req started at start if it
+// exceeded the threshold.
+func (s *Server) checkSlowCall(ctx context.Context, req *request, start time.Time) {
+	d := time.Since(start)
+	if d < s.SlowCallThreshold {
+		return
+	}
+	v, ok := s.slowCalls.Load(req.Method)
+	if !ok {
+		v, _ = s.slowCalls.LoadOrStore(req.Method, new(uint64))
+	}
+	atomic.AddUint64(v.(*uint64), 1)
+	log.Printf("jsonrpc: slow call%s: method %v, id %v, took %v", logID(ctx), req.Method, req.ID, d)
+}
@@ -0,0 +1,44 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowCallThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	defer captureLog(&buf)()
+
+	server := NewServer()
+	server.SlowCallThreshold = 5 * time.Millisecond
+	server.HandleFunc("random", random)
+	server.HandleFunc("sleep", func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 0, nil
+	})
+
+	server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"random"}`))
+	server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":7,"method":"sleep"}`))
+	server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"sleep"}`))
+
+	got := server.SlowCalls()
+	if len(got) != 1 || got["sleep"] != 2 {
+		t.Errorf("invalid slow calls:\ngot: %v\nwant: map[sleep:2]", got)
+	}
+	if out := buf.String(); !strings.Contains(out, "method sleep, id 7") {
+		t.Errorf("slow call not logged:\n%s", out)
+	}
+}
+
+// captureLog redirects the standard logger to w until the returned function
+// is called.
+func captureLog(w io.Writer) func() {
+	log.SetOutput(w)
+	return func() { log.SetOutput(os.Stderr) }
+}
@@ -0,0 +1,200 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// isSquashed reports whether the struct field f is tagged
+// `jsonrpc:",squash"`: its fields are encoded as fields of the parent
+// struct, as those of embedded structs are, so that shared sets of params
+// such as pagination can be composed into several params structs.
+func isSquashed(f reflect.StructField) bool {
+	if indirect(f.Type).Kind() != reflect.Struct {
+		return false
+	}
+	if f.Anonymous && strings.Split(f.Tag.Get("json"), ",")[0] == "" {
+		// already promoted by encoding/json
+		return false
+	}
+	_, opts := jsonrpcTag(f)
+	return hasTagOption(opts, "squash")
+}
+
+// squashField is a squashed field of a params or result type.
+type squashField struct {
+	// parent is the path to the object holding the field, in the
+	// encoding of encoding/json.
+	parent []string
+	// name is the name of the field.
+	name string
+	// keys are the names of the fields it holds, once squashed.
+	keys []string
+}
+
+// squashedFields returns the squashed fields of t, the outermost first.
+func squashedFields(t reflect.Type) []squashField {
+	var fields []squashField
+	collectSquashedFields(t, nil, &fields, map[reflect.Type]bool{})
+	return fields
+}
+
+func collectSquashedFields(t reflect.Type, prefix []string, fields *[]squashField, seen map[reflect.Type]bool) {
+	if t == nil {
+		return
+	}
+	t = indirect(t)
+	if t.Implements(typeOfMarshaler) || reflect.PtrTo(t).Implements(typeOfUnmarshaler) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		collectSquashedFields(t.Elem(), append(prefix, "*"), fields, seen)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var own []string // names of the fields which aren't squashed
+	var squashed []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if isSquashed(f) {
+			squashed = append(squashed, f)
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+			// fields of embedded structs are promoted
+			for _, jf := range jsonFields(indirect(f.Type)) {
+				own = append(own, jf.name)
+			}
+			collectSquashedFields(f.Type, prefix, fields, seen)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		own = append(own, name)
+		collectSquashedFields(f.Type, append(append([]string(nil), prefix...), name), fields, seen)
+	}
+	for _, f := range squashed {
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		sf := squashField{parent: append([]string(nil), prefix...), name: name}
+		for _, jf := range jsonFields(indirect(f.Type)) {
+			if !containsFold(own, jf.name) {
+				sf.keys = append(sf.keys, jf.name)
+			}
+		}
+		*fields = append(*fields, sf)
+		collectSquashedFields(f.Type, append(append([]string(nil), prefix...), name), fields, seen)
+	}
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsquash moves the fields of params held by squashed fields into objects,
+// as encoding/json expects them.
+func unsquash(params json.RawMessage, fields []squashField) (json.RawMessage, error) {
+	return convertSquashed(params, fields, false)
+}
+
+// squash moves the fields of the objects of squashed fields in result into
+// their parent.
+func squash(result json.RawMessage, fields []squashField) (json.RawMessage, error) {
+	return convertSquashed(result, fields, true)
+}
+
+func convertSquashed(b json.RawMessage, fields []squashField, flatten bool) (json.RawMessage, error) {
+	if isNullParams(b) || firstByte(b) != '{' && firstByte(b) != '[' {
+		return b, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	for i := range fields {
+		f := &fields[i]
+		if flatten {
+			// the innermost first
+			f = &fields[len(fields)-1-i]
+		}
+		v, _ = convertPath(v, f.parent, func(v interface{}) (interface{}, error) {
+			if m, ok := v.(map[string]interface{}); ok {
+				if flatten {
+					flattenField(m, f.name)
+				} else {
+					nestField(m, f.name, f.keys)
+				}
+			}
+			return v, nil
+		})
+	}
+	return json.Marshal(v)
+}
+
+// nestField moves the members of m named keys into the object m[name].
+func nestField(m map[string]interface{}, name string, keys []string) {
+	child, _ := m[name].(map[string]interface{})
+	for k, v := range m {
+		if k == name || !containsFold(keys, k) {
+			continue
+		}
+		if child == nil {
+			child = make(map[string]interface{})
+		}
+		child[k] = v
+		delete(m, k)
+	}
+	if child != nil {
+		m[name] = child
+	}
+}
+
+// flattenField moves the members of the object m[name] into m.
+func flattenField(m map[string]interface{}, name string) {
+	v, ok := m[name]
+	if !ok {
+		return
+	}
+	child, isObject := v.(map[string]interface{})
+	if v != nil && !isObject {
+		return
+	}
+	delete(m, name)
+	for k, v := range child {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+}
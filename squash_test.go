@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+)
+
+type Page struct {
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type Owner struct {
+	Owner string `json:"owner"`
+}
+
+func TestSquash(t *testing.T) {
+	server := NewServer()
+	type ListUsers struct {
+		Page  `jsonrpc:",squash"`
+		Owner        // embedded
+		Role  string `json:"role"`
+	}
+	type ListGroups struct {
+		Paging *Page  `json:"paging" jsonrpc:",squash"`
+		Name   string `json:"name"`
+	}
+	type Users struct {
+		Next  Page     `json:"next" jsonrpc:",squash"`
+		Names []string `json:"names"`
+	}
+	server.HandleFunc("listUsers", func(ctx context.Context, p ListUsers) (Users, error) {
+		return Users{
+			Next:  Page{Limit: p.Limit, Cursor: p.Cursor + "+"},
+			Names: []string{p.Owner.Owner, p.Role},
+		}, nil
+	})
+	server.HandleFunc("listGroups", func(ctx context.Context, p ListGroups) (Page, error) {
+		if p.Paging == nil {
+			return Page{}, nil
+		}
+		return *p.Paging, nil
+	})
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"listUsers","params":{"limit":10,"cursor":"a","owner":"bob","role":"admin"}}`, `{"jsonrpc":"2.0","id":1,"result":{"cursor":"a+","limit":10,"names":["bob","admin"]}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"listUsers","params":{"role":"admin"}}`, `{"jsonrpc":"2.0","id":1,"result":{"cursor":"+","limit":0,"names":["","admin"]}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"listGroups","params":{"limit":5,"name":"ops"}}`, `{"jsonrpc":"2.0","id":1,"result":{"limit":5}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"listGroups","params":{"name":"ops"}}`, `{"jsonrpc":"2.0","id":1,"result":{"limit":0}}`},
+	}
+	for _, test := range tests {
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// StatsMethod is the method registered by Server.HandleStats.
+const StatsMethod = "rpc.stats"
+
+// MethodStats are the statistics of the calls made to a method over the last
+// Server.StatsWindow. Latencies are approximated to the next power of two
+// microseconds, bounded by Max.
+type MethodStats struct {
+	Calls uint64 `json:"calls"`
+	// Errors is the number of calls which failed, with an error response
+	// or, for notifications, a handler error.
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// WithStatsWindow sets Server.StatsWindow.
+func WithStatsWindow(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.StatsWindow = d
+	}
+}
+
+// Stats returns the statistics of every method called while s.StatsWindow
+// was positive, over the last window.
+func (s *Server) Stats() map[string]MethodStats {
+	out := make(map[string]MethodStats)
+	now := time.Now()
+	s.stats.Range(func(k, v interface{}) bool {
+		out[k.(string)] = v.(*methodStats).snapshot(now, s.StatsWindow)
+		return true
+	})
+	return out
+}
+
+// HandleStats registers StatsMethod, returning the result of Stats with the
+// durations encoded in Server.DurationFormat. Options restrict who may call
+// it, see Authenticated.
+func (s *Server) HandleStats(opts ...MethodOption) error {
+	return s.HandleFunc(StatsMethod, func(ctx context.Context) (map[string]MethodStats, error) {
+		return s.Stats(), nil
+	}, opts...)
+}
+
+// recordStats adds the call of req, which took d and failed if err isn't
+// nil, to the statistics of its method. Unknown methods aren't recorded.
+func (s *Server) recordStats(req *request, d time.Duration, err error) {
+	if errors.Is(err, ErrMethodNotFound) {
+		return
+	}
+	v, ok := s.stats.Load(req.Method)
+	if !ok {
+		v, _ = s.stats.LoadOrStore(req.Method, &methodStats{})
+	}
+	v.(*methodStats).record(time.Now(), s.StatsWindow, d, err != nil)
+}
+
+const (
+	// statsSlots is the number of slots the window is divided into, the
+	// oldest slot being dropped as time goes.
+	statsSlots = 60
+	// latencyBuckets is the number of buckets of the latency histograms,
+	// bucket i counting the latencies below 2^i microseconds.
+	latencyBuckets = 32
+)
+
+type methodStats struct {
+	mu    sync.Mutex
+	slots [statsSlots]statsSlot
+}
+
+type statsSlot struct {
+	// n is the number of the slot since the epoch, the slot holding
+	// stale stats if it isn't the current one.
+	n         int64
+	calls     uint64
+	errors    uint64
+	max       time.Duration
+	latencies [latencyBuckets]uint64
+}
+
+func slotDuration(window time.Duration) int64 {
+	if d := int64(window / statsSlots); d > 0 {
+		return d
+	}
+	return 1
+}
+
+func (ms *methodStats) record(now time.Time, window, d time.Duration, failed bool) {
+	n := now.UnixNano() / slotDuration(window)
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	slot := &ms.slots[n%statsSlots]
+	if slot.n != n {
+		*slot = statsSlot{n: n}
+	}
+	slot.calls++
+	if failed {
+		slot.errors++
+	}
+	if d > slot.max {
+		slot.max = d
+	}
+	b := bits.Len64(uint64(d / time.Microsecond))
+	if b >= latencyBuckets {
+		b = latencyBuckets - 1
+	}
+	slot.latencies[b]++
+}
+
+func (ms *methodStats) snapshot(now time.Time, window time.Duration) MethodStats {
+	n := now.UnixNano() / slotDuration(window)
+	var st MethodStats
+	var latencies [latencyBuckets]uint64
+	ms.mu.Lock()
+	for i := range ms.slots {
+		slot := &ms.slots[i]
+		if slot.n <= n-statsSlots || slot.n > n {
+			continue
+		}
+		st.Calls += slot.calls
+		st.Errors += slot.errors
+		if slot.max > st.Max {
+			st.Max = slot.max
+		}
+		for b, c := range slot.latencies {
+			latencies[b] += c
+		}
+	}
+	ms.mu.Unlock()
+	if st.Calls == 0 {
+		return st
+	}
+	st.ErrorRate = float64(st.Errors) / float64(st.Calls)
+	st.P50 = percentile(&latencies, st.Calls, 0.50, st.Max)
+	st.P90 = percentile(&latencies, st.Calls, 0.90, st.Max)
+	st.P99 = percentile(&latencies, st.Calls, 0.99, st.Max)
+	return st
+}
+
+// percentile returns the upper bound of the bucket of latencies holding the
+// q quantile of the calls, at most max.
+func percentile(latencies *[latencyBuckets]uint64, calls uint64, q float64, max time.Duration) time.Duration {
+	rank := uint64(math.Ceil(q * float64(calls)))
+	var seen uint64
+	for b, c := range latencies {
+		if seen += c; seen >= rank {
+			if d := time.Duration(1<<uint(b)) * time.Microsecond; d < max {
+				return d
+			}
+			break
+		}
+	}
+	return max
+}
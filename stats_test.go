@@ -0,0 +1,88 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	server := NewServer()
+	server.StatsWindow = time.Minute
+	server.HandleFunc("half", func(ctx context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, errors.New("odd")
+		}
+		return n, nil
+	})
+	server.HandleStats()
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"half","params":1}`,
+		`{"jsonrpc":"2.0","id":2,"method":"half","params":2}`,
+		`{"jsonrpc":"2.0","id":3,"method":"half","params":3}`,
+		`{"jsonrpc":"2.0","id":4,"method":"half","params":4}`,
+		`{"jsonrpc":"2.0","id":5,"method":"unknown"}`,
+	} {
+		server.ServeMessage(context.Background(), []byte(req))
+	}
+
+	stats := server.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("stats of %v methods, want 1: %v", len(stats), stats)
+	}
+	st := stats["half"]
+	if st.Calls != 4 || st.Errors != 2 || st.ErrorRate != 0.5 {
+		t.Errorf("calls %v, errors %v, rate %v, want 4, 2, 0.5", st.Calls, st.Errors, st.ErrorRate)
+	}
+	if st.P50 <= 0 || st.P50 > st.P90 || st.P90 > st.P99 || st.P99 > st.Max {
+		t.Errorf("inconsistent latencies: %+v", st)
+	}
+
+	resp := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":6,"method":"rpc.stats"}`))
+	var r struct {
+		Result map[string]MethodStats `json:"result"`
+	}
+	if err := json.Unmarshal(resp, &r); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Result["half"]; got.Calls != 4 || got.Max != st.Max {
+		t.Errorf("rpc.stats: got %+v, want %+v", got, st)
+	}
+}
+
+func TestStatsWindow(t *testing.T) {
+	var ms methodStats
+	start := time.Unix(1000, 0)
+	ms.record(start, time.Minute, 3*time.Millisecond, false)
+	ms.record(start.Add(30*time.Second), time.Minute, 100*time.Millisecond, true)
+	for i := 0; i < 8; i++ {
+		ms.record(start.Add(30*time.Second), time.Minute, 10*time.Microsecond, false)
+	}
+
+	st := ms.snapshot(start.Add(45*time.Second), time.Minute)
+	if st.Calls != 10 || st.Errors != 1 || st.Max != 100*time.Millisecond {
+		t.Errorf("got %+v, want 10 calls, 1 error, max 100ms", st)
+	}
+	if want := 16 * time.Microsecond; st.P50 != want {
+		t.Errorf("p50 %v, want %v", st.P50, want)
+	}
+	if want := 4096 * time.Microsecond; st.P90 != want {
+		t.Errorf("p90 %v, want %v", st.P90, want)
+	}
+	if st.P99 != st.Max {
+		t.Errorf("p99 %v, want max %v", st.P99, st.Max)
+	}
+
+	// the first call is out of the window
+	st = ms.snapshot(start.Add(75*time.Second), time.Minute)
+	if st.Calls != 9 || st.Max != 100*time.Millisecond {
+		t.Errorf("got %+v, want 9 calls", st)
+	}
+	st = ms.snapshot(start.Add(2*time.Minute), time.Minute)
+	if st.Calls != 0 {
+		t.Errorf("got %+v, want no calls", st)
+	}
+}
@@ -0,0 +1,189 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Notification is a value of a stream sent as a notification of Method with
+// Params instead of a result, for protocols pushing events this way, such as
+// eth_subscribe.
+type Notification struct {
+	Method string
+	Params interface{}
+}
+
+// isStreamType reports whether handlers returning t stream their results.
+// Such handlers return a receive channel, every value received is a chunk of
+// the result and closing the channel ends it.
+func isStreamType(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0
+}
+
+// sendStream writes the values received from the stream of resp as newline
+// delimited JSON, one response object per value, so that huge results never
+// need to be held in memory. Writes are buffered and flushed according to
+// flushInterval, see Server.StreamFlushInterval. It stops early if ctx is
+// done, handlers should then stop sending as well.
+func sendStream(ctx context.Context, rw http.ResponseWriter, resp *Response, flushInterval time.Duration) {
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	w := &streamWriter{rw: rw, bw: bufio.NewWriter(rw), id: logID(ctx)}
+	w.flusher, _ = rw.(http.Flusher)
+	defer w.flush()
+
+	const (
+		recvCase = iota
+		doneCase
+		timerCase
+		defaultCase
+	)
+	cases := []reflect.SelectCase{
+		recvCase: {Dir: reflect.SelectRecv, Chan: resp.stream},
+		doneCase: {Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	var ticker *time.Ticker
+	if flushInterval > 0 {
+		ticker = time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ticker.C)})
+	} else if flushInterval == 0 {
+		// flush only when no value is ready, which coalesces bursts
+		noTimer := reflect.ValueOf((<-chan time.Time)(nil))
+		cases = append(cases,
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: noTimer},
+			reflect.SelectCase{Dir: reflect.SelectDefault},
+		)
+	}
+
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		switch chosen {
+		case doneCase:
+			return
+		case timerCase:
+			if !w.flush() {
+				return
+			}
+			continue
+		case defaultCase:
+			if !w.flush() {
+				return
+			}
+			chosen, v, ok = reflect.Select(cases[:doneCase+1])
+			if chosen == doneCase {
+				return
+			}
+		}
+		if !ok {
+			return
+		}
+
+		if n, ok := v.Interface().(Notification); ok {
+			if !w.writeNotification(n) {
+				return
+			}
+		} else {
+			result, err := json.Marshal(v.Interface())
+			chunk := &Response{id: resp.id, result: result}
+			if err != nil {
+				chunk = errResponse(resp.id, ErrInternalError)
+			}
+			if !w.write(chunk) || chunk.error != nil {
+				return
+			}
+		}
+		if flushInterval < 0 && !w.flush() {
+			return
+		}
+	}
+}
+
+// streamWriter buffers the chunks of a stream.
+type streamWriter struct {
+	rw      http.ResponseWriter
+	bw      *bufio.Writer
+	flusher http.Flusher
+	id      string // correlation id for log lines
+}
+
+func (w *streamWriter) write(chunk *Response) bool {
+	if err := chunk.encode(w.bw); err != nil {
+		log.Printf("jsonrpc: sending stream%s: %v", w.id, err)
+		return false
+	}
+	w.bw.WriteByte('\n')
+	return true
+}
+
+func (w *streamWriter) writeNotification(n Notification) bool {
+	b, err := encodeNotification(n.Method, n.Params)
+	if err != nil {
+		log.Printf("jsonrpc: sending stream%s: %v", w.id, err)
+		return false
+	}
+	w.bw.Write(b)
+	w.bw.WriteByte('\n')
+	return true
+}
+
+func (w *streamWriter) flush() bool {
+	if w.bw.Buffered() == 0 {
+		return true
+	}
+	if err := w.bw.Flush(); err != nil {
+		log.Printf("jsonrpc: sending stream%s: %v", w.id, err)
+		return false
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return true
+}
+
+// drainStream receives the values of stream until it is closed.
+func drainStream(stream reflect.Value) {
+	for {
+		if _, ok := stream.Recv(); !ok {
+			return
+		}
+	}
+}
+
+// emptyArray is the result of empty streams.
+var emptyArray = json.RawMessage("[]")
+
+// collectStream receives all the values of stream and returns them encoded as
+// a JSON array. It is used where a response can't be streamed, like in
+// batches or non-HTTP transports. Notifications are encoded as the JSON-RPC
+// notifications sendStream would have sent.
+func collectStream(stream reflect.Value) (json.RawMessage, error) {
+	values := make([]interface{}, 0)
+	var err error
+	if !stream.IsNil() {
+		for {
+			v, ok := stream.Recv()
+			if !ok {
+				break
+			}
+			if n, ok := v.Interface().(Notification); ok {
+				b, nerr := encodeNotification(n.Method, n.Params)
+				if nerr != nil && err == nil {
+					// keep receiving so that the sender isn't stuck
+					err = nerr
+				}
+				values = append(values, json.RawMessage(b))
+				continue
+			}
+			values = append(values, v.Interface())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
@@ -0,0 +1,169 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func count(ctx context.Context, n int) (<-chan Reply, error) {
+	c := make(chan Reply)
+	go func() {
+		defer close(c)
+		for i := 1; i <= n; i++ {
+			select {
+			case c <- Reply{i}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+func TestServeStream(t *testing.T) {
+	server := NewServer()
+	if err := server.HandleFunc("count", count); err != nil {
+		t.Fatalf("registering stream handler: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "localhost:8080", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"count","params":3}`)))
+	rw := httptest.NewRecorder()
+	server.ServeHTTP(rw, req)
+
+	want := `{"jsonrpc":"2.0","id":1,"result":{"C":1}}
+{"jsonrpc":"2.0","id":1,"result":{"C":2}}
+{"jsonrpc":"2.0","id":1,"result":{"C":3}}
+`
+	if got := rw.Body.String(); got != want {
+		t.Errorf("invalid stream:\ngot: %v\nwant: %v", got, want)
+	}
+	if ct := rw.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("invalid content type: %v", ct)
+	}
+	if !rw.Flushed {
+		t.Errorf("stream not flushed")
+	}
+}
+
+func TestServeStreamCollected(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("count", count)
+
+	got := server.ServeMessage(context.Background(), []byte(`[{"jsonrpc":"2.0","id":1,"method":"count","params":2}]`))
+	want := `[{"jsonrpc":"2.0","id":1,"result":[{"C":1},{"C":2}]}]`
+	if string(got) != want {
+		t.Errorf("invalid batch response:\ngot: %s\nwant: %v", got, want)
+	}
+
+	result, err := server.Invoke(context.Background(), "count", []byte("2"))
+	if err != nil || string(result) != `[{"C":1},{"C":2}]` {
+		t.Errorf("invalid invoke result: %s, %v", result, err)
+	}
+}
+
+func TestServeStreamCollectedNotifications(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("watch", func(ctx context.Context) (<-chan interface{}, error) {
+		c := make(chan interface{}, 2)
+		c <- "0x1"
+		c <- Notification{Method: "changed", Params: []int{1}}
+		close(c)
+		return c, nil
+	})
+
+	got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"watch"}`))
+	want := `{"jsonrpc":"2.0","id":1,"result":["0x1",{"jsonrpc":"2.0","method":"changed","params":[1]}]}`
+	if string(got) != want {
+		t.Errorf("invalid response:\ngot: %s\nwant: %v", got, want)
+	}
+}
+
+func TestServeStreamNil(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("none", func(ctx context.Context) (<-chan int, error) {
+		return nil, nil
+	})
+
+	want := `{"jsonrpc":"2.0","id":1,"result":[]}`
+	req := httptest.NewRequest("POST", "localhost:8080", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"none"}`))
+	rw := httptest.NewRecorder()
+	server.ServeHTTP(rw, req)
+	if got := rw.Body.String(); got != want {
+		t.Errorf("invalid HTTP response:\ngot: %v\nwant: %v", got, want)
+	}
+	if got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"none"}`)); string(got) != want {
+		t.Errorf("invalid response:\ngot: %s\nwant: %v", got, want)
+	}
+}
+
+func TestServeStreamNotification(t *testing.T) {
+	server := NewServer()
+	done := make(chan struct{})
+	server.HandleFunc("ticks", func(ctx context.Context) (<-chan int, error) {
+		c := make(chan int)
+		go func() {
+			defer close(done)
+			defer close(c)
+			for i := 0; ; i++ {
+				select {
+				case c <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return c, nil
+	})
+
+	if got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"ticks"}`)); got != nil {
+		t.Errorf("unexpected response: %s", got)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("stream of a notification not ended")
+	}
+}
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestStreamFlushInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		flushes  int
+	}{
+		{"every_value", -1, 3},
+		{"periodic", time.Hour, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := NewServer()
+			server.StreamFlushInterval = tc.interval
+			server.HandleFunc("count", count)
+
+			req := httptest.NewRequest("POST", "localhost:8080", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"count","params":3}`)))
+			rw := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+			server.ServeHTTP(rw, req)
+
+			if lines := strings.Count(rw.Body.String(), "\n"); lines != 3 {
+				t.Errorf("invalid number of chunks: got %v, want 3", lines)
+			}
+			if rw.flushes != tc.flushes {
+				t.Errorf("invalid number of flushes: got %v, want %v", rw.flushes, tc.flushes)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat is an encoding of time.Time values, see Server.TimeFormat.
+type TimeFormat int
+
+const (
+	// TimeRFC3339 encodes times as RFC 3339 strings with nanoseconds, as
+	// encoding/json does.
+	TimeRFC3339 TimeFormat = iota
+	// TimeUnix encodes times as whole seconds since the Unix epoch.
+	TimeUnix
+	// TimeUnixMilli encodes times as whole milliseconds since the Unix
+	// epoch.
+	TimeUnixMilli
+)
+
+// DurationFormat is an encoding of time.Duration values, see
+// Server.DurationFormat.
+type DurationFormat int
+
+const (
+	// DurationNanoseconds encodes durations as a number of nanoseconds, as
+	// encoding/json does.
+	DurationNanoseconds DurationFormat = iota
+	// DurationMilliseconds encodes durations as a number of milliseconds.
+	DurationMilliseconds
+	// DurationSeconds encodes durations as a number of seconds.
+	DurationSeconds
+	// DurationISO8601 encodes durations as ISO 8601 strings, such as
+	// "PT1H30M". Days and weeks are accepted in params, not years nor
+	// months whose length varies.
+	DurationISO8601
+	// DurationString encodes durations as Go duration strings, such as
+	// "1h30m0s".
+	DurationString
+)
+
+var typeOfDuration = reflect.TypeOf(time.Duration(0))
+
+// timePaths are the paths to the time.Time and time.Duration values of a
+// type, relative to its JSON encoding.
+type timePaths struct {
+	times, durations [][]string
+}
+
+// timePaths returns the paths to the times and durations of t converted by
+// s, those without a codec.
+func (s *Server) timePaths(t reflect.Type) timePaths {
+	var p timePaths
+	if _, ok := s.codecs.Load(typeOfTime); !ok {
+		p.times = typePaths(t, typeOfTime)
+	}
+	if _, ok := s.codecs.Load(typeOfDuration); !ok {
+		p.durations = typePaths(t, typeOfDuration)
+	}
+	return p
+}
+
+// convertsTimes reports whether the values at p are converted from or to the
+// encodings of encoding/json.
+func (s *Server) convertsTimes(p timePaths) bool {
+	return s.TimeFormat != TimeRFC3339 && len(p.times) > 0 ||
+		s.DurationFormat != DurationNanoseconds && len(p.durations) > 0
+}
+
+// decodeTimes converts the times and durations of params at p from the
+// formats of s to those of encoding/json.
+func (s *Server) decodeTimes(params json.RawMessage, p timePaths) (json.RawMessage, error) {
+	return convertTimes(params, p, s.TimeFormat, s.DurationFormat, func(f TimeFormat, v interface{}) (interface{}, error) {
+		t, err := parseTime(f, v)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}, func(f DurationFormat, v interface{}) (interface{}, error) {
+		d, err := parseDuration(f, v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatInt(int64(d), 10)), nil
+	})
+}
+
+// encodeTimes converts the times and durations of result at p from the
+// formats of encoding/json to those of s.
+func (s *Server) encodeTimes(result json.RawMessage, p timePaths) (json.RawMessage, error) {
+	return convertTimes(result, p, s.TimeFormat, s.DurationFormat, func(f TimeFormat, v interface{}) (interface{}, error) {
+		t, err := parseTime(TimeRFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+		return formatTime(f, t), nil
+	}, func(f DurationFormat, v interface{}) (interface{}, error) {
+		d, err := parseDuration(DurationNanoseconds, v)
+		if err != nil {
+			return nil, err
+		}
+		return formatDuration(f, d), nil
+	})
+}
+
+func convertTimes(
+	b json.RawMessage, p timePaths, tf TimeFormat, df DurationFormat,
+	convTime func(TimeFormat, interface{}) (interface{}, error),
+	convDuration func(DurationFormat, interface{}) (interface{}, error),
+) (json.RawMessage, error) {
+	if isNullParams(b) {
+		return b, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var err error
+	if tf != TimeRFC3339 {
+		for _, path := range p.times {
+			v, err = convertPath(v, path, func(v interface{}) (interface{}, error) { return convTime(tf, v) })
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if df != DurationNanoseconds {
+		for _, path := range p.durations {
+			v, err = convertPath(v, path, func(v interface{}) (interface{}, error) { return convDuration(df, v) })
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// convertPath replaces the non null values of v at path by their conversion
+// and returns v.
+func convertPath(v interface{}, path []string, conv func(interface{}) (interface{}, error)) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if len(path) == 0 {
+		return conv(v)
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if path[0] != "*" && !strings.EqualFold(k, path[0]) {
+				continue
+			}
+			c, err := convertPath(e, path[1:], conv)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+			v[k] = c
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return v, nil
+		}
+		for i, e := range v {
+			c, err := convertPath(e, path[1:], conv)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", i, err)
+			}
+			v[i] = c
+		}
+	}
+	return v, nil
+}
+
+func parseTime(f TimeFormat, v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			switch f {
+			case TimeUnix:
+				return time.Unix(n, 0), nil
+			case TimeUnixMilli:
+				return time.Unix(0, n*int64(time.Millisecond)), nil
+			}
+			break
+		}
+		n, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch f {
+		case TimeUnix:
+			return time.Unix(0, int64(n*1e9)), nil
+		case TimeUnixMilli:
+			return time.Unix(0, int64(n*1e6)), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %v", v)
+}
+
+func formatTime(f TimeFormat, t time.Time) interface{} {
+	switch f {
+	case TimeUnix:
+		return json.Number(strconv.FormatInt(t.Unix(), 10))
+	case TimeUnixMilli:
+		return json.Number(strconv.FormatInt(t.UnixNano()/1e6, 10))
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseDuration(f DurationFormat, v interface{}) (time.Duration, error) {
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(strings.TrimLeft(v, "+-"), "P") {
+			return parseISODuration(v)
+		}
+		return time.ParseDuration(v)
+	case json.Number:
+		if f == DurationNanoseconds {
+			n, err := v.Int64()
+			return time.Duration(n), err
+		}
+		n, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		switch f {
+		case DurationMilliseconds:
+			return time.Duration(n * float64(time.Millisecond)), nil
+		case DurationSeconds:
+			return time.Duration(n * float64(time.Second)), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid duration %v", v)
+}
+
+func formatDuration(f DurationFormat, d time.Duration) interface{} {
+	switch f {
+	case DurationMilliseconds:
+		return json.Number(strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64))
+	case DurationSeconds:
+		return json.Number(strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+	case DurationISO8601:
+		return formatISODuration(d)
+	case DurationString:
+		return d.String()
+	}
+	return json.Number(strconv.FormatInt(int64(d), 10))
+}
+
+// formatISODuration returns the ISO 8601 representation of d in hours,
+// minutes and seconds.
+func formatISODuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	b.WriteString("PT")
+	if h := u / uint64(time.Hour); h > 0 {
+		b.WriteString(strconv.FormatUint(h, 10) + "H")
+		u -= h * uint64(time.Hour)
+	}
+	if m := u / uint64(time.Minute); m > 0 {
+		b.WriteString(strconv.FormatUint(m, 10) + "M")
+		u -= m * uint64(time.Minute)
+	}
+	if u > 0 {
+		b.WriteString(strconv.FormatFloat(float64(u)/float64(time.Second), 'f', -1, 64) + "S")
+	}
+	return b.String()
+}
+
+var errInvalidISODuration = errors.New("invalid ISO 8601 duration")
+
+// parseISODuration parses an ISO 8601 duration made of weeks, days, hours,
+// minutes and seconds.
+func parseISODuration(s string) (time.Duration, error) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, errInvalidISODuration
+	}
+	s = s[1:]
+	var d time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime || len(s) == 1 {
+				return 0, errInvalidISODuration
+			}
+			inTime = true
+			s = s[1:]
+			continue
+		}
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' && r != ',' })
+		if i <= 0 {
+			return 0, errInvalidISODuration
+		}
+		n, err := strconv.ParseFloat(strings.Replace(s[:i], ",", ".", 1), 64)
+		if err != nil {
+			return 0, errInvalidISODuration
+		}
+		var unit time.Duration
+		switch c := s[i]; {
+		case !inTime && c == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && c == 'D':
+			unit = 24 * time.Hour
+		case inTime && c == 'H':
+			unit = time.Hour
+		case inTime && c == 'M':
+			unit = time.Minute
+		case inTime && c == 'S':
+			unit = time.Second
+		default:
+			return 0, errInvalidISODuration
+		}
+		d += time.Duration(n * float64(unit))
+		s = s[i+1:]
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// typePaths returns the paths to the values of type target in the JSON
+// encoding of t, relative to it. "*" stands for any array index or key.
+func typePaths(t, target reflect.Type) [][]string {
+	var paths [][]string
+	collectTypePaths(t, target, nil, &paths, map[reflect.Type]bool{})
+	return paths
+}
+
+var typeOfUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func collectTypePaths(t, target reflect.Type, prefix []string, paths *[][]string, seen map[reflect.Type]bool) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == target {
+		*paths = append(*paths, append([]string(nil), prefix...))
+		return
+	}
+	if t.Implements(typeOfMarshaler) || reflect.PtrTo(t).Implements(typeOfUnmarshaler) {
+		// encoded its own way
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		collectTypePaths(t.Elem(), target, append(prefix, "*"), paths, seen)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			// fields of embedded structs are promoted
+			collectTypePaths(f.Type, target, prefix, paths, seen)
+			continue
+		}
+		collectTypePaths(f.Type, target, append(append([]string(nil), prefix...), name), paths, seen)
+	}
+}
@@ -0,0 +1,96 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeFormats(t *testing.T) {
+	type Window struct {
+		Start   time.Time     `json:"start"`
+		Length  time.Duration `json:"length"`
+		Reminds []time.Duration
+		End     *time.Time `json:"end,omitempty"`
+	}
+	server := NewServer()
+	server.HandleFunc("shift", func(ctx context.Context, w Window) (Window, error) {
+		w.Start = w.Start.Add(w.Length)
+		return w, nil
+	})
+	server.HandleFunc("at", func(ctx context.Context, at time.Time) (time.Time, error) {
+		return at.Add(time.Hour), nil
+	})
+
+	tests := []struct {
+		tf        TimeFormat
+		df        DurationFormat
+		req, want string
+	}{
+		{
+			TimeRFC3339, DurationNanoseconds,
+			`{"jsonrpc":"2.0","id":1,"method":"shift","params":{"start":"2024-01-01T00:00:00Z","length":60000000000,"Reminds":[1000000000]}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"start":"2024-01-01T00:01:00Z","length":60000000000,"Reminds":[1000000000]}}`,
+		},
+		{
+			TimeUnix, DurationISO8601,
+			`{"jsonrpc":"2.0","id":1,"method":"shift","params":{"start":1704067200,"length":"PT1H30M","reminds":["P1D","PT0.5S"]}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"Reminds":["PT24H","PT0.5S"],"length":"PT1H30M","start":1704072600}}`,
+		},
+		{
+			TimeUnixMilli, DurationSeconds,
+			`{"jsonrpc":"2.0","id":1,"method":"shift","params":{"start":1704067200000,"length":1.5,"Reminds":null}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"Reminds":null,"length":1.5,"start":1704067201500}}`,
+		},
+		{
+			TimeUnix, DurationString,
+			`{"jsonrpc":"2.0","id":1,"method":"shift","params":{"start":"2024-01-01T00:00:00Z","length":"1m30s","Reminds":[]}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"Reminds":[],"length":"1m30s","start":1704067290}}`,
+		},
+		{
+			TimeUnix, DurationNanoseconds,
+			`{"jsonrpc":"2.0","id":1,"method":"at","params":1704067200}`,
+			`{"jsonrpc":"2.0","id":1,"result":1704070800}`,
+		},
+		{
+			TimeUnix, DurationISO8601,
+			`{"jsonrpc":"2.0","id":1,"method":"shift","params":{"start":1704067200,"length":"P1Y"}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":"length: invalid ISO 8601 duration"}}`,
+		},
+	}
+	for _, test := range tests {
+		server.TimeFormat, server.DurationFormat = test.tf, test.df
+		if got := string(server.ServeMessage(context.Background(), []byte(test.req))); got != test.want {
+			t.Errorf("%v:\ngot: %v\nwant: %v", test.req, got, test.want)
+		}
+	}
+}
+
+func TestISODuration(t *testing.T) {
+	tests := []struct {
+		s string
+		d time.Duration
+	}{
+		{"PT0S", 0},
+		{"PT1H30M", 90 * time.Minute},
+		{"PT1.5S", 1500 * time.Millisecond},
+		{"-PT2M3S", -(2*time.Minute + 3*time.Second)},
+		{"PT49H", 49 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := formatISODuration(test.d); got != test.s {
+			t.Errorf("format %v: got %v, want %v", test.d, got, test.s)
+		}
+		if got, err := parseISODuration(test.s); err != nil || got != test.d {
+			t.Errorf("parse %v: got %v, %v, want %v", test.s, got, err, test.d)
+		}
+	}
+	for _, s := range []string{"P", "PT", "P1M", "PT1D", "1H", "P1H", "PTS"} {
+		if d, err := parseISODuration(s); err == nil {
+			t.Errorf("parse %v: got %v, want an error", s, d)
+		}
+	}
+	if d, err := parseISODuration("P1W2DT3H"); err != nil || d != (9*24+3)*time.Hour {
+		t.Errorf("parse P1W2DT3H: got %v, %v", d, err)
+	}
+}
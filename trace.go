@@ -0,0 +1,170 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Headers propagating the trace context of requests, see WithTracing.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+	B3TraceIDHeader   = "X-B3-TraceId"
+	B3SpanIDHeader    = "X-B3-SpanId"
+	B3ParentIDHeader  = "X-B3-ParentSpanId"
+	B3SampledHeader   = "X-B3-Sampled"
+)
+
+// SpanContext identifies a span of a distributed trace.
+type SpanContext struct {
+	// TraceID and SpanID are hex encoded, on 32 and 16 digits.
+	TraceID string
+	SpanID  string
+	// ParentSpanID is the id of the span which started this one, if known.
+	ParentSpanID string
+	Sampled      bool
+	// TraceState carries vendor specific data, passed along as is.
+	TraceState string
+}
+
+type spanContextKey struct{}
+
+// WithSpanContext returns a copy of ctx carrying sc, whose calls made by
+// clients with tracing become children of.
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// GetSpanContext returns the span carried by ctx: the span of the request in
+// ClientHooks, the span of the caller in the handlers of servers with
+// Tracing.
+func GetSpanContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// Traceparent returns the W3C traceparent header of sc.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header.
+func ParseTraceparent(h string) (SpanContext, bool) {
+	parts := strings.Split(h, "-")
+	if len(parts) < 4 || !isHex(parts[0], 2) || parts[0] == "ff" || parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{TraceID: parts[1], SpanID: parts[2]}
+	if !isHexID(sc.TraceID, 32) || !isHexID(sc.SpanID, 16) || !isHex(parts[3], 2) {
+		return SpanContext{}, false
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// isHexID reports whether id is made of n lower case hex digits, not all
+// zeros.
+func isHexID(id string, n int) bool {
+	return isHex(id, n) && strings.Trim(id, "0") != ""
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// WithTracing makes the client start a span for every request, a child of
+// the span of the context of the call if any, see WithSpanContext, the root
+// of a new sampled trace otherwise. The span is sent in the W3C traceparent
+// and tracestate headers, and reported to ClientHooks.
+func WithTracing() ClientOption {
+	return func(c *Client) {
+		c.tracing = true
+	}
+}
+
+// WithB3Headers is like WithTracing and sends the B3 headers of the span as
+// well, for systems based on Zipkin.
+func WithB3Headers() ClientOption {
+	return func(c *Client) {
+		c.tracing, c.b3 = true, true
+	}
+}
+
+// startSpan returns a copy of ctx carrying a new span for a request, if
+// tracing is enabled.
+func (c *Client) startSpan(ctx context.Context) context.Context {
+	if !c.tracing {
+		return ctx
+	}
+	sc := SpanContext{SpanID: randomID(8), Sampled: true}
+	if parent, ok := GetSpanContext(ctx); ok {
+		sc.TraceID, sc.ParentSpanID = parent.TraceID, parent.SpanID
+		sc.Sampled, sc.TraceState = parent.Sampled, parent.TraceState
+	} else {
+		sc.TraceID = randomID(16)
+	}
+	return WithSpanContext(ctx, sc)
+}
+
+// injectSpan sets the trace headers of the span of ctx on h.
+func (c *Client) injectSpan(ctx context.Context, h http.Header) {
+	sc, ok := GetSpanContext(ctx)
+	if !c.tracing || !ok {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	}
+	if !c.b3 {
+		return
+	}
+	h.Set(B3TraceIDHeader, sc.TraceID)
+	h.Set(B3SpanIDHeader, sc.SpanID)
+	if sc.ParentSpanID != "" {
+		h.Set(B3ParentIDHeader, sc.ParentSpanID)
+	}
+	if sc.Sampled {
+		h.Set(B3SampledHeader, "1")
+	} else {
+		h.Set(B3SampledHeader, "0")
+	}
+}
+
+// withSpanContext returns a copy of ctx carrying the span of the caller of
+// r, read from the traceparent header or else from the B3 headers.
+func withSpanContext(ctx context.Context, r *http.Request) context.Context {
+	if sc, ok := ParseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+		sc.TraceState = r.Header.Get(TracestateHeader)
+		return WithSpanContext(ctx, sc)
+	}
+	sc := SpanContext{
+		TraceID:      strings.ToLower(r.Header.Get(B3TraceIDHeader)),
+		SpanID:       strings.ToLower(r.Header.Get(B3SpanIDHeader)),
+		ParentSpanID: strings.ToLower(r.Header.Get(B3ParentIDHeader)),
+		Sampled:      r.Header.Get(B3SampledHeader) != "0",
+	}
+	if len(sc.TraceID) == 16 {
+		// 64 bit trace ids are left padded
+		sc.TraceID = strings.Repeat("0", 16) + sc.TraceID
+	}
+	if !isHexID(sc.TraceID, 32) || !isHexID(sc.SpanID, 16) {
+		return ctx
+	}
+	return WithSpanContext(ctx, sc)
+}
@@ -0,0 +1,76 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		h  string
+		ok bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		sc, ok := ParseTraceparent(test.h)
+		if ok != test.ok {
+			t.Errorf("%q: got %v, want %v", test.h, ok, test.ok)
+		}
+		if ok && test.h[:2] == "00" && sc.Traceparent() != test.h {
+			t.Errorf("%q: encoded back as %q", test.h, sc.Traceparent())
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		rw.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":true}`))
+	}))
+	defer backend.Close()
+	downstream := NewClient(backend.URL, WithB3Headers())
+
+	var callerSpan SpanContext
+	s := NewServer()
+	s.Tracing = true
+	s.HandleFunc("proxy", func(ctx context.Context) (bool, error) {
+		callerSpan, _ = GetSpanContext(ctx)
+		_, err := downstream.Call(ctx, "m", nil)
+		return true, err
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var span SpanContext
+	client := NewClient(ts.URL, WithTracing(), WithHooks(ClientHooks{
+		OnCall: func(ctx context.Context, info *CallInfo) { span = info.Span },
+	}))
+	if _, err := client.Call(context.Background(), "proxy", nil); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	if !isHexID(span.TraceID, 32) || !isHexID(span.SpanID, 16) || span.ParentSpanID != "" || !span.Sampled {
+		t.Fatalf("invalid root span: %+v", span)
+	}
+	if callerSpan.TraceID != span.TraceID || callerSpan.SpanID != span.SpanID {
+		t.Errorf("invalid span on the server:\ngot: %+v\nwant: %+v", callerSpan, span)
+	}
+	child, ok := ParseTraceparent(got.Get(TraceparentHeader))
+	if !ok || child.TraceID != span.TraceID || child.SpanID == span.SpanID {
+		t.Errorf("invalid downstream traceparent %q for trace %v", got.Get(TraceparentHeader), span.TraceID)
+	}
+	if got.Get(B3TraceIDHeader) != span.TraceID || got.Get(B3SpanIDHeader) != child.SpanID || got.Get(B3ParentIDHeader) != span.SpanID || got.Get(B3SampledHeader) != "1" {
+		t.Errorf("invalid B3 headers: %v", got)
+	}
+}
@@ -0,0 +1,99 @@
+package jsonrpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+)
+
+// WithProxy sends requests through the proxy at proxyURL. The http, https and
+// socks5 schemes are supported. It has no effect together with
+// WithHTTPClient.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(c *Client) {
+		c.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithProxyFromEnvironment sends requests through the proxy configured by the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. It has no
+// effect together with WithHTTPClient.
+func WithProxyFromEnvironment() ClientOption {
+	return func(c *Client) {
+		c.proxy = http.ProxyFromEnvironment
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for https endpoints. The
+// other TLS options modify this configuration, so it should come first. It
+// has no effect together with WithHTTPClient.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.tlsConfig = cfg.Clone()
+	}
+}
+
+// WithRootCAs sets the certificate authorities used to verify servers instead
+// of the system pool.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(c *Client) {
+		c.tls().RootCAs = pool
+	}
+}
+
+// WithClientCertificate presents cert to servers requiring client
+// authentication.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *Client) {
+		cfg := c.tls()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithMinTLSVersion sets the minimum accepted TLS version, e.g.
+// tls.VersionTLS13.
+func WithMinTLSVersion(version uint16) ClientOption {
+	return func(c *Client) {
+		c.tls().MinVersion = version
+	}
+}
+
+// WithServerName overrides the name sent with SNI and used to verify the
+// server certificate.
+func WithServerName(name string) ClientOption {
+	return func(c *Client) {
+		c.tls().ServerName = name
+	}
+}
+
+// WithInsecureSkipVerify disables server certificate verification. It is
+// meant for development only.
+func WithInsecureSkipVerify() ClientOption {
+	return func(c *Client) {
+		c.tls().InsecureSkipVerify = true
+	}
+}
+
+func (c *Client) tls() *tls.Config {
+	if c.tlsConfig == nil {
+		c.tlsConfig = &tls.Config{}
+	}
+	return c.tlsConfig
+}
+
+// newHTTPClient returns the http client used when none was given with
+// WithHTTPClient.
+func (c *Client) newHTTPClient() *http.Client {
+	if c.proxy == nil && c.tlsConfig == nil {
+		return http.DefaultClient
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if c.proxy != nil {
+		t.Proxy = c.proxy
+	}
+	if c.tlsConfig != nil {
+		t.TLSClientConfig = c.tlsConfig
+	}
+	return &http.Client{Transport: t}
+}
@@ -0,0 +1,73 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestWithProxy(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("random", random)
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// a forward proxy receives the absolute url of the target
+		proxied = r.URL.String()
+		server.ServeHTTP(rw, r)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := NewClient("http://rpc.invalid/api", WithProxy(proxyURL))
+	rnd := &Reply{}
+	resp, err := client.Call(context.Background(), "random", nil)
+	if err != nil {
+		t.Fatalf("random: error not expected: %v", err)
+	}
+	if err := resp.Decode(rnd); err != nil || rnd.C != 33 {
+		t.Errorf("random: invalid reply %v: %v", rnd, err)
+	}
+	if proxied != "http://rpc.invalid/api" {
+		t.Errorf("request not sent through the proxy:\ngot: %q\nwant: %q", proxied, "http://rpc.invalid/api")
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	server := NewServer()
+	server.HandleFunc("random", random)
+	ts := httptest.NewTLSServer(server)
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+		ok   bool
+	}{
+		{"system_roots", nil, false},
+		{"root_cas", []ClientOption{WithRootCAs(pool)}, true},
+		{"server_name", []ClientOption{WithRootCAs(pool), WithServerName("example.com")}, true},
+		{"bad_server_name", []ClientOption{WithRootCAs(pool), WithServerName("bad.invalid")}, false},
+		{"min_version", []ClientOption{WithRootCAs(pool), WithMinTLSVersion(tls.VersionTLS12)}, true},
+		{"insecure", []ClientOption{WithInsecureSkipVerify()}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(ts.URL, tc.opts...)
+			_, err := client.Call(context.Background(), "random", nil)
+			if tc.ok && err != nil {
+				t.Errorf("error not expected: %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Errorf("expected a certificate error")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionHeader is the HTTP header selecting the version of the methods
+// called, see Server.HandleVersion.
+const VersionHeader = "X-API-Version"
+
+// VersionMember is the extension member of requests selecting the version of
+// the method called, as in {"jsonrpc":"2.0","id":1,"method":"user.get",
+// "version":2}. It takes precedence over WithVersion and VersionHeader.
+const VersionMember = "version"
+
+// VersionPolicy selects the version of a method called without one.
+type VersionPolicy int
+
+const (
+	// LatestVersion selects the highest version.
+	LatestVersion VersionPolicy = iota
+	// OldestVersion selects the lowest version, which keeps existing
+	// clients on the behavior they were written against.
+	OldestVersion
+	// RequireVersion answers calls without a version with Method not found.
+	RequireVersion
+)
+
+// Deprecation describes a deprecated method version.
+type Deprecation struct {
+	// Sunset is when the version will be removed, if planned.
+	Sunset  *time.Time `json:"sunset,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+// Deprecated marks a method, or a method version, as deprecated. Calls to it
+// get the Deprecation and Sunset HTTP headers and a warning in the response
+// meta, see ResponseMeta. A zero sunset means no removal is planned.
+func Deprecated(sunset time.Time, msg string) MethodOption {
+	return func(h *handlerType) {
+		h.deprecation = &Deprecation{Message: msg}
+		if !sunset.IsZero() {
+			h.deprecation.Sunset = &sunset
+		}
+	}
+}
+
+// methodVersions are the versions of a method, sorted.
+type methodVersions struct {
+	mu       sync.RWMutex
+	versions []int
+}
+
+type versionKey struct{}
+
+// WithVersion returns a copy of ctx selecting version of the methods called,
+// for transports other than HTTP.
+func WithVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// HandleVersion registers handler as version of method, callable as
+// "method@version" or as method with the version selected by the version
+// extension member of the request, see VersionMember, WithVersion or the
+// X-API-Version header. Calls without a version are routed
+// according to s.VersionPolicy. Options apply to this version only, as they
+// do with HandleFunc.
+func (s *Server) HandleVersion(method string, version int, handler interface{}, opts ...MethodOption) error {
+	if version <= 0 {
+		return fmt.Errorf("jsonrpc: invalid version %v of %v", version, method)
+	}
+	if strings.Contains(method, "@") {
+		return fmt.Errorf("jsonrpc: invalid method name %v", method)
+	}
+	if err := s.HandleFunc(versionedName(method, version), handler, opts...); err != nil {
+		return err
+	}
+	v, _ := s.versions.LoadOrStore(method, &methodVersions{})
+	vs := v.(*methodVersions)
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	for _, old := range vs.versions {
+		if old == version {
+			return nil
+		}
+	}
+	vs.versions = append(vs.versions, version)
+	sort.Ints(vs.versions)
+	return nil
+}
+
+func versionedName(method string, version int) string {
+	return method + "@" + strconv.Itoa(version)
+}
+
+// resolveVersion returns the name the version of the method selected for req,
+// received with ctx, is registered as.
+func (s *Server) resolveVersion(ctx context.Context, req *request) (string, bool) {
+	method := req.Method
+	if strings.Contains(method, "@") {
+		// registered versions are found directly
+		return "", false
+	}
+	requested := 0
+	if req.version != nil {
+		if err := json.Unmarshal(req.version, &requested); err != nil || requested <= 0 {
+			return "", false
+		}
+	} else if v, ok := ctx.Value(versionKey{}).(int); ok {
+		requested = v
+	} else if r := HTTPRequest(ctx); r != nil && r.Header.Get(VersionHeader) != "" {
+		v, err := strconv.Atoi(r.Header.Get(VersionHeader))
+		if err != nil {
+			return "", false
+		}
+		requested = v
+	}
+
+	v, ok := s.versions.Load(method)
+	if !ok {
+		return "", false
+	}
+	vs := v.(*methodVersions)
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	selected := 0
+	switch {
+	case requested > 0:
+		for _, v := range vs.versions {
+			if v == requested {
+				selected = v
+			}
+		}
+	case s.VersionPolicy == LatestVersion && len(vs.versions) > 0:
+		selected = vs.versions[len(vs.versions)-1]
+	case s.VersionPolicy == OldestVersion && len(vs.versions) > 0:
+		selected = vs.versions[0]
+	}
+	if selected == 0 {
+		return "", false
+	}
+	return versionedName(method, selected), true
+}
+
+// warnDeprecated signals the clients of ctx calling the method registered as
+// name, handled by h, if it is deprecated.
+func warnDeprecated(ctx context.Context, name string, h *handlerType) {
+	d := h.deprecation
+	if d == nil {
+		return
+	}
+	SetResponseHeader(ctx, "Deprecation", "true")
+	if d.Sunset != nil {
+		SetResponseHeader(ctx, "Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	msg := name + " is deprecated"
+	if d.Message != "" {
+		msg += ": " + d.Message
+	}
+	AddWarning(ctx, msg)
+}
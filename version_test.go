@@ -0,0 +1,135 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleVersion(t *testing.T) {
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := NewServer()
+	server.Meta.Member = "meta"
+	server.HandleVersion("user.get", 1, func(ctx context.Context) (string, error) { return "v1", nil },
+		Deprecated(sunset, "use version 2"))
+	server.HandleVersion("user.get", 2, func(ctx context.Context) (string, error) { return "v2", nil })
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	post := func(method, version string) (string, http.Header) {
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`))
+		if version != "" {
+			req.Header.Set(VersionHeader, version)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b), resp.Header
+	}
+
+	v1 := `{"jsonrpc":"2.0","id":1,"result":"v1","meta":{"warnings":["user.get@1 is deprecated: use version 2"]}}`
+	v2 := `{"jsonrpc":"2.0","id":1,"result":"v2"}`
+	notFound := `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`
+	tests := []struct {
+		method  string
+		version string
+		want    string
+	}{
+		{"user.get@1", "", v1},
+		{"user.get@2", "", v2},
+		{"user.get", "1", v1},
+		{"user.get", "", v2},
+		{"user.get@3", "", notFound},
+		{"user.get", "x", notFound},
+		{"user.put", "", notFound},
+	}
+	for _, tt := range tests {
+		got, h := post(tt.method, tt.version)
+		if got != tt.want {
+			t.Errorf("%v %v:\ngot: %v\nwant: %v", tt.method, tt.version, got, tt.want)
+		}
+		if deprecated := tt.want == v1; deprecated != (h.Get("Deprecation") == "true") {
+			t.Errorf("%v %v: invalid Deprecation header %q", tt.method, tt.version, h.Get("Deprecation"))
+		}
+		if tt.want == v1 && h.Get("Sunset") != "Tue, 01 Jan 2030 00:00:00 GMT" {
+			t.Errorf("%v %v: invalid Sunset header %q", tt.method, tt.version, h.Get("Sunset"))
+		}
+	}
+
+	server.VersionPolicy = OldestVersion
+	if got, _ := post("user.get", ""); got != v1 {
+		t.Errorf("oldest version:\ngot: %v\nwant: %v", got, v1)
+	}
+	server.VersionPolicy = RequireVersion
+	if got, _ := post("user.get", ""); got != notFound {
+		t.Errorf("required version:\ngot: %v\nwant: %v", got, notFound)
+	}
+	got := server.ServeMessage(WithVersion(context.Background(), 2), []byte(`{"jsonrpc":"2.0","id":1,"method":"user.get"}`))
+	if string(got) != v2 {
+		t.Errorf("WithVersion:\ngot: %s\nwant: %v", got, v2)
+	}
+
+	methods := server.Methods()
+	if len(methods) != 2 || methods[0].Name != "user.get@1" || methods[0].Deprecation == nil || methods[1].Deprecation != nil {
+		t.Errorf("invalid methods: %+v", methods)
+	}
+	if err := server.HandleVersion("user.get", 0, random); err == nil {
+		t.Errorf("HandleVersion: expected an error for version 0")
+	}
+}
+
+func TestHandleVersionOptions(t *testing.T) {
+	server := NewServer()
+	server.HandleVersion("user.get", 1, func(ctx context.Context) (string, error) { return "v1", nil })
+	server.HandleVersion("user.get", 2, func(ctx context.Context) (string, error) { return "v2", nil }, Authenticated())
+
+	req := []byte(`{"jsonrpc":"2.0","id":1,"method":"user.get@2"}`)
+	want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Unauthorized"}}`
+	if got := server.ServeMessage(context.Background(), req); string(got) != want {
+		t.Errorf("anonymous call:\ngot: %s\nwant: %v", got, want)
+	}
+	want = `{"jsonrpc":"2.0","id":1,"result":"v2"}`
+	if got := server.ServeMessage(WithPrincipal(context.Background(), &Principal{Subject: "ann"}), req); string(got) != want {
+		t.Errorf("authenticated call:\ngot: %s\nwant: %v", got, want)
+	}
+	want = `{"jsonrpc":"2.0","id":1,"result":"v1"}`
+	if got := server.ServeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"user.get@1"}`)); string(got) != want {
+		t.Errorf("version 1:\ngot: %s\nwant: %v", got, want)
+	}
+}
+
+func TestVersionMember(t *testing.T) {
+	server := NewServer()
+	server.Meta.Member = "meta"
+	server.HandleVersion("user.get", 1, func(ctx context.Context) (string, error) { return "v1", nil },
+		Deprecated(time.Time{}, ""))
+	server.HandleVersion("user.get", 2, func(ctx context.Context) (string, error) { return "v2", nil })
+
+	tests := []struct {
+		req, want string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"user.get","version":1}`, `{"jsonrpc":"2.0","id":1,"result":"v1","meta":{"warnings":["user.get@1 is deprecated"]}}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"user.get","version":2}`, `{"jsonrpc":"2.0","id":1,"result":"v2"}`},
+		{`{"jsonrpc":"2.0","id":1,"method":"user.get","version":"x"}`, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`},
+	}
+	for _, test := range tests {
+		// the member takes precedence over WithVersion
+		ctx := WithVersion(context.Background(), 3)
+		if got := server.ServeMessage(ctx, []byte(test.req)); string(got) != test.want {
+			t.Errorf("%v:\ngot: %s\nwant: %v", test.req, got, test.want)
+		}
+	}
+
+	methods := server.Methods()
+	if b, _ := json.Marshal(methods[0].Deprecation); string(b) != `{}` {
+		t.Errorf("invalid deprecation without sunset: %s", b)
+	}
+}